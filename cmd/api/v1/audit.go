@@ -10,6 +10,7 @@ import (
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 	"github.com/metal-stack-cloud/cli/cmd/config"
 	"github.com/metal-stack-cloud/cli/cmd/sorters"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
 	"github.com/metal-stack/metal-lib/pkg/genericcli"
 	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
@@ -38,31 +39,33 @@ func newAuditCmd(c *config.Config) *cobra.Command {
 		DescribePrinter: func() printers.Printer { return c.DescribePrinter },
 		ListPrinter:     func() printers.Printer { return c.ListPrinter },
 		ListCmdMutateFn: func(cmd *cobra.Command) {
-			cmd.Flags().String("request-id", "", "request id of the audit trace.")
+			a.addFilterFlags(cmd)
 
-			cmd.Flags().String("from", "", "start of range of the audit traces. e.g. 1h, 10m, 2006-01-02 15:04:05")
-			cmd.Flags().String("to", "", "end of range of the audit traces. e.g. 1h, 10m, 2006-01-02 15:04:05")
+			cmd.Flags().Int64("limit", 0, "limit the number of audit traces.")
 
-			cmd.Flags().String("user", "", "user of the audit trace.")
-			cmd.Flags().String("tenant", "", "tenant of the audit trace.")
+			cmd.Flags().Bool("prettify-body", false, "attempts to interpret the body as json and prettifies it.")
 
-			cmd.Flags().String("project", "", "project id of the audit trace")
+			cmd.Flags().Bool("follow", false, "keeps polling for new audit traces and prints them as they arrive, json output formats are printed as newline delimited json.")
+			cmd.Flags().Duration("follow-interval", 5*time.Second, "the interval in which new audit traces are polled in follow mode.")
+			cmd.Flags().Int32("page-size", 1000, "the maximum amount of traces fetched with a single request in follow mode, polls containing more traces get split.")
 
-			cmd.Flags().String("phase", "", "the audit trace phase.")
-			cmd.Flags().String("method", "", "api method of the audit trace.")
-			cmd.Flags().Int32("result-code", 0, "gRPC result status code of the audit trace.")
-			cmd.Flags().String("source-ip", "", "source-ip of the audit trace.")
+			cmd.Flags().Bool("correlate", false, "joins request and response phase of a trace into a single row showing the latency, requests without response are flagged.")
 
-			cmd.Flags().String("body", "", "filters audit trace body payloads for the given text (full-text search).")
+			cmd.MarkFlagsMutuallyExclusive("follow", "correlate")
+			cmd.MarkFlagsMutuallyExclusive("phase", "correlate")
+			cmd.MarkFlagsMutuallyExclusive("follow", "limit")
 
-			cmd.Flags().Int64("limit", 0, "limit the number of audit traces.")
+			listRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.GetBool("follow") {
+					return a.follow()
+				}
+				if viper.GetBool("correlate") {
+					return a.listCorrelated()
+				}
 
-			cmd.Flags().Bool("prettify-body", false, "attempts to interpret the body as json and prettifies it.")
-
-			genericcli.Must(cmd.RegisterFlagCompletionFunc("phase", c.Completion.AuditPhaseListCompletion))
-			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
-			genericcli.Must(cmd.RegisterFlagCompletionFunc("tenant", c.Completion.TenantListCompletion))
-			genericcli.Must(cmd.RegisterFlagCompletionFunc("result-code", c.Completion.AuditStatusCodesCompletion))
+				return listRunE(cmd, args)
+			}
 		},
 		DescribeCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().String("tenant", "", "tenant of the audit trace.")
@@ -71,15 +74,62 @@ func newAuditCmd(c *config.Config) *cobra.Command {
 
 			cmd.Flags().Bool("prettify-body", false, "attempts to interpret the body as json and prettifies it.")
 
+			cmd.Flags().Bool("side-by-side", false, "prints request and response of the audit trace with their bodies side by side.")
+
+			cmd.MarkFlagsMutuallyExclusive("phase", "side-by-side")
+
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("phase", c.Completion.AuditPhaseListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("tenant", c.Completion.TenantListCompletion))
+
+			describeRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.GetBool("side-by-side") {
+					id, err := genericcli.GetExactlyOneArg(args)
+					if err != nil {
+						return err
+					}
+
+					return a.describeSideBySide(id)
+				}
+
+				return describeRunE(cmd, args)
+			}
 		},
 	}
 
-	return genericcli.NewCmds(cmdsConfig)
+	return genericcli.NewCmds(cmdsConfig, a.newExportCmd(), a.newStatsCmd(), a.newForwardCmd(), a.newHistoryCmd())
+}
+
+// addFilterFlags adds the flags for filtering audit traces that are used by listRequestFromCLI
+func (a *audit) addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("request-id", "", "request id of the audit trace.")
+
+	cmd.Flags().String("from", "", "start of range of the audit traces. e.g. 1h, 7d, yesterday, 2006-01-02, 2006-01-02 15:04:05, 2006-01-02T15:04:05Z07:00")
+	cmd.Flags().String("to", "", "end of range of the audit traces. e.g. 1h, 7d, today, 2006-01-02, 2006-01-02 15:04:05, 2006-01-02T15:04:05Z07:00")
+
+	cmd.Flags().String("user", "", "user of the audit trace.")
+	cmd.Flags().String("tenant", "", "tenant of the audit trace.")
+
+	cmd.Flags().String("project", "", "project id of the audit trace")
+
+	cmd.Flags().String("phase", "", "the audit trace phase.")
+	cmd.Flags().String("method", "", "api method of the audit trace.")
+	cmd.Flags().Int32("result-code", 0, "gRPC result status code of the audit trace.")
+	cmd.Flags().String("source-ip", "", "source-ip of the audit trace.")
+
+	cmd.Flags().String("body", "", "filters audit trace body payloads for the given text (full-text search).")
+
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("phase", a.c.Completion.AuditPhaseListCompletion))
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("project", a.c.Completion.ProjectListCompletion))
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("tenant", a.c.Completion.TenantListCompletion))
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("result-code", a.c.Completion.AuditStatusCodesCompletion))
 }
 
 func (a *audit) Get(id string) (*apiv1.AuditTrace, error) {
+	return a.get(id, a.toPhase(viper.GetString("phase")))
+}
+
+func (a *audit) get(id string, phase *apiv1.AuditPhase) (*apiv1.AuditTrace, error) {
 	ctx, cancel := a.c.NewRequestContext()
 	defer cancel()
 
@@ -91,7 +141,7 @@ func (a *audit) Get(id string) (*apiv1.AuditTrace, error) {
 	req := &apiv1.AuditServiceGetRequest{
 		Login: tenant,
 		Uuid:  id,
-		Phase: a.toPhase(viper.GetString("phase")),
+		Phase: phase,
 	}
 
 	resp, err := a.c.Client.Apiv1().Audit().Get(ctx, connect.NewRequest(req))
@@ -107,14 +157,34 @@ func (a *audit) Get(id string) (*apiv1.AuditTrace, error) {
 }
 
 func (a *audit) List() ([]*apiv1.AuditTrace, error) {
+	req, err := a.listRequestFromCLI()
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := a.c.NewRequestContext()
 	defer cancel()
 
-	fromDateTime, err := eventuallyRelativeDateTime(viper.GetString("from"))
+	resp, err := a.c.Client.Apiv1().Audit().List(ctx, connect.NewRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit traces: %w", err)
+	}
+
+	if viper.GetBool("prettify-body") {
+		for _, trace := range resp.Msg.Traces {
+			a.tryPrettifyBody(trace)
+		}
+	}
+
+	return resp.Msg.Traces, nil
+}
+
+func (a *audit) listRequestFromCLI() (*apiv1.AuditServiceListRequest, error) {
+	fromDateTime, err := a.timeFromCLI("from")
 	if err != nil {
 		return nil, err
 	}
-	toDateTime, err := eventuallyRelativeDateTime(viper.GetString("to"))
+	toDateTime, err := a.timeFromCLI("to")
 	if err != nil {
 		return nil, err
 	}
@@ -144,32 +214,26 @@ func (a *audit) List() ([]*apiv1.AuditTrace, error) {
 		Phase:      a.toPhase(viper.GetString("phase")),
 	}
 
-	resp, err := a.c.Client.Apiv1().Audit().List(ctx, connect.NewRequest(req))
-	if err != nil {
-		return nil, fmt.Errorf("failed to list audit traces: %w", err)
-	}
-
-	if viper.GetBool("prettify-body") {
-		for _, trace := range resp.Msg.Traces {
-			a.tryPrettifyBody(trace)
-		}
-	}
-
-	return resp.Msg.Traces, nil
+	return req, nil
 }
 
-func eventuallyRelativeDateTime(s string) (*timestamppb.Timestamp, error) {
+// timeFromCLI parses the time expression of the given flag, times without timezone are interpreted in the timezone of the context
+func (a *audit) timeFromCLI(flag string) (*timestamppb.Timestamp, error) {
+	s := viper.GetString(flag)
 	if s == "" {
 		return nil, nil
 	}
-	duration, err := time.ParseDuration(s)
-	if err == nil {
-		return timestamppb.New(time.Now().Add(-duration)), nil
+
+	loc, err := a.c.GetTimezone()
+	if err != nil {
+		return nil, err
 	}
-	t, err := time.Parse("2006-01-02 15:04:05", s)
+
+	t, err := helpers.ParseTimeExpression(s, time.Now(), loc)
 	if err != nil {
-		return timestamppb.Now(), fmt.Errorf("failed to convert time: %w", err)
+		return nil, fmt.Errorf("invalid %s: %w", flag, err)
 	}
+
 	return timestamppb.New(t), nil
 }
 
@@ -201,6 +265,19 @@ func (a *audit) tryPrettifyBody(trace *apiv1.AuditTrace) {
 	}
 }
 
+// tryNormalizeBody is like tryPrettifyBody but results in compact json with sorted keys
+func (a *audit) tryNormalizeBody(trace *apiv1.AuditTrace) {
+	if trace.Body != nil {
+		trimmed := strings.Trim(*trace.Body, `"`)
+		body := map[string]any{}
+		if err := json.Unmarshal([]byte(trimmed), &body); err == nil {
+			if normalized, err := json.Marshal(body); err == nil {
+				trace.Body = pointer.Pointer(string(normalized))
+			}
+		}
+	}
+}
+
 func (a *audit) toPhase(phase string) *apiv1.AuditPhase {
 	p, ok := apiv1.AuditPhase_value[phase]
 	if !ok {
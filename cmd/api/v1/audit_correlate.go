@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"golang.org/x/term"
+	"google.golang.org/grpc/codes"
+)
+
+// auditSideBySideWidth is used for the side by side view when the output is not a terminal
+const auditSideBySideWidth = 160
+
+func (a *audit) listCorrelated() error {
+	traces, err := a.List()
+	if err != nil {
+		return err
+	}
+
+	correlations := models.CorrelateAuditTraces(traces)
+
+	sort.SliceStable(correlations, func(i, j int) bool {
+		return correlations[i].Record().Timestamp.After(correlations[j].Record().Timestamp)
+	})
+
+	return a.c.ListPrinter.Print(correlations)
+}
+
+func (a *audit) describeSideBySide(id string) error {
+	var traces []*apiv1.AuditTrace
+
+	for _, phase := range []apiv1.AuditPhase{apiv1.AuditPhase_AUDIT_PHASE_REQUEST, apiv1.AuditPhase_AUDIT_PHASE_RESPONSE} {
+		trace, err := a.get(id, &phase)
+		if err != nil {
+			if connect.CodeOf(err) == connect.CodeNotFound {
+				continue
+			}
+			return err
+		}
+
+		a.tryPrettifyBody(trace)
+
+		traces = append(traces, trace)
+	}
+
+	if len(traces) == 0 {
+		return fmt.Errorf("no audit trace found with id %q", id)
+	}
+
+	c := models.CorrelateAuditTraces(traces)[0]
+	record := c.Record()
+
+	code := ""
+	if c.Response != nil && c.Response.ResultCode != nil {
+		code = codes.Code(uint32(*c.Response.ResultCode)).String()
+	}
+
+	latency := color.RedString("no response")
+	if c.Latency != nil {
+		latency = c.Latency.Round(time.Millisecond).String()
+	}
+
+	_, _ = fmt.Fprintf(a.c.Out, "Request-Id: %s\n", c.Uuid)
+	_, _ = fmt.Fprintf(a.c.Out, "Time:       %s\n", record.Timestamp.Format(time.DateTime))
+	_, _ = fmt.Fprintf(a.c.Out, "Method:     %s\n", record.Method)
+	_, _ = fmt.Fprintf(a.c.Out, "User:       %s\n", record.User)
+	if record.Project != "" {
+		_, _ = fmt.Fprintf(a.c.Out, "Project:    %s\n", record.Project)
+	}
+	_, _ = fmt.Fprintf(a.c.Out, "Source-Ip:  %s\n", record.SourceIp)
+	_, _ = fmt.Fprintf(a.c.Out, "Code:       %s\n", code)
+	_, _ = fmt.Fprintf(a.c.Out, "Latency:    %s\n\n", latency)
+
+	body := func(r *models.AuditRecord, missing string) string {
+		if r == nil {
+			return missing
+		}
+		return r.Body
+	}
+
+	_, _ = fmt.Fprint(a.c.Out, helpers.SideBySide(
+		"REQUEST\n\n"+body(c.Request, "no request recorded"),
+		"RESPONSE\n\n"+body(c.Response, "no response recorded"),
+		a.outputWidth(),
+	))
+
+	return nil
+}
+
+func (a *audit) outputWidth() int {
+	if f, ok := a.c.Out.(*os.File); ok {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+
+	return auditSideBySideWidth
+}
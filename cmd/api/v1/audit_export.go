@@ -0,0 +1,374 @@
+package v1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"sigs.k8s.io/yaml"
+)
+
+// auditExportMinWindow is the smallest time window that is used when a window contains more traces than the page size
+const auditExportMinWindow = time.Second
+
+// auditExportCheckpoint is persisted next to the export file after every completed time window
+type auditExportCheckpoint struct {
+	// Exported is the end of the last completely exported time window
+	Exported time.Time `json:"exported"`
+	// To is the end of the requested range
+	To time.Time `json:"to"`
+	// Offset is the size of the export file at the time the window was completed
+	Offset int64 `json:"offset"`
+	// Format is the format of the export file
+	Format string `json:"format"`
+	// Count is the number of traces exported so far
+	Count int `json:"count"`
+	// Tenant is the tenant of the exported traces
+	Tenant string `json:"tenant"`
+	// Filters is a hash of the filters of the export
+	Filters string `json:"filters"`
+	// Range is the time range of the export as given on the command line
+	Range string `json:"range"`
+	// NormalizeBody is set when the bodies of the exported traces are normalized
+	NormalizeBody bool `json:"normalize-body"`
+}
+
+// mismatch returns what differs between the export of the checkpoint and the requested export, an empty string if they match
+func (c *auditExportCheckpoint) mismatch(requested *auditExportCheckpoint) string {
+	switch {
+	case c.Format != requested.Format:
+		return "format " + c.Format
+	case c.Tenant != requested.Tenant:
+		return "tenant " + c.Tenant
+	case c.Filters != requested.Filters:
+		return "filters"
+	case c.Range != requested.Range:
+		return "time range " + c.Range
+	case c.NormalizeBody != requested.NormalizeBody:
+		return "body normalization"
+	default:
+		return ""
+	}
+}
+
+func (a *audit) newExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "exports audit traces of a time range into a file",
+		Long: `exports audit traces of a time range into a file. The range is walked in time windows, such that the export is not limited by the amount of traces returned in a single request.
+
+When writing to a file, the progress is recorded in a checkpoint file next to the export file. An interrupted export is resumed when running the command again with the same output file, the resumed export must use the same format, filters, time range and body normalization as the interrupted one.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.export()
+		},
+	}
+
+	a.addFilterFlags(exportCmd)
+
+	exportCmd.Flags().String("format", "jsonl", "the format of the export, can be jsonl or csv.")
+	exportCmd.Flags().String("out", "", "the file to write the export to, defaults to stdout.")
+	exportCmd.Flags().Duration("window", time.Hour, "the size of the time windows in which the range is fetched.")
+	exportCmd.Flags().Int32("page-size", 1000, "the maximum amount of traces fetched with a single request, windows containing more traces get split.")
+	exportCmd.Flags().Bool("normalize-body", false, "attempts to interpret the body as json and normalizes it to compact json with sorted keys.")
+
+	genericcli.Must(exportCmd.MarkFlagRequired("from"))
+	genericcli.Must(exportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"jsonl", "csv"}, cobra.ShellCompDirectiveNoFileComp)))
+
+	return exportCmd
+}
+
+func (a *audit) export() error {
+	format := viper.GetString("format")
+	if format != "jsonl" && format != "csv" {
+		return fmt.Errorf("unsupported export format %q, must be jsonl or csv", format)
+	}
+
+	req, err := a.listRequestFromCLI()
+	if err != nil {
+		return err
+	}
+
+	var (
+		from = req.From.AsTime()
+		to   = time.Now()
+
+		out            io.Writer = a.c.Out
+		file           afero.File
+		checkpoint     *auditExportCheckpoint
+		checkpointPath string
+		writeHeader    = true
+	)
+
+	if req.To != nil {
+		to = req.To.AsTime()
+	}
+
+	if !from.Before(to) {
+		return errors.New("from must be before to")
+	}
+
+	if path := viper.GetString("out"); path != "" {
+		checkpointPath = path + ".checkpoint"
+
+		checkpoint, err = a.readExportCheckpoint(checkpointPath)
+		if err != nil {
+			return err
+		}
+
+		requested := &auditExportCheckpoint{
+			To:            to,
+			Format:        format,
+			Tenant:        req.Login,
+			Filters:       auditFilterHash(req),
+			Range:         fmt.Sprintf("from=%s to=%s", viper.GetString("from"), viper.GetString("to")),
+			NormalizeBody: viper.GetBool("normalize-body"),
+		}
+
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if checkpoint != nil {
+			if mismatch := checkpoint.mismatch(requested); mismatch != "" {
+				return fmt.Errorf("found checkpoint of an unfinished export with different %s at %s, remove it to start over", mismatch, checkpointPath)
+			}
+
+			flags = os.O_WRONLY
+			from = checkpoint.Exported
+			to = checkpoint.To
+			writeHeader = false
+
+			_, _ = fmt.Fprintf(a.c.Err, "%s resuming export from %s (%d traces exported so far)\n", color.YellowString("⚠"), from.Format(time.RFC3339), checkpoint.Count)
+		} else {
+			checkpoint = requested
+		}
+
+		file, err = a.c.Fs.OpenFile(path, flags, 0600)
+		if err != nil {
+			return fmt.Errorf("unable to open export file: %w", err)
+		}
+		defer file.Close()
+
+		// drop everything that was written after the last completed window
+		err = file.Truncate(checkpoint.Offset)
+		if err != nil {
+			return fmt.Errorf("unable to truncate export file: %w", err)
+		}
+		_, err = file.Seek(checkpoint.Offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+		out = file
+	}
+
+	var (
+		csvWriter   = csv.NewWriter(out)
+		jsonEncoder = json.NewEncoder(out)
+		count       = pointer.SafeDeref(checkpoint).Count
+		window      = viper.GetDuration("window")
+		pageSize    = viper.GetInt32("page-size")
+	)
+
+	if format == "csv" && writeHeader {
+		err = csvWriter.Write(models.AuditRecordCSVHeader)
+		if err != nil {
+			return err
+		}
+	}
+
+	write := func(traces []*apiv1.AuditTrace) error {
+		for _, trace := range traces {
+			if viper.GetBool("normalize-body") {
+				a.tryNormalizeBody(trace)
+			}
+
+			record := models.NewAuditRecord(trace)
+
+			switch format {
+			case "csv":
+				err = csvWriter.Write(record.CSV())
+			default:
+				err = jsonEncoder.Encode(record)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		csvWriter.Flush()
+
+		return csvWriter.Error()
+	}
+
+	err = a.walkWindows(req, from, to, window, pageSize, func(end time.Time, traces []*apiv1.AuditTrace) error {
+		err := write(traces)
+		if err != nil {
+			return fmt.Errorf("unable to write export: %w", err)
+		}
+
+		count += len(traces)
+
+		if file == nil {
+			return nil
+		}
+
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		checkpoint.Exported = end
+		checkpoint.Offset = offset
+		checkpoint.Count = count
+
+		return a.writeExportCheckpoint(checkpointPath, checkpoint)
+	})
+	if err != nil {
+		return err
+	}
+
+	if file == nil {
+		return nil
+	}
+
+	err = a.c.Fs.Remove(checkpointPath)
+	if err != nil {
+		return fmt.Errorf("unable to remove checkpoint: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(a.c.Out, "%s exported %d audit traces to %s\n", color.GreenString("✔"), count, viper.GetString("out"))
+
+	return nil
+}
+
+// walkWindows fetches the traces of the given range window by window, fn is called with the traces of every window in ascending order
+func (a *audit) walkWindows(req *apiv1.AuditServiceListRequest, from, to time.Time, window time.Duration, pageSize int32, fn func(end time.Time, traces []*apiv1.AuditTrace) error) error {
+	if window <= 0 {
+		return errors.New("window must be a positive duration")
+	}
+	if pageSize <= 0 {
+		return errors.New("page size must be positive")
+	}
+
+	// the request is modified for every window, it is restored such that it can be reused afterwards
+	origFrom, origTo, origLimit := req.From, req.To, req.Limit
+	defer func() {
+		req.From, req.To, req.Limit = origFrom, origTo, origLimit
+	}()
+
+	for start := from; start.Before(to); {
+		end := start.Add(window)
+		if end.After(to) {
+			end = to
+		}
+
+		traces, err := a.fetchWindow(req, start, end, pageSize)
+		if err != nil {
+			return err
+		}
+
+		err = fn(end, traces)
+		if err != nil {
+			return err
+		}
+
+		start = end
+	}
+
+	return nil
+}
+
+// fetchWindow returns all traces of the given window, the window is split up when it contains more traces than fit into a page
+func (a *audit) fetchWindow(req *apiv1.AuditServiceListRequest, start, end time.Time, pageSize int32) ([]*apiv1.AuditTrace, error) {
+	ctx, cancel := a.c.NewRequestContext()
+	defer cancel()
+
+	req.From = timestamppb.New(start)
+	req.To = timestamppb.New(end)
+	req.Limit = pointer.Pointer(pageSize)
+
+	resp, err := a.c.Client.Apiv1().Audit().List(ctx, connect.NewRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit traces: %w", err)
+	}
+
+	traces := resp.Msg.Traces
+
+	if int32(len(traces)) >= pageSize {
+		if end.Sub(start) <= auditExportMinWindow {
+			_, _ = fmt.Fprintf(a.c.Err, "%s more than %d traces between %s and %s, some traces may be missing, consider increasing the page size\n", color.YellowString("⚠"), pageSize, start.Format(time.RFC3339), end.Format(time.RFC3339))
+		} else {
+			middle := start.Add(end.Sub(start) / 2)
+
+			first, err := a.fetchWindow(req, start, middle, pageSize)
+			if err != nil {
+				return nil, err
+			}
+			second, err := a.fetchWindow(req, middle, end, pageSize)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(first, second...), nil
+		}
+	}
+
+	// the api range is inclusive, traces on the window end are part of the next window
+	var result []*apiv1.AuditTrace
+	for _, trace := range traces {
+		if trace.Timestamp.AsTime().Before(end) {
+			result = append(result, trace)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.AsTime().Before(result[j].Timestamp.AsTime())
+	})
+
+	return result, nil
+}
+
+func (a *audit) readExportCheckpoint(path string) (*auditExportCheckpoint, error) {
+	raw, err := afero.ReadFile(a.c.Fs, path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read checkpoint: %w", err)
+	}
+
+	var checkpoint auditExportCheckpoint
+	err = yaml.Unmarshal(raw, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse checkpoint %s: %w", path, err)
+	}
+
+	return &checkpoint, nil
+}
+
+func (a *audit) writeExportCheckpoint(path string, checkpoint *auditExportCheckpoint) error {
+	raw, err := yaml.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	err = afero.WriteFile(a.c.Fs, path, raw, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write checkpoint: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/cmd/tableprinters"
+	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+)
+
+// auditFollowOverlap is subtracted from the cursor on every poll such that traces which are persisted
+// with a delay are not missed, duplicates are filtered out
+const auditFollowOverlap = time.Minute
+
+// auditFollower polls the audit traces with a moving from cursor and returns every trace only once
+type auditFollower struct {
+	a        *audit
+	req      *apiv1.AuditServiceListRequest
+	interval time.Duration
+	// pageSize limits the amount of traces fetched with a single request, polls returning more traces are split up
+	pageSize int32
+	cursor   time.Time
+	until    *time.Time
+	seen     map[string]time.Time
+}
+
+func (a *audit) newFollower(req *apiv1.AuditServiceListRequest, interval time.Duration, pageSize int32) *auditFollower {
+	f := &auditFollower{
+		a:        a,
+		req:      req,
+		interval: interval,
+		pageSize: pageSize,
+		cursor:   time.Now(),
+		seen:     map[string]time.Time{},
+	}
+
+	if req.From != nil {
+		f.cursor = req.From.AsTime()
+	}
+	if req.To != nil {
+		f.until = pointer.Pointer(req.To.AsTime())
+	}
+
+	return f
+}
+
+func auditTraceKey(trace *apiv1.AuditTrace) string {
+	return trace.Uuid + "/" + trace.Phase.String()
+}
+
+// poll fetches the traces since the cursor that were not returned before, ordered by time
+func (f *auditFollower) poll() ([]*apiv1.AuditTrace, error) {
+	from := f.cursor.Add(-auditFollowOverlap)
+
+	to := time.Now()
+	if f.until != nil && f.until.Before(to) {
+		to = *f.until
+	}
+
+	traces, err := f.a.fetchWindow(f.req, from, to, f.pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.unseen(traces), nil
+}
+
+// unseen returns the traces that were not returned before ordered by time and advances the cursor
+func (f *auditFollower) unseen(all []*apiv1.AuditTrace) []*apiv1.AuditTrace {
+	var traces []*apiv1.AuditTrace
+	for _, trace := range all {
+		key := auditTraceKey(trace)
+		if _, ok := f.seen[key]; ok {
+			continue
+		}
+
+		f.seen[key] = trace.Timestamp.AsTime()
+		traces = append(traces, trace)
+
+		if trace.Timestamp.AsTime().After(f.cursor) {
+			f.cursor = trace.Timestamp.AsTime()
+		}
+	}
+
+	// forget about traces that cannot be returned anymore
+	for key, ts := range f.seen {
+		if ts.Before(f.cursor.Add(-auditFollowOverlap)) {
+			delete(f.seen, key)
+		}
+	}
+
+	sort.SliceStable(traces, func(i, j int) bool {
+		return traces[i].Timestamp.AsTime().Before(traces[j].Timestamp.AsTime())
+	})
+
+	return traces
+}
+
+// run polls until the context is cancelled or the end of the requested range is reached
+func (f *auditFollower) run(ctx context.Context, fn func([]*apiv1.AuditTrace) error) error {
+	for {
+		traces, err := f.poll()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if len(traces) > 0 {
+			err = fn(traces)
+			if err != nil {
+				return err
+			}
+		}
+
+		if f.until != nil && time.Now().After(*f.until) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(f.interval):
+		}
+	}
+}
+
+func (a *audit) follow() error {
+	req, err := a.listRequestFromCLI()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	printTraces := a.followPrinter()
+
+	return a.newFollower(req, viper.GetDuration("follow-interval"), viper.GetInt32("page-size")).run(ctx, func(traces []*apiv1.AuditTrace) error {
+		if viper.GetBool("prettify-body") {
+			for _, trace := range traces {
+				a.tryPrettifyBody(trace)
+			}
+		}
+
+		return printTraces(traces)
+	})
+}
+
+// followPrinter returns a function printing traces incrementally, json output formats result in newline delimited json
+func (a *audit) followPrinter() func([]*apiv1.AuditTrace) error {
+	switch format := viper.GetString("output-format"); format {
+	case "json", "jsonraw":
+		enc := json.NewEncoder(a.c.Out)
+		return func(traces []*apiv1.AuditTrace) error {
+			for _, trace := range traces {
+				err := enc.Encode(models.NewAuditRecord(trace))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case "table", "wide", "markdown":
+		headers := !viper.GetBool("no-headers")
+		return func(traces []*apiv1.AuditTrace) error {
+			tp := tableprinters.New()
+			printer := printers.NewTablePrinter(&printers.TablePrinterConfig{
+				ToHeaderAndRows: tp.ToHeaderAndRows,
+				Wide:            format == "wide",
+				Markdown:        format == "markdown",
+				NoHeaders:       !headers,
+			}).WithOut(a.c.Out)
+			tp.SetPrinter(printer)
+
+			headers = false
+
+			return printer.Print(traces)
+		}
+	default:
+		return func(traces []*apiv1.AuditTrace) error {
+			for _, trace := range traces {
+				err := a.c.ListPrinter.Print(trace)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
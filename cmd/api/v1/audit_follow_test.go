@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	apitests "github.com/metal-stack-cloud/api/go/tests"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_auditFollower_run(t *testing.T) {
+	now := time.Date(2022, 5, 19, 1, 2, 3, 0, time.UTC)
+
+	trace := func(uuid string, phase apiv1.AuditPhase, ts time.Time) *apiv1.AuditTrace {
+		return &apiv1.AuditTrace{Uuid: uuid, Phase: phase, Timestamp: timestamppb.New(ts)}
+	}
+
+	var (
+		t1 = trace("1", apiv1.AuditPhase_AUDIT_PHASE_REQUEST, now)
+		t2 = trace("1", apiv1.AuditPhase_AUDIT_PHASE_RESPONSE, now.Add(time.Second))
+		t3 = trace("2", apiv1.AuditPhase_AUDIT_PHASE_REQUEST, now.Add(2*time.Second))
+
+		polls [][]*apiv1.AuditTrace
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	respond := func(traces ...*apiv1.AuditTrace) *connect.Response[apiv1.AuditServiceListResponse] {
+		return &connect.Response[apiv1.AuditServiceListResponse]{Msg: &apiv1.AuditServiceListResponse{Traces: traces}}
+	}
+
+	// every poll is paged, such that busy polls are split up instead of being truncated
+	paged := mock.MatchedBy(func(req *connect.Request[apiv1.AuditServiceListRequest]) bool {
+		return req.Msg.GetLimit() == 10 && req.Msg.To != nil
+	})
+
+	a := &audit{
+		c: &config.Config{
+			Client: apitests.New(t).Client(&apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Audit: func(m *mock.Mock) {
+						// every poll overlaps with the previous one
+						m.On("List", mock.Anything, paged).Return(respond(t1), nil).Once()
+						m.On("List", mock.Anything, paged).Return(respond(t2, t1), nil).Once()
+						m.On("List", mock.Anything, paged).Return(respond(t1, t2), nil).Once()
+						m.On("List", mock.Anything, paged).Return(respond(t3, t2, t1), nil).Once().Run(func(mock.Arguments) {
+							cancel()
+						})
+					},
+				},
+			}),
+		},
+	}
+
+	f := a.newFollower(&apiv1.AuditServiceListRequest{Login: "a-tenant", From: timestamppb.New(now)}, time.Millisecond, 10)
+
+	err := f.run(ctx, func(traces []*apiv1.AuditTrace) error {
+		polls = append(polls, traces)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, [][]*apiv1.AuditTrace{{t1}, {t2}, {t3}}, polls)
+	require.Equal(t, now.Add(2*time.Second), f.cursor)
+	require.Equal(t, timestamppb.New(now.Add(time.Second-auditFollowOverlap)), f.req.From)
+}
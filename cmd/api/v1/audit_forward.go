@@ -0,0 +1,537 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// auditSyslogAppName is the app-name of the emitted syslog messages
+	auditSyslogAppName = "metal-stack-cloud"
+	// auditSyslogSDID is the structured data id of the emitted syslog messages, 32473 is the enterprise number reserved for documentation
+	auditSyslogSDID = "audit@32473"
+)
+
+var auditSyslogFacilities = map[string]int{
+	"user":     1,
+	"daemon":   3,
+	"auth":     4,
+	"authpriv": 10,
+	"audit":    13,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// auditForwardCheckpoint is persisted after every batch of traces that was delivered to the sink
+type auditForwardCheckpoint struct {
+	// Cursor is the timestamp of the latest forwarded trace
+	Cursor time.Time `json:"cursor"`
+	// Seen contains the traces which were already forwarded within the overlap of the cursor
+	Seen map[string]time.Time `json:"seen,omitempty"`
+}
+
+// auditSink delivers audit traces to an external system
+type auditSink interface {
+	Send(traces []*apiv1.AuditTrace) error
+	Close() error
+}
+
+func (a *audit) newForwardCmd() *cobra.Command {
+	forwardCmd := &cobra.Command{
+		Use:   "forward",
+		Short: "continuously forwards audit traces to a syslog server or a http endpoint",
+		Long: `continuously forwards audit traces to a syslog server or a http endpoint.
+
+The sink is given as url:
+
+  udp://host:514, tcp://host:514 or unix:///dev/log emit RFC5424 syslog messages, either with the trace as json (syslog format) or as CEF (cef format).
+  http://host/path or https://host/path post batches of traces as json array (json format).
+
+After every delivered batch a checkpoint is written, a restarted forwarder resumes from the checkpoint. The default checkpoint is bound to the tenant and the given filters, changing the filters starts a new checkpoint. Traces are delivered at least once, a trace may be delivered again when the forwarder is stopped between the delivery and writing the checkpoint.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.forward()
+		},
+	}
+
+	a.addFilterFlags(forwardCmd)
+
+	forwardCmd.Flags().String("sink", "", "the url of the sink to forward the audit traces to, e.g. udp://localhost:514, tcp://siem:6514, unix:///dev/log or https://siem/ingest.")
+	forwardCmd.Flags().String("format", "", "the format of the forwarded traces, can be syslog or cef for syslog sinks and json for http sinks. defaults to syslog or json depending on the sink.")
+	forwardCmd.Flags().String("syslog-facility", "local0", "the syslog facility of the emitted messages.")
+	forwardCmd.Flags().Int("syslog-max-size", 2048, "the maximum size in bytes of syslog messages sent to udp and unix datagram sinks, longer messages are truncated.")
+	forwardCmd.Flags().StringSlice("http-header", nil, "additional headers sent to http sinks in the form <key>=<value>, e.g. Authorization=Bearer <token>.")
+	forwardCmd.Flags().String("checkpoint", "", "the file where the forwarding progress is stored, defaults to a file named after the tenant and the filters in the config directory.")
+	forwardCmd.Flags().Duration("follow-interval", 5*time.Second, "the interval in which new audit traces are polled.")
+	forwardCmd.Flags().Duration("window", time.Hour, "the size of the time windows in which traces are fetched when catching up.")
+	forwardCmd.Flags().Int32("page-size", 1000, "the maximum amount of traces fetched with a single request, windows and polls containing more traces get split.")
+
+	genericcli.Must(forwardCmd.MarkFlagRequired("sink"))
+	genericcli.Must(forwardCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"syslog", "cef", "json"}, cobra.ShellCompDirectiveNoFileComp)))
+	genericcli.Must(forwardCmd.RegisterFlagCompletionFunc("syslog-facility", cobra.FixedCompletions(auditSyslogFacilityNames(), cobra.ShellCompDirectiveNoFileComp)))
+
+	return forwardCmd
+}
+
+func (a *audit) forward() error {
+	sink, err := a.newAuditSink(viper.GetString("sink"), viper.GetString("format"))
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	req, err := a.listRequestFromCLI()
+	if err != nil {
+		return err
+	}
+
+	checkpointPath := viper.GetString("checkpoint")
+	if checkpointPath == "" {
+		checkpointPath, err = auditForwardCheckpointPath(req)
+		if err != nil {
+			return err
+		}
+	}
+
+	checkpoint, err := a.readForwardCheckpoint(checkpointPath)
+	if err != nil {
+		return err
+	}
+
+	f := a.newFollower(req, viper.GetDuration("follow-interval"), viper.GetInt32("page-size"))
+	if checkpoint != nil {
+		f.cursor = checkpoint.Cursor
+		if checkpoint.Seen != nil {
+			f.seen = checkpoint.Seen
+		}
+
+		_, _ = fmt.Fprintf(a.c.Err, "%s resuming from checkpoint at %s\n", color.GreenString("✔"), f.cursor.Format(time.RFC3339))
+	}
+
+	deliver := func(traces []*apiv1.AuditTrace) error {
+		if len(traces) == 0 {
+			return nil
+		}
+
+		err := sink.Send(traces)
+		if err != nil {
+			return fmt.Errorf("unable to forward audit traces: %w", err)
+		}
+
+		return a.writeForwardCheckpoint(checkpointPath, &auditForwardCheckpoint{
+			Cursor: f.cursor,
+			Seen:   f.seen,
+		})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// catch up in windows first, a single poll is limited in the amount of returned traces
+	err = a.walkWindows(req, f.cursor.Add(-auditFollowOverlap), time.Now(), viper.GetDuration("window"), viper.GetInt32("page-size"), func(_ time.Time, traces []*apiv1.AuditTrace) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return deliver(f.unseen(traces))
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	return f.run(ctx, deliver)
+}
+
+func auditForwardCheckpointPath(req *apiv1.AuditServiceListRequest) (string, error) {
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(path.Dir(configPath), "audit-forward", auditForwardCheckpointName(req)), nil
+}
+
+// auditForwardCheckpointName returns the checkpoint file name for the tenant and a hash of the filters, such that
+// forwarders with different filters do not share their progress, the time range is not part of the hash
+func auditForwardCheckpointName(req *apiv1.AuditServiceListRequest) string {
+	return fmt.Sprintf("%s-%s.yaml", req.Login, auditFilterHash(req))
+}
+
+// auditFilterHash returns a short hash of the filters of the request, the tenant, time range and limit are not part of the hash
+func auditFilterHash(req *apiv1.AuditServiceListRequest) string {
+	var filters []string
+
+	add := func(key, value string) {
+		filters = append(filters, key+"="+value)
+	}
+
+	add("uuid", req.GetUuid())
+	add("user", req.GetUser())
+	add("project", req.GetProject())
+	add("method", req.GetMethod())
+	add("source-ip", req.GetSourceIp())
+	add("body", req.GetBody())
+	if req.ResultCode != nil {
+		add("result-code", strconv.Itoa(int(*req.ResultCode)))
+	}
+	if req.Phase != nil {
+		add("phase", req.Phase.String())
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(filters, "\n")))
+
+	return fmt.Sprintf("%x", sum[:4])
+}
+
+func (a *audit) readForwardCheckpoint(checkpointPath string) (*auditForwardCheckpoint, error) {
+	raw, err := afero.ReadFile(a.c.Fs, checkpointPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read checkpoint: %w", err)
+	}
+
+	var checkpoint auditForwardCheckpoint
+	err = yaml.Unmarshal(raw, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse checkpoint %s: %w", checkpointPath, err)
+	}
+
+	return &checkpoint, nil
+}
+
+func (a *audit) writeForwardCheckpoint(checkpointPath string, checkpoint *auditForwardCheckpoint) error {
+	raw, err := yaml.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	err = a.c.Fs.MkdirAll(path.Dir(checkpointPath), 0700)
+	if err != nil {
+		return fmt.Errorf("unable to create checkpoint directory: %w", err)
+	}
+
+	// write to a temporary file first such that an interruption does not leave a broken checkpoint
+	err = afero.WriteFile(a.c.Fs, checkpointPath+".tmp", raw, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write checkpoint: %w", err)
+	}
+
+	err = a.c.Fs.Rename(checkpointPath+".tmp", checkpointPath)
+	if err != nil {
+		return fmt.Errorf("unable to write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (a *audit) newAuditSink(sink, format string) (auditSink, error) {
+	u, err := url.Parse(sink)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" {
+			return nil, fmt.Errorf("format %q is not supported for http sinks, must be json", format)
+		}
+
+		headers := http.Header{}
+		for _, h := range viper.GetStringSlice("http-header") {
+			key, value, ok := strings.Cut(h, "=")
+			if !ok {
+				return nil, fmt.Errorf("http headers must be provided in the form <key>=<value>")
+			}
+			headers.Add(key, value)
+		}
+
+		return &httpAuditSink{
+			url:     u.String(),
+			headers: headers,
+			client:  &http.Client{Timeout: 30 * time.Second},
+		}, nil
+
+	case "udp", "tcp", "unix":
+		if format == "" {
+			format = "syslog"
+		}
+		if format != "syslog" && format != "cef" {
+			return nil, fmt.Errorf("format %q is not supported for syslog sinks, must be syslog or cef", format)
+		}
+
+		facility, ok := auditSyslogFacilities[viper.GetString("syslog-facility")]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q, must be one of %s", viper.GetString("syslog-facility"), strings.Join(auditSyslogFacilityNames(), "|"))
+		}
+
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "-"
+		}
+
+		s := &syslogAuditSink{
+			facility: facility,
+			hostname: hostname,
+			cef:      format == "cef",
+			warn:     a.c.Err,
+		}
+
+		switch u.Scheme {
+		case "unix":
+			s.conn, err = net.Dial("unixgram", u.Path)
+			if err != nil {
+				s.conn, err = net.Dial("unix", u.Path)
+				s.framed = true
+			}
+		case "tcp":
+			s.conn, err = net.Dial("tcp", u.Host)
+			s.framed = true
+		default:
+			s.conn, err = net.Dial("udp", u.Host)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to syslog sink: %w", err)
+		}
+
+		if !s.framed {
+			s.maxSize = viper.GetInt("syslog-max-size")
+		}
+
+		return s, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported sink %q, must be an udp, tcp, unix, http or https url", sink)
+	}
+}
+
+func auditSyslogFacilityNames() []string {
+	var names []string
+	for name := range auditSyslogFacilities {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+type syslogAuditSink struct {
+	conn     net.Conn
+	facility int
+	hostname string
+	cef      bool
+	// framed uses octet counting as described in RFC6587 for stream transports
+	framed bool
+	// maxSize is the maximum size of a message for datagram transports, longer messages are truncated
+	maxSize int
+	warn    io.Writer
+}
+
+func (s *syslogAuditSink) Send(traces []*apiv1.AuditTrace) error {
+	for _, trace := range traces {
+		msg := s.message(trace)
+
+		if s.maxSize > 0 && len(msg) > s.maxSize {
+			_, _ = fmt.Fprintf(s.warn, "%s syslog message of audit trace %s exceeds %d bytes and gets truncated\n", color.YellowString("⚠"), trace.Uuid, s.maxSize)
+			msg = truncateUTF8(msg, s.maxSize)
+		}
+
+		if s.framed {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+
+		_, err := io.WriteString(s.conn, msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *syslogAuditSink) Close() error {
+	return s.conn.Close()
+}
+
+// message formats the trace as RFC5424 syslog message
+func (s *syslogAuditSink) message(trace *apiv1.AuditTrace) string {
+	var (
+		record   = models.NewAuditRecord(trace)
+		severity = 6 // informational
+	)
+
+	if failedTrace(trace) {
+		severity = 4 // warning
+	}
+
+	header := fmt.Sprintf("<%d>1 %s %s %s - %s", s.facility*8+severity, record.Timestamp.UTC().Format(time.RFC3339Nano), s.hostname, auditSyslogAppName, "audit")
+
+	if s.cef {
+		return header + " - " + cefMessage(record)
+	}
+
+	code := ""
+	if record.ResultCode != nil {
+		code = codes.Code(uint32(*record.ResultCode)).String()
+	}
+
+	sd := fmt.Sprintf(`[%s uuid="%s" tenant="%s" user="%s" method="%s" phase="%s" code="%s"]`, auditSyslogSDID,
+		sdEscape(record.Uuid), sdEscape(record.Tenant), sdEscape(record.User), sdEscape(record.Method), sdEscape(record.Phase), sdEscape(code))
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return header + " " + sd
+	}
+
+	return header + " " + sd + " " + string(raw)
+}
+
+// truncateUTF8 cuts s to at most size bytes without splitting a multi-byte character
+func truncateUTF8(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+
+	return s[:size]
+}
+
+// sdEscape escapes the characters which must be escaped in structured data param values according to RFC5424
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// cefMessage formats the record in the ArcSight common event format
+func cefMessage(record *models.AuditRecord) string {
+	header := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	ext := strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+
+	severity := 3
+	code := ""
+	if record.ResultCode != nil {
+		code = strconv.Itoa(int(*record.ResultCode))
+		if codes.Code(uint32(*record.ResultCode)) != codes.OK {
+			severity = 7
+		}
+	}
+
+	extensions := []string{
+		"rt=" + strconv.FormatInt(record.Timestamp.UnixMilli(), 10),
+		"externalId=" + ext.Replace(record.Uuid),
+		"suser=" + ext.Replace(record.User),
+		"src=" + ext.Replace(record.SourceIp),
+		"cs1Label=tenant",
+		"cs1=" + ext.Replace(record.Tenant),
+		"cs2Label=project",
+		"cs2=" + ext.Replace(record.Project),
+		"cs3Label=phase",
+		"cs3=" + ext.Replace(record.Phase),
+	}
+	if code != "" {
+		extensions = append(extensions, "cn1Label=resultCode", "cn1="+code)
+	}
+
+	return fmt.Sprintf("CEF:0|%s|%s|%s|%s|%s|%d|%s",
+		header.Replace("metal-stack-cloud"),
+		header.Replace("api"),
+		header.Replace("v1"),
+		header.Replace(record.Method),
+		header.Replace(record.Method+" "+record.Phase),
+		severity,
+		strings.Join(extensions, " "),
+	)
+}
+
+type httpAuditSink struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+func (s *httpAuditSink) Send(traces []*apiv1.AuditTrace) error {
+	var records []*models.AuditRecord
+	for _, trace := range traces {
+		records = append(records, models.NewAuditRecord(trace))
+	}
+
+	raw, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+
+	req.Header = s.headers.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sink responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+func (s *httpAuditSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func failedTrace(trace *apiv1.AuditTrace) bool {
+	return failedResultCode(trace.ResultCode)
+}
+
+func failedResultCode(code *int32) bool {
+	return code != nil && codes.Code(uint32(*code)) != codes.OK
+}
@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	apitests "github.com/metal-stack-cloud/api/go/tests"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_auditForwardCheckpointName(t *testing.T) {
+	base := &apiv1.AuditServiceListRequest{
+		Login:  "a-tenant",
+		Method: pointer.Pointer("/metalstack.api.v1.IPService/Get"),
+		From:   timestamppb.New(time.Date(2022, 5, 19, 1, 2, 3, 0, time.UTC)),
+	}
+
+	name := auditForwardCheckpointName(base)
+	require.True(t, strings.HasPrefix(name, "a-tenant-"), name)
+	require.True(t, strings.HasSuffix(name, ".yaml"), name)
+
+	require.Equal(t, name, auditForwardCheckpointName(&apiv1.AuditServiceListRequest{
+		Login:  "a-tenant",
+		Method: pointer.Pointer("/metalstack.api.v1.IPService/Get"),
+		From:   timestamppb.New(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+		To:     timestamppb.New(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)),
+		Limit:  pointer.Pointer(int32(100)),
+	}), "the time range and limit must not change the checkpoint")
+
+	for _, other := range []*apiv1.AuditServiceListRequest{
+		{Login: "a-tenant"},
+		{Login: "b-tenant", Method: base.Method},
+		{Login: "a-tenant", Method: base.Method, User: pointer.Pointer("a-user")},
+		{Login: "a-tenant", Method: base.Method, ResultCode: pointer.Pointer(int32(0))},
+		{Login: "a-tenant", Method: base.Method, Phase: pointer.Pointer(apiv1.AuditPhase_AUDIT_PHASE_RESPONSE)},
+	} {
+		require.NotEqual(t, name, auditForwardCheckpointName(other))
+	}
+}
+
+type recordingConn struct {
+	net.Conn
+	messages []string
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.messages = append(c.messages, string(b))
+	return len(b), nil
+}
+
+func Test_syslogAuditSink_Send(t *testing.T) {
+	trace := &apiv1.AuditTrace{
+		Uuid:      "c40ad996-e1fd-4511-a7bf-418219cb8d91",
+		Timestamp: timestamppb.New(time.Date(2022, 5, 19, 1, 2, 3, 0, time.UTC)),
+		Method:    "/metalstack.api.v1.IPService/Get",
+		Phase:     apiv1.AuditPhase_AUDIT_PHASE_REQUEST,
+		Body:      pointer.Pointer(strings.Repeat("ä", 100)),
+	}
+
+	tests := []struct {
+		name        string
+		maxSize     int
+		framed      bool
+		wantWarning bool
+	}{
+		{
+			name:    "fits into a datagram",
+			maxSize: 2048,
+		},
+		{
+			name:        "truncated to the datagram size",
+			maxSize:     201,
+			wantWarning: true,
+		},
+		{
+			name:   "stream transports are not truncated",
+			framed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				conn = &recordingConn{}
+				warn bytes.Buffer
+				s    = &syslogAuditSink{conn: conn, facility: 16, hostname: "host", framed: tt.framed, maxSize: tt.maxSize, warn: &warn}
+			)
+
+			require.NoError(t, s.Send([]*apiv1.AuditTrace{trace}))
+			require.Len(t, conn.messages, 1)
+
+			full := s.message(trace)
+			got := conn.messages[0]
+
+			switch {
+			case tt.framed:
+				require.True(t, strings.HasSuffix(got, " "+full))
+			case tt.wantWarning:
+				require.LessOrEqual(t, len(got), tt.maxSize)
+				require.True(t, strings.HasPrefix(full, got))
+			default:
+				require.Equal(t, full, got)
+			}
+
+			if tt.wantWarning {
+				require.Contains(t, warn.String(), "syslog message of audit trace c40ad996-e1fd-4511-a7bf-418219cb8d91 exceeds 201 bytes and gets truncated")
+			} else {
+				require.Empty(t, warn.String())
+			}
+		})
+	}
+}
+
+func Test_truncateUTF8(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		size int
+		want string
+	}{
+		{name: "shorter", s: "abc", size: 4, want: "abc"},
+		{name: "exact", s: "abc", size: 3, want: "abc"},
+		{name: "ascii", s: "abc", size: 2, want: "ab"},
+		{name: "on character boundary", s: "aää", size: 3, want: "aä"},
+		{name: "does not split a character", s: "aää", size: 4, want: "aä"},
+		{name: "nothing fits", s: "ä", size: 1, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, truncateUTF8(tt.s, tt.size))
+		})
+	}
+}
+
+func Test_auditFollower_pollSplitsFullPages(t *testing.T) {
+	now := time.Now()
+	from := now.Add(-time.Hour)
+
+	trace := func(uuid string, ts time.Time) *apiv1.AuditTrace {
+		return &apiv1.AuditTrace{Uuid: uuid, Phase: apiv1.AuditPhase_AUDIT_PHASE_REQUEST, Timestamp: timestamppb.New(ts)}
+	}
+
+	var (
+		t1 = trace("1", from.Add(-30*time.Second))
+		t2 = trace("2", from.Add(30*time.Minute))
+	)
+
+	window := func(start, end time.Time) any {
+		return mock.MatchedBy(func(req *connect.Request[apiv1.AuditServiceListRequest]) bool {
+			return req.Msg.From.AsTime().Equal(start) && req.Msg.To.AsTime().Equal(end) && req.Msg.GetLimit() == 2
+		})
+	}
+
+	respond := func(traces ...*apiv1.AuditTrace) *connect.Response[apiv1.AuditServiceListResponse] {
+		return &connect.Response[apiv1.AuditServiceListResponse]{Msg: &apiv1.AuditServiceListResponse{Traces: traces}}
+	}
+
+	var (
+		start  = from.Add(-auditFollowOverlap)
+		middle = start.Add(now.Sub(start) / 2)
+	)
+
+	a := &audit{
+		c: &config.Config{
+			Client: apitests.New(t).Client(&apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Audit: func(m *mock.Mock) {
+						// the whole range returns a full page and gets split up
+						m.On("List", mock.Anything, window(start, now)).Return(respond(t1, t2), nil)
+						m.On("List", mock.Anything, window(start, middle)).Return(respond(t1), nil)
+						m.On("List", mock.Anything, window(middle, now)).Return(respond(t2), nil)
+					},
+				},
+			}),
+		},
+	}
+
+	f := a.newFollower(&apiv1.AuditServiceListRequest{Login: "a-tenant", From: timestamppb.New(from), To: timestamppb.New(now)}, time.Millisecond, 2)
+
+	traces, err := f.poll()
+	require.NoError(t, err)
+	require.Equal(t, []*apiv1.AuditTrace{t1, t2}, traces)
+}
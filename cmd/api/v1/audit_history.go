@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// auditHistoryDefaultRange is used when no start of the range is given
+const auditHistoryDefaultRange = 30 * 24 * time.Hour
+
+// readOnlyMethodPrefixes and readOnlyMethodSuffixes identify api methods that do not mutate resources
+var (
+	readOnlyMethodPrefixes = []string{"Get", "List", "Watch"}
+	readOnlyMethodSuffixes = []string{"Get", "List", "Credentials", "Info", "Prices"}
+)
+
+func (a *audit) newHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history <resource-uuid>",
+		Short: "shows the changes made to a resource",
+		Long: `shows the changes made to a resource. All mutating requests referencing the resource in their request or response body are ordered by time and the request body of every change is diffed against the body of the previous successful change.
+
+Requests which only reference the resource in their response, like the creation of the resource, are found by their response. If no start of the range is given, the last 30 days are searched.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := genericcli.GetExactlyOneArg(args)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("phase") {
+				return errors.New("the history cannot be filtered by phase, requests and responses are always needed")
+			}
+
+			return a.history(id)
+		},
+	}
+
+	a.addFilterFlags(historyCmd)
+
+	// requests and responses are always needed for the history
+	genericcli.Must(historyCmd.Flags().MarkHidden("phase"))
+
+	historyCmd.Flags().Duration("window", 24*time.Hour, "the size of the time windows in which the range is fetched.")
+	historyCmd.Flags().Int32("page-size", 1000, "the maximum amount of traces fetched with a single request, windows containing more traces get split.")
+
+	return historyCmd
+}
+
+func (a *audit) history(id string) error {
+	req, err := a.listRequestFromCLI()
+	if err != nil {
+		return err
+	}
+
+	req.Body = pointer.Pointer(id)
+
+	to := time.Now()
+	if req.To != nil {
+		to = req.To.AsTime()
+	}
+	from := to.Add(-auditHistoryDefaultRange)
+	if req.From != nil {
+		from = req.From.AsTime()
+	}
+
+	var traces []*apiv1.AuditTrace
+	err = a.walkWindows(req, from, to, viper.GetDuration("window"), viper.GetInt32("page-size"), func(_ time.Time, window []*apiv1.AuditTrace) error {
+		traces = append(traces, window...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var correlations []*models.AuditCorrelation
+	for _, c := range models.CorrelateAuditTraces(traces) {
+		if c.Request == nil && c.Response != nil && isMutatingMethod(c.Response.Method) {
+			// the resource is only referenced in the response, e.g. when it was created by this request
+			request, err := a.get(c.Uuid, pointer.Pointer(apiv1.AuditPhase_AUDIT_PHASE_REQUEST))
+			if err != nil {
+				if connect.CodeOf(err) == connect.CodeNotFound {
+					continue
+				}
+				return err
+			}
+
+			c.Request = models.NewAuditRecord(request)
+			c.Latency = pointer.Pointer(c.Response.Timestamp.Sub(c.Request.Timestamp))
+		}
+
+		if c.Request == nil || !isMutatingMethod(c.Request.Method) {
+			continue
+		}
+
+		correlations = append(correlations, c)
+	}
+
+	sort.SliceStable(correlations, func(i, j int) bool {
+		return correlations[i].Request.Timestamp.Before(correlations[j].Request.Timestamp)
+	})
+
+	var (
+		changes []*models.AuditChange
+		// previous is the body of the last successful change
+		previous any
+	)
+
+	for _, c := range correlations {
+		body := parseAuditBody(c.Request.Body)
+
+		change := &models.AuditChange{
+			Uuid:      c.Uuid,
+			Timestamp: c.Request.Timestamp,
+			User:      c.Request.User,
+			Method:    c.Request.Method,
+			Changes:   helpers.DiffJSON(previous, body),
+		}
+
+		if c.Response != nil {
+			change.ResultCode = c.Response.ResultCode
+			change.Failed = failedResultCode(c.Response.ResultCode)
+		}
+
+		if !change.Failed {
+			previous = body
+		}
+
+		changes = append(changes, change)
+	}
+
+	return a.c.ListPrinter.Print(changes)
+}
+
+func isMutatingMethod(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+
+	for _, prefix := range readOnlyMethodPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	for _, suffix := range readOnlyMethodSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseAuditBody returns the body as unmarshalled json, bodies which are no valid json are returned as string
+func parseAuditBody(body string) any {
+	if body == "" {
+		return nil
+	}
+
+	var result any
+	if err := json.Unmarshal([]byte(strings.Trim(body, `"`)), &result); err == nil {
+		return result
+	}
+
+	return body
+}
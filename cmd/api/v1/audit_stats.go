@@ -0,0 +1,242 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+)
+
+// auditStatsKeys are the fields traces can be grouped by
+var auditStatsKeys = map[string]func(*apiv1.AuditTrace) string{
+	"method": func(t *apiv1.AuditTrace) string { return t.Method },
+	"user":   func(t *apiv1.AuditTrace) string { return t.User },
+	"result-code": func(t *apiv1.AuditTrace) string {
+		if t.ResultCode == nil {
+			return ""
+		}
+		return codes.Code(uint32(*t.ResultCode)).String()
+	},
+	"project":   func(t *apiv1.AuditTrace) string { return pointer.SafeDeref(t.Project) },
+	"tenant":    func(t *apiv1.AuditTrace) string { return t.Tenant },
+	"source-ip": func(t *apiv1.AuditTrace) string { return t.SourceIp },
+}
+
+func auditStatsKeyNames() []string {
+	var names []string
+	for name := range auditStatsKeys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (a *audit) newStatsCmd() *cobra.Command {
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "aggregates audit traces by groups and time buckets",
+		Long: `aggregates audit traces by groups and time buckets, showing the amount of requests and their error rate.
+
+Only the response phase of the audit traces is taken into account as it contains the result of a request. Requests that did not receive a response are not counted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("phase") {
+				return errors.New("the stats cannot be filtered by phase, only the response phase contains the result of a request")
+			}
+
+			return a.stats()
+		},
+	}
+
+	a.addFilterFlags(statsCmd)
+
+	statsCmd.Flags().StringSlice("group-by", []string{"method"}, fmt.Sprintf("the fields to group the audit traces by, can be %s.", strings.Join(auditStatsKeyNames(), "|")))
+	statsCmd.Flags().String("bucket", "", "splits the groups into time buckets of the given size, e.g. 1h or 1d.")
+	statsCmd.Flags().Bool("sparkline", false, "renders the buckets of a group as sparkline instead of a row per bucket, requires --bucket.")
+	statsCmd.Flags().Duration("window", time.Hour, "the size of the time windows in which the range is fetched.")
+	statsCmd.Flags().Int32("page-size", 1000, "the maximum amount of traces fetched with a single request, windows containing more traces get split.")
+
+	genericcli.Must(statsCmd.MarkFlagRequired("from"))
+	genericcli.Must(statsCmd.RegisterFlagCompletionFunc("group-by", cobra.FixedCompletions(auditStatsKeyNames(), cobra.ShellCompDirectiveNoFileComp)))
+	genericcli.Must(statsCmd.RegisterFlagCompletionFunc("bucket", cobra.FixedCompletions([]string{"1h", "1d"}, cobra.ShellCompDirectiveNoFileComp)))
+
+	return statsCmd
+}
+
+func (a *audit) stats() error {
+	groupBy := viper.GetStringSlice("group-by")
+	for _, key := range groupBy {
+		if _, ok := auditStatsKeys[key]; !ok {
+			return fmt.Errorf("unable to group by %q, must be one of %s", key, strings.Join(auditStatsKeyNames(), "|"))
+		}
+	}
+
+	var bucket time.Duration
+	if s := viper.GetString("bucket"); s != "" {
+		var err error
+		bucket, err = parseBucket(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	if viper.GetBool("sparkline") && bucket == 0 {
+		return errors.New("sparkline requires a bucket")
+	}
+
+	req, err := a.listRequestFromCLI()
+	if err != nil {
+		return err
+	}
+
+	req.Phase = pointer.Pointer(apiv1.AuditPhase_AUDIT_PHASE_RESPONSE)
+
+	from := req.From.AsTime()
+	to := time.Now()
+	if req.To != nil {
+		to = req.To.AsTime()
+	}
+
+	if !from.Before(to) {
+		return errors.New("from must be before to")
+	}
+
+	stats := &models.AuditStats{
+		GroupBy:   groupBy,
+		Sparkline: viper.GetBool("sparkline"),
+	}
+
+	loc, err := a.c.GetTimezone()
+	if err != nil {
+		return err
+	}
+
+	if bucket > 0 {
+		stats.Bucket = viper.GetString("bucket")
+		stats.Buckets = bucketStarts(from, to, bucket, loc)
+	}
+
+	groups := map[string]*models.AuditStatsGroup{}
+
+	err = a.walkWindows(req, from, to, viper.GetDuration("window"), viper.GetInt32("page-size"), func(_ time.Time, traces []*apiv1.AuditTrace) error {
+		for _, trace := range traces {
+			var keys []string
+			for _, key := range groupBy {
+				keys = append(keys, auditStatsKeys[key](trace))
+			}
+
+			id := strings.Join(keys, "\x00")
+
+			g, ok := groups[id]
+			if !ok {
+				g = &models.AuditStatsGroup{Keys: keys}
+				for _, start := range stats.Buckets {
+					g.Series = append(g.Series, &models.AuditStatsBucket{Start: start})
+				}
+
+				groups[id] = g
+				stats.Groups = append(stats.Groups, g)
+			}
+
+			failed := failedTrace(trace)
+
+			g.Count++
+			if failed {
+				g.Errors++
+			}
+
+			if bucket > 0 {
+				idx := bucketOf(stats.Buckets, trace.Timestamp.AsTime())
+				if idx < 0 {
+					continue
+				}
+
+				g.Series[idx].Count++
+				if failed {
+					g.Series[idx].Errors++
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, g := range stats.Groups {
+		g.ErrorRate = errorRate(g.Errors, g.Count)
+		for _, b := range g.Series {
+			b.ErrorRate = errorRate(b.Errors, b.Count)
+		}
+	}
+
+	sort.SliceStable(stats.Groups, func(i, j int) bool {
+		if stats.Groups[i].Count != stats.Groups[j].Count {
+			return stats.Groups[i].Count > stats.Groups[j].Count
+		}
+		return slices.Compare(stats.Groups[i].Keys, stats.Groups[j].Keys) < 0
+	})
+
+	return a.c.ListPrinter.Print(stats)
+}
+
+func errorRate(failed, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return float64(failed) / float64(count)
+}
+
+// bucketStarts returns the starts of the buckets covering from until to, buckets are aligned to the given timezone.
+// Buckets of whole days start at midnight and are advanced by calendar days, such that they stay aligned when
+// crossing a daylight saving time change.
+func bucketStarts(from, to time.Time, bucket time.Duration, loc *time.Location) []time.Time {
+	var starts []time.Time
+
+	local := from.In(loc)
+
+	if days := int(bucket / (24 * time.Hour)); bucket%(24*time.Hour) == 0 {
+		start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		for ; start.Before(to); start = start.AddDate(0, 0, days) {
+			starts = append(starts, start)
+		}
+		return starts
+	}
+
+	_, offset := local.Zone()
+	shift := time.Duration(offset) * time.Second
+
+	for start := from.Add(shift).Truncate(bucket).Add(-shift).In(loc); start.Before(to); start = start.Add(bucket) {
+		starts = append(starts, start)
+	}
+
+	return starts
+}
+
+// bucketOf returns the index of the bucket containing t or -1 if t is before the first bucket
+func bucketOf(starts []time.Time, t time.Time) int {
+	return sort.Search(len(starts), func(i int) bool {
+		return starts[i].After(t)
+	}) - 1
+}
+
+// parseBucket parses a bucket size, in addition to go durations days and weeks are supported, e.g. 1d
+func parseBucket(s string) (time.Duration, error) {
+	d, err := helpers.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid bucket %q, must be a positive duration like 1h or 1d", s)
+	}
+
+	return d, nil
+}
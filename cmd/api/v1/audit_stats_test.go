@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bucketStarts(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		bucket   time.Duration
+		loc      *time.Location
+		want     []time.Time
+		contains map[time.Time]int
+	}{
+		{
+			name:   "hours in utc",
+			from:   time.Date(2022, 5, 19, 1, 2, 3, 0, time.UTC),
+			to:     time.Date(2022, 5, 19, 3, 0, 0, 0, time.UTC),
+			bucket: time.Hour,
+			loc:    time.UTC,
+			want: []time.Time{
+				time.Date(2022, 5, 19, 1, 0, 0, 0, time.UTC),
+				time.Date(2022, 5, 19, 2, 0, 0, 0, time.UTC),
+			},
+			contains: map[time.Time]int{
+				time.Date(2022, 5, 19, 1, 2, 3, 0, time.UTC):   0,
+				time.Date(2022, 5, 19, 2, 59, 59, 0, time.UTC): 1,
+			},
+		},
+		{
+			name:   "days crossing the start of daylight saving time",
+			from:   time.Date(2022, 3, 26, 12, 0, 0, 0, berlin),
+			to:     time.Date(2022, 3, 28, 12, 0, 0, 0, berlin),
+			bucket: 24 * time.Hour,
+			loc:    berlin,
+			want: []time.Time{
+				time.Date(2022, 3, 26, 0, 0, 0, 0, berlin),
+				time.Date(2022, 3, 27, 0, 0, 0, 0, berlin),
+				time.Date(2022, 3, 28, 0, 0, 0, 0, berlin),
+			},
+			contains: map[time.Time]int{
+				time.Date(2022, 3, 26, 23, 59, 59, 0, berlin): 0,
+				time.Date(2022, 3, 27, 0, 0, 0, 0, berlin):    1,
+				// the 27th only has 23 hours
+				time.Date(2022, 3, 27, 23, 30, 0, 0, berlin): 1,
+				time.Date(2022, 3, 28, 0, 30, 0, 0, berlin):  2,
+			},
+		},
+		{
+			name:   "days crossing the end of daylight saving time",
+			from:   time.Date(2022, 10, 29, 12, 0, 0, 0, berlin),
+			to:     time.Date(2022, 10, 31, 12, 0, 0, 0, berlin),
+			bucket: 24 * time.Hour,
+			loc:    berlin,
+			want: []time.Time{
+				time.Date(2022, 10, 29, 0, 0, 0, 0, berlin),
+				time.Date(2022, 10, 30, 0, 0, 0, 0, berlin),
+				time.Date(2022, 10, 31, 0, 0, 0, 0, berlin),
+			},
+			contains: map[time.Time]int{
+				// the 30th has 25 hours
+				time.Date(2022, 10, 30, 23, 30, 0, 0, berlin): 1,
+				time.Date(2022, 10, 31, 0, 0, 0, 0, berlin):   2,
+			},
+		},
+		{
+			name:   "weeks crossing the start of daylight saving time",
+			from:   time.Date(2022, 3, 21, 12, 0, 0, 0, berlin),
+			to:     time.Date(2022, 4, 1, 0, 0, 0, 0, berlin),
+			bucket: 7 * 24 * time.Hour,
+			loc:    berlin,
+			want: []time.Time{
+				time.Date(2022, 3, 21, 0, 0, 0, 0, berlin),
+				time.Date(2022, 3, 28, 0, 0, 0, 0, berlin),
+			},
+			contains: map[time.Time]int{
+				time.Date(2022, 3, 27, 23, 59, 0, 0, berlin): 0,
+				time.Date(2022, 3, 28, 0, 0, 0, 0, berlin):   1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bucketStarts(tt.from, tt.to, tt.bucket, tt.loc)
+
+			require.Len(t, got, len(tt.want))
+			for i := range tt.want {
+				require.True(t, tt.want[i].Equal(got[i]), "bucket %d: want %s, got %s", i, tt.want[i], got[i])
+			}
+
+			for ts, want := range tt.contains {
+				require.Equal(t, want, bucketOf(got, ts), "bucket of %s", ts)
+			}
+
+			require.Equal(t, -1, bucketOf(got, got[0].Add(-time.Nanosecond)))
+		})
+	}
+}
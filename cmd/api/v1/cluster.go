@@ -64,11 +64,23 @@ func newClusterCmd(c *config.Config) *cobra.Command {
 			cmd.Flags().Uint32("worker-max-surge", 1, "the maximum amount of new worker nodes added to the worker group during a rolling update")
 			cmd.Flags().Uint32("worker-max-unavailable", 0, "the maximum amount of worker nodes removed from the worker group during a rolling update")
 			cmd.Flags().String("worker-type", "", "the worker type of the initial worker group")
+			cmd.Flags().Bool("estimate-cost", false, "only estimates the monthly cost of the cluster from the default prices instead of creating it")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("partition", c.Completion.PartitionAssetListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("kubernetes-version", c.Completion.KubernetesVersionAssetListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("worker-type", c.Completion.MachineTypeAssetListCompletion))
+
+			cmd.MarkFlagsMutuallyExclusive("file", "estimate-cost")
+
+			createRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.GetBool("estimate-cost") {
+					return w.estimateCreate()
+				}
+
+				return createRunE(cmd, args)
+			}
 		},
 		DescribeCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "project of the cluster")
@@ -94,11 +106,23 @@ func newClusterCmd(c *config.Config) *cobra.Command {
 			cmd.Flags().Uint32("worker-max-unavailable", 0, "the maximum amount of worker nodes removed from the worker group during a rolling update")
 			cmd.Flags().String("worker-type", "", "the worker type of the initial worker group")
 			cmd.Flags().Bool("remove-worker-group", false, "if set the selected worker group is being removed")
+			cmd.Flags().Bool("estimate-cost", false, "only estimates the monthly cost of the updated cluster and the difference to its current cost instead of updating it")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("kubernetes-version", c.Completion.KubernetesVersionAssetListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("worker-type", c.Completion.MachineTypeAssetListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("worker-group", c.Completion.ClusterWorkerGroupsCompletion))
+
+			cmd.MarkFlagsMutuallyExclusive("file", "estimate-cost")
+
+			updateRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.GetBool("estimate-cost") {
+					return w.estimateUpdate(args)
+				}
+
+				return updateRunE(cmd, args)
+			}
 		},
 		UpdateRequestFromCLI: w.updateFromCLI,
 	}
@@ -192,7 +216,7 @@ func newClusterCmd(c *config.Config) *cobra.Command {
 
 	genericcli.Must(statusCmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
 
-	return genericcli.NewCmds(cmdsConfig, kubeconfigCmd, execConfigCmd, monitoringCmd, reconcileCmd, statusCmd)
+	return genericcli.NewCmds(cmdsConfig, kubeconfigCmd, execConfigCmd, monitoringCmd, reconcileCmd, statusCmd, w.newCostCmd())
 }
 
 func (c *cluster) Create(req *apiv1.ClusterServiceCreateRequest) (*apiv1.Cluster, error) {
@@ -459,7 +483,7 @@ func (c *cluster) updateFromCLI(args []string) (*apiv1.ClusterServiceUpdateReque
 		var (
 			newWorkers []*apiv1.WorkerUpdate
 			showPrompt = func(op operation, name string) error {
-				if viper.GetBool("skip-security-prompts") {
+				if viper.GetBool("skip-security-prompts") || viper.GetBool("estimate-cost") {
 					return nil
 				}
 
@@ -0,0 +1,259 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const clusterCostControlPlane = "control plane"
+
+func (c *cluster) newCostCmd() *cobra.Command {
+	costCmd := &cobra.Command{
+		Use:   "cost <id>",
+		Short: "estimates the monthly cost of a cluster",
+		Long: `estimates the monthly cost range of a cluster from the default prices.
+
+The lower end of the range is the cost of all worker groups running with their minimum size, the upper end with their maximum size. A compute price applies to a worker group when it is named exactly like the machine type of the worker group, worker groups without such a price are not estimated. The control plane is priced by the kubernetes price if there is exactly one. Prices are hourly unless their unit states otherwise, a month has ` + fmt.Sprint(helpers.HoursPerMonth) + ` hours.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.cost(args)
+		},
+		ValidArgsFunction: c.c.Completion.ClusterListCompletion,
+	}
+
+	costCmd.Flags().StringP("project", "p", "", "project of the cluster")
+
+	genericcli.Must(costCmd.RegisterFlagCompletionFunc("project", c.c.Completion.ProjectListCompletion))
+
+	return costCmd
+}
+
+func (c *cluster) cost(args []string) error {
+	id, err := genericcli.GetExactlyOneArg(args)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := c.Get(id)
+	if err != nil {
+		return err
+	}
+
+	prices, err := c.defaultPrices()
+	if err != nil {
+		return err
+	}
+
+	cost := clusterCost(cluster.Workers, prices)
+	cost.Cluster = cluster.Uuid
+	cost.Name = cluster.Name
+
+	return c.printCost(cost)
+}
+
+// estimateCreate prints the cost of the cluster as it would be created from the command line flags
+func (c *cluster) estimateCreate() error {
+	rq, err := c.createFromCLI()
+	if err != nil {
+		return err
+	}
+
+	workers := rq.Workers
+	if len(workers) == 0 {
+		workers = []*apiv1.Worker{{
+			Name:        viper.GetString("worker-group"),
+			MachineType: viper.GetString("worker-type"),
+			Minsize:     viper.GetUint32("worker-min"),
+			Maxsize:     viper.GetUint32("worker-max"),
+		}}
+	}
+
+	prices, err := c.defaultPrices()
+	if err != nil {
+		return err
+	}
+
+	cost := clusterCost(workers, prices)
+	cost.Name = rq.Name
+
+	return c.printCost(cost)
+}
+
+// estimateUpdate prints the cost of the cluster as it would be updated from the command line flags together with the difference to its current cost
+func (c *cluster) estimateUpdate(args []string) error {
+	rq, err := c.updateFromCLI(args)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := c.Get(rq.Uuid)
+	if err != nil {
+		return err
+	}
+
+	prices, err := c.defaultPrices()
+	if err != nil {
+		return err
+	}
+
+	workers := cluster.Workers
+	if rq.Workers != nil {
+		workers = workerUpdatesToWorkers(cluster.Workers, rq.Workers)
+	}
+
+	cost := clusterCost(workers, prices)
+	cost.Cluster = cluster.Uuid
+	cost.Name = cluster.Name
+	cost.Current = clusterCost(cluster.Workers, prices)
+	cost.MinMonthlyCostDelta = pointer.Pointer(cost.MinMonthlyCost - cost.Current.MinMonthlyCost)
+	cost.MaxMonthlyCostDelta = pointer.Pointer(cost.MaxMonthlyCost - cost.Current.MaxMonthlyCost)
+
+	return c.printCost(cost)
+}
+
+func (c *cluster) defaultPrices() ([]*apiv1.Price, error) {
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := c.c.Client.Apiv1().Payment().GetDefaultPrices(ctx, connect.NewRequest(&apiv1.PaymentServiceGetDefaultPricesRequest{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default prices: %w", err)
+	}
+
+	return resp.Msg.Prices, nil
+}
+
+func (c *cluster) printCost(cost *models.ClusterCost) error {
+	for _, item := range cost.Items {
+		if item.MinMonthlyCost != nil {
+			continue
+		}
+
+		switch {
+		case item.Name == clusterCostControlPlane:
+			_, _ = fmt.Fprintf(c.c.Err, "%s no unique kubernetes price found, the cost of the control plane is not estimated\n", color.YellowString("⚠"))
+		case item.MachineType == "":
+			_, _ = fmt.Fprintf(c.c.Err, "%s no machine type given for worker group %q, its cost is not estimated\n", color.YellowString("⚠"), item.Name)
+		default:
+			_, _ = fmt.Fprintf(c.c.Err, "%s no price found for machine type %q of worker group %q, its cost is not estimated\n", color.YellowString("⚠"), item.MachineType, item.Name)
+		}
+	}
+
+	return c.c.ListPrinter.Print(cost)
+}
+
+// clusterCost estimates the monthly cost range of the given worker groups and the control plane
+func clusterCost(workers []*apiv1.Worker, prices []*apiv1.Price) *models.ClusterCost {
+	var kubernetesPrices []*apiv1.Price
+	for _, p := range prices {
+		if p.ProductType == apiv1.ProductType_PRODUCT_TYPE_KUBERNETES {
+			kubernetesPrices = append(kubernetesPrices, p)
+		}
+	}
+
+	cost := &models.ClusterCost{
+		Complete: true,
+	}
+
+	add := func(item *models.ClusterCostItem, price *apiv1.Price) {
+		cost.Items = append(cost.Items, item)
+
+		if price == nil {
+			cost.Complete = false
+			return
+		}
+
+		item.MinMonthlyCost = pointer.Pointer(helpers.MonthlyCost(price.UnitAmountDecimal, price.UnitLabel, float64(item.Minsize)))
+		item.MaxMonthlyCost = pointer.Pointer(helpers.MonthlyCost(price.UnitAmountDecimal, price.UnitLabel, float64(item.Maxsize)))
+
+		cost.MinMonthlyCost += *item.MinMonthlyCost
+		cost.MaxMonthlyCost += *item.MaxMonthlyCost
+
+		if cost.Currency == "" {
+			cost.Currency = strings.ToUpper(price.Currency)
+		}
+	}
+
+	for _, w := range workers {
+		add(&models.ClusterCostItem{
+			Name:        w.Name,
+			MachineType: w.MachineType,
+			Minsize:     w.Minsize,
+			Maxsize:     w.Maxsize,
+		}, machineTypePrice(prices, w.MachineType))
+	}
+
+	if len(kubernetesPrices) > 0 {
+		var price *apiv1.Price
+		if len(kubernetesPrices) == 1 {
+			price = kubernetesPrices[0]
+		}
+
+		add(&models.ClusterCostItem{
+			Name:    clusterCostControlPlane,
+			Minsize: 1,
+			Maxsize: 1,
+		}, price)
+	}
+
+	return cost
+}
+
+// machineTypePrice returns the compute price named exactly like the machine type, nil if there is none
+func machineTypePrice(prices []*apiv1.Price, machineType string) *apiv1.Price {
+	if machineType == "" {
+		return nil
+	}
+
+	for _, p := range prices {
+		if p.ProductType == apiv1.ProductType_PRODUCT_TYPE_COMPUTE && p.Name == machineType {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// workerUpdatesToWorkers returns the worker groups resulting from the update, unset fields keep the values of the current worker group
+func workerUpdatesToWorkers(current []*apiv1.Worker, updates []*apiv1.WorkerUpdate) []*apiv1.Worker {
+	byName := map[string]*apiv1.Worker{}
+	for _, w := range current {
+		byName[w.Name] = w
+	}
+
+	var workers []*apiv1.Worker
+	for _, u := range updates {
+		w := &apiv1.Worker{
+			Name: u.Name,
+		}
+
+		if existing, ok := byName[u.Name]; ok {
+			w.MachineType = existing.MachineType
+			w.Minsize = existing.Minsize
+			w.Maxsize = existing.Maxsize
+		}
+
+		if u.MachineType != nil {
+			w.MachineType = *u.MachineType
+		}
+		if u.Minsize != nil {
+			w.Minsize = *u.Minsize
+		}
+		if u.Maxsize != nil {
+			w.Maxsize = *u.Maxsize
+		}
+
+		workers = append(workers, w)
+	}
+
+	return workers
+}
@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testComputePrice = &apiv1.Price{Name: "c1-medium-x86", ProductType: apiv1.ProductType_PRODUCT_TYPE_COMPUTE, UnitAmountDecimal: 0.5, Currency: "eur"}
+	testLargePrice   = &apiv1.Price{Name: "c1-large-x86", ProductType: apiv1.ProductType_PRODUCT_TYPE_COMPUTE, UnitAmountDecimal: 2, Currency: "eur"}
+	testStoragePrice = &apiv1.Price{Name: "c1-medium-x86", ProductType: apiv1.ProductType_PRODUCT_TYPE_STORAGE, UnitAmountDecimal: 1, Currency: "eur"}
+	testControlPlane = &apiv1.Price{Name: "Kubernetes", ProductType: apiv1.ProductType_PRODUCT_TYPE_KUBERNETES, UnitAmountDecimal: 1, Currency: "eur"}
+)
+
+func Test_machineTypePrice(t *testing.T) {
+	prices := []*apiv1.Price{
+		testStoragePrice,
+		{Name: "Compute", Description: "machines of type c1-medium-x86", ProductType: apiv1.ProductType_PRODUCT_TYPE_COMPUTE},
+		{Name: "c1-medium-x86-gpu", ProductType: apiv1.ProductType_PRODUCT_TYPE_COMPUTE},
+		testComputePrice,
+		testLargePrice,
+	}
+
+	tests := []struct {
+		name        string
+		machineType string
+		want        *apiv1.Price
+	}{
+		{name: "exact name", machineType: "c1-medium-x86", want: testComputePrice},
+		{name: "other machine type", machineType: "c1-large-x86", want: testLargePrice},
+		{name: "no price", machineType: "c2-xlarge-x86"},
+		{name: "prefix of a price name does not match", machineType: "c1-medium"},
+		{name: "case must match", machineType: "C1-MEDIUM-X86"},
+		{name: "no machine type", machineType: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Same(t, tt.want, machineTypePrice(prices, tt.machineType))
+		})
+	}
+}
+
+func Test_clusterCost(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers []*apiv1.Worker
+		prices  []*apiv1.Price
+		want    *models.ClusterCost
+	}{
+		{
+			name: "workers and control plane",
+			workers: []*apiv1.Worker{
+				{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 1, Maxsize: 3},
+				{Name: "group-1", MachineType: "c1-large-x86", Minsize: 2, Maxsize: 2},
+			},
+			prices: []*apiv1.Price{testStoragePrice, testComputePrice, testLargePrice, testControlPlane},
+			want: &models.ClusterCost{
+				Items: []*models.ClusterCostItem{
+					{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 1, Maxsize: 3, MinMonthlyCost: pointer.Pointer(365.0), MaxMonthlyCost: pointer.Pointer(1095.0)},
+					{Name: "group-1", MachineType: "c1-large-x86", Minsize: 2, Maxsize: 2, MinMonthlyCost: pointer.Pointer(2920.0), MaxMonthlyCost: pointer.Pointer(2920.0)},
+					{Name: clusterCostControlPlane, Minsize: 1, Maxsize: 1, MinMonthlyCost: pointer.Pointer(730.0), MaxMonthlyCost: pointer.Pointer(730.0)},
+				},
+				Currency:       "EUR",
+				MinMonthlyCost: 4015,
+				MaxMonthlyCost: 4745,
+				Complete:       true,
+			},
+		},
+		{
+			name: "worker without price",
+			workers: []*apiv1.Worker{
+				{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 1, Maxsize: 1},
+				{Name: "group-1", MachineType: "c2-xlarge-x86", Minsize: 1, Maxsize: 1},
+				{Name: "group-2", Minsize: 1, Maxsize: 1},
+			},
+			prices: []*apiv1.Price{testComputePrice},
+			want: &models.ClusterCost{
+				Items: []*models.ClusterCostItem{
+					{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 1, Maxsize: 1, MinMonthlyCost: pointer.Pointer(365.0), MaxMonthlyCost: pointer.Pointer(365.0)},
+					{Name: "group-1", MachineType: "c2-xlarge-x86", Minsize: 1, Maxsize: 1},
+					{Name: "group-2", Minsize: 1, Maxsize: 1},
+				},
+				Currency:       "EUR",
+				MinMonthlyCost: 365,
+				MaxMonthlyCost: 365,
+			},
+		},
+		{
+			name:    "ambiguous control plane price",
+			workers: []*apiv1.Worker{{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 0, Maxsize: 2}},
+			prices:  []*apiv1.Price{testComputePrice, testControlPlane, {Name: "Kubernetes HA", ProductType: apiv1.ProductType_PRODUCT_TYPE_KUBERNETES, UnitAmountDecimal: 2}},
+			want: &models.ClusterCost{
+				Items: []*models.ClusterCostItem{
+					{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 0, Maxsize: 2, MinMonthlyCost: pointer.Pointer(0.0), MaxMonthlyCost: pointer.Pointer(730.0)},
+					{Name: clusterCostControlPlane, Minsize: 1, Maxsize: 1},
+				},
+				Currency:       "EUR",
+				MaxMonthlyCost: 730,
+			},
+		},
+		{
+			name: "no workers and prices",
+			want: &models.ClusterCost{
+				Complete: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, clusterCost(tt.workers, tt.prices)); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_workerUpdatesToWorkers(t *testing.T) {
+	current := []*apiv1.Worker{
+		{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 1, Maxsize: 3},
+		{Name: "group-1", MachineType: "c1-large-x86", Minsize: 2, Maxsize: 2},
+	}
+
+	tests := []struct {
+		name    string
+		updates []*apiv1.WorkerUpdate
+		want    []*apiv1.Worker
+	}{
+		{
+			name:    "unset fields keep the current values",
+			updates: []*apiv1.WorkerUpdate{{Name: "group-0"}, {Name: "group-1"}},
+			want:    current,
+		},
+		{
+			name: "changed fields",
+			updates: []*apiv1.WorkerUpdate{
+				{Name: "group-0", Maxsize: pointer.Pointer(uint32(5))},
+				{Name: "group-1", MachineType: pointer.Pointer("c2-xlarge-x86"), Minsize: pointer.Pointer(uint32(1))},
+			},
+			want: []*apiv1.Worker{
+				{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 1, Maxsize: 5},
+				{Name: "group-1", MachineType: "c2-xlarge-x86", Minsize: 1, Maxsize: 2},
+			},
+		},
+		{
+			name: "added group",
+			updates: []*apiv1.WorkerUpdate{
+				{Name: "group-0"},
+				{Name: "group-1"},
+				{Name: "group-2", MachineType: pointer.Pointer("c1-medium-x86"), Minsize: pointer.Pointer(uint32(1)), Maxsize: pointer.Pointer(uint32(2))},
+			},
+			want: []*apiv1.Worker{
+				current[0],
+				current[1],
+				{Name: "group-2", MachineType: "c1-medium-x86", Minsize: 1, Maxsize: 2},
+			},
+		},
+		{
+			name:    "removed group",
+			updates: []*apiv1.WorkerUpdate{{Name: "group-1"}},
+			want:    []*apiv1.Worker{current[1]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, workerUpdatesToWorkers(current, tt.updates), testcommon.IgnoreUnexported()); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
@@ -30,6 +30,8 @@ func newStorageCmd(c *config.Config) *cobra.Command {
 
 	storageCmd.AddCommand(newVolumeCmd(c))
 	storageCmd.AddCommand(newSnapshotCmd(c))
+	storageCmd.AddCommand(newStorageOrphansCmd(c))
+	storageCmd.AddCommand(newStorageReportCmd(c))
 
 	return storageCmd
 }
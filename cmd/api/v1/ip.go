@@ -36,6 +36,7 @@ func newIPCmd(c *config.Config) *cobra.Command {
 		ListPrinter:     func() printers.Printer { return c.ListPrinter },
 		ListCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "project from where ips should be listed")
+			cmd.Flags().StringSlice("tags", nil, "selects ips by tags, e.g. app=ingress, env!=dev, managed or !deprecated")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
 		},
@@ -45,8 +46,24 @@ func newIPCmd(c *config.Config) *cobra.Command {
 			cmd.Flags().StringP("description", "", "", "description of the ip")
 			cmd.Flags().StringSliceP("tags", "", nil, "tags to add to the ip")
 			cmd.Flags().BoolP("static", "", false, "make this ip static")
+			cmd.Flags().Int("count", 1, "the amount of ips to allocate")
+			cmd.Flags().String("name-template", "", "go template for the names of the ips when allocating multiple ips, e.g. ingress-{{ .Index }}, available fields are Index (starting at 0), Number (starting at 1) and Project")
+			cmd.Flags().Int("parallelism", 4, "the maximum amount of concurrent allocations when allocating multiple ips")
+			cmd.Flags().Bool("atomic", false, "stops starting further allocations and releases the already allocated ips if one of multiple allocations fails")
+
+			cmd.MarkFlagsMutuallyExclusive("name", "name-template")
+			cmd.MarkFlagsMutuallyExclusive("file", "count")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+
+			createRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.GetInt("count") != 1 || viper.IsSet("name-template") {
+					return w.createBulk()
+				}
+
+				return createRunE(cmd, args)
+			}
 		},
 		UpdateCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "project of the ip")
@@ -54,8 +71,21 @@ func newIPCmd(c *config.Config) *cobra.Command {
 			cmd.Flags().String("description", "", "description of the ip")
 			cmd.Flags().StringSlice("tags", nil, "tags of the ip")
 			cmd.Flags().Bool("static", false, "make this ip static")
+			cmd.Flags().StringSlice("selector", nil, "updates all ips matching the given tag selectors instead of a single ip, e.g. app=ingress, env!=dev, managed or !deprecated")
+
+			cmd.MarkFlagsMutuallyExclusive("selector", "name")
+			cmd.MarkFlagsMutuallyExclusive("selector", "file")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+
+			updateRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.IsSet("selector") {
+					return w.updateSelected(args)
+				}
+
+				return updateRunE(cmd, args)
+			}
 		},
 		DescribeCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "project of the ip")
@@ -64,23 +94,37 @@ func newIPCmd(c *config.Config) *cobra.Command {
 		},
 		DeleteCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "project of the ip")
+			cmd.Flags().StringSlice("selector", nil, "deletes all ips matching the given tag selectors instead of a single ip, e.g. app=ingress, env!=dev, managed or !deprecated")
+
+			cmd.MarkFlagsMutuallyExclusive("selector", "file")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+
+			deleteRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.IsSet("selector") {
+					return w.deleteSelected(args)
+				}
+
+				return deleteRunE(cmd, args)
+			}
 		},
-		CreateRequestFromCLI: func() (*apiv1.IPServiceAllocateRequest, error) {
-			return &apiv1.IPServiceAllocateRequest{
-				Project:     c.GetProject(),
-				Name:        viper.GetString("name"),
-				Description: viper.GetString("description"),
-				Tags:        viper.GetStringSlice("tags"),
-				Static:      viper.GetBool("static"),
-			}, nil
-		},
+		CreateRequestFromCLI: w.createRequestFromCLI,
 		UpdateRequestFromCLI: w.updateFromCLI,
 		ValidArgsFn:          c.Completion.IpListCompletion,
 	}
 
-	return genericcli.NewCmds(cmdsConfig)
+	return genericcli.NewCmds(cmdsConfig, w.newUsageCmd(), w.newExportCmd())
+}
+
+func (c *ip) createRequestFromCLI() (*apiv1.IPServiceAllocateRequest, error) {
+	return &apiv1.IPServiceAllocateRequest{
+		Project:     c.c.GetProject(),
+		Name:        viper.GetString("name"),
+		Description: viper.GetString("description"),
+		Tags:        viper.GetStringSlice("tags"),
+		Static:      viper.GetBool("static"),
+	}, nil
 }
 
 func (c *ip) updateFromCLI(args []string) (*apiv1.IPServiceUpdateRequest, error) {
@@ -166,6 +210,16 @@ func (c *ip) Get(id string) (*apiv1.IP, error) {
 }
 
 func (c *ip) List() ([]*apiv1.IP, error) {
+	return c.listSelected(viper.GetStringSlice("tags")...)
+}
+
+// listSelected lists the ips of the project whose tags match the given selectors
+func (c *ip) listSelected(selectors ...string) ([]*apiv1.IP, error) {
+	selector, err := helpers.ParseSelector(selectors...)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := c.c.NewRequestContext()
 	defer cancel()
 
@@ -176,7 +230,14 @@ func (c *ip) List() ([]*apiv1.IP, error) {
 		return nil, err
 	}
 
-	return resp.Msg.Ips, nil
+	var ips []*apiv1.IP
+	for _, ip := range resp.Msg.Ips {
+		if selector.Matches(ip.Tags) {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips, nil
 }
 
 func (c *ip) Update(rq *apiv1.IPServiceUpdateRequest) (*apiv1.IP, error) {
@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"connectrpc.com/connect"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/spf13/viper"
+)
+
+// errIPAllocationSkipped is returned for allocations which were not started because a previous atomic allocation failed
+var errIPAllocationSkipped = errors.New("allocation skipped")
+
+// ipNameTemplateData is passed to the name template when allocating multiple ips
+type ipNameTemplateData struct {
+	Index   int
+	Number  int
+	Project string
+}
+
+func (c *ip) createBulk() error {
+	count := viper.GetInt("count")
+	if count < 1 {
+		return fmt.Errorf("count must be at least 1")
+	}
+
+	base, err := c.createRequestFromCLI()
+	if err != nil {
+		return err
+	}
+
+	var tpl *template.Template
+	if viper.IsSet("name-template") {
+		tpl, err = template.New("name").Option("missingkey=error").Parse(viper.GetString("name-template"))
+		if err != nil {
+			return fmt.Errorf("invalid name template: %w", err)
+		}
+	}
+
+	var requests []*apiv1.IPServiceAllocateRequest
+	for i := range count {
+		name := base.Name
+		if tpl != nil {
+			var sb strings.Builder
+			err = tpl.Execute(&sb, ipNameTemplateData{Index: i, Number: i + 1, Project: base.Project})
+			if err != nil {
+				return fmt.Errorf("unable to render name template: %w", err)
+			}
+			name = sb.String()
+		}
+
+		requests = append(requests, &apiv1.IPServiceAllocateRequest{
+			Project:     base.Project,
+			Name:        name,
+			Description: base.Description,
+			Tags:        base.Tags,
+			Static:      base.Static,
+		})
+	}
+
+	atomic := viper.GetBool("atomic")
+
+	// with atomic the shared context is cancelled on the first error such that no further allocations are started,
+	// allocations already in flight are completed as they may succeed on the server side and need to be released
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ips, errs := helpers.RunParallel(requests, viper.GetInt("parallelism"), func(_ int, rq *apiv1.IPServiceAllocateRequest) (*apiv1.IP, error) {
+		if ctx.Err() != nil {
+			return nil, errIPAllocationSkipped
+		}
+
+		ip, err := c.Create(rq)
+		if err != nil && atomic {
+			cancel()
+		}
+
+		return ip, err
+	})
+
+	var (
+		allocated []*apiv1.IP
+		failures  []error
+		skipped   int
+	)
+	for i, err := range errs {
+		if errors.Is(err, errIPAllocationSkipped) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to allocate ip %q: %w", requests[i].Name, err))
+			continue
+		}
+		allocated = append(allocated, ips[i])
+	}
+
+	if len(failures) > 0 && atomic {
+		if skipped > 0 {
+			_, _ = fmt.Fprintf(c.c.Err, "%s skipped %d remaining allocations\n", color.YellowString("⚠"), skipped)
+		}
+
+		return errors.Join(append(failures, c.rollback(allocated))...)
+	}
+
+	if len(allocated) > 0 {
+		err = c.c.ListPrinter.Print(allocated)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("allocated %d of %d ips: %w", len(allocated), count, errors.Join(failures...))
+	}
+
+	return nil
+}
+
+// rollback releases the given ips, an error is returned listing the addresses that could not be released
+func (c *ip) rollback(ips []*apiv1.IP) error {
+	if len(ips) == 0 {
+		return nil
+	}
+
+	_, errs := helpers.RunParallel(ips, viper.GetInt("parallelism"), func(_ int, ip *apiv1.IP) (*apiv1.IP, error) {
+		ctx, cancel := c.c.NewRequestContext()
+		defer cancel()
+
+		resp, err := c.c.Client.Apiv1().IP().Delete(ctx, connect.NewRequest(&apiv1.IPServiceDeleteRequest{
+			Project: ip.Project,
+			Uuid:    ip.Uuid,
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		return resp.Msg.Ip, nil
+	})
+
+	var failures []error
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to release ip %s (%s), please release it manually: %w", ips[i].Ip, ips[i].Uuid, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.Join(failures...)
+	}
+
+	_, _ = fmt.Fprintf(c.c.Out, "%s released %d already allocated ips\n", color.GreenString("✔"), len(ips))
+
+	return nil
+}
@@ -0,0 +1,429 @@
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/tag"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var invalidRecordNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// ipRecordMarker is written as comment behind every exported record, such that records of released ips can be told apart
+// from records that were added manually when checking a file
+const ipRecordMarker = "ip-uuid="
+
+// ipRecordTemplateData is passed to the name template of the ip export
+type ipRecordTemplateData struct {
+	Name        string
+	Uuid        string
+	IP          string
+	Project     string
+	Description string
+	Tags        tag.TagMap
+}
+
+func (c *ip) newExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "exports the ips of a project as dns records",
+		Long: `exports the ips of a project as dns records, either as zone file, hosts file or json.
+
+The record name is the name of the ip, alternatively it can be rendered by a go template with the fields Name, Uuid, IP, Project, Description and Tags, e.g. {{ .Tags.app }}-{{ .Project }}. Record names are lowercased and invalid characters are replaced by dashes, ips resulting in an empty name are skipped.
+
+With --check an existing file is compared to the allocated ips instead, the command exits with code 1 if the file is out of sync. Records in the file are only reported as stale if their name is exported or if they were written by the export, which marks every record with a comment containing the uuid of the ip. Other records of the file like localhost are ignored.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.export()
+		},
+	}
+
+	exportCmd.Flags().StringP("project", "p", "", "project of the ips")
+	exportCmd.Flags().StringSlice("tags", nil, "selects ips by tags, e.g. app=ingress, env!=dev, managed or !deprecated")
+	exportCmd.Flags().String("format", "zonefile", "the format of the export, can be zonefile, hosts or json.")
+	exportCmd.Flags().String("name-template", "{{ .Name }}", "go template for the record names.")
+	exportCmd.Flags().String("zone", "", "the zone of the records, e.g. example.com. it is set as origin in zone files and appended to the names in hosts files.")
+	exportCmd.Flags().Int("ttl", 300, "the ttl of the records in zone files.")
+	exportCmd.Flags().String("check", "", "compares the given file in the given format with the allocated ips instead of exporting them.")
+
+	genericcli.Must(exportCmd.RegisterFlagCompletionFunc("project", c.c.Completion.ProjectListCompletion))
+	genericcli.Must(exportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"zonefile", "hosts", "json"}, cobra.ShellCompDirectiveNoFileComp)))
+
+	return exportCmd
+}
+
+func (c *ip) export() error {
+	format := viper.GetString("format")
+	if !slices.Contains([]string{"zonefile", "hosts", "json"}, format) {
+		return fmt.Errorf("unsupported export format %q, must be zonefile, hosts or json", format)
+	}
+
+	ips, err := c.listSelected(viper.GetStringSlice("tags")...)
+	if err != nil {
+		return err
+	}
+
+	records, err := c.ipRecords(ips)
+	if err != nil {
+		return err
+	}
+
+	if path := viper.GetString("check"); path != "" {
+		return c.checkRecords(path, format, records)
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(c.c.Out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(records)
+	case "hosts":
+		return writeHostsFile(c.c.Out, records)
+	default:
+		return writeZoneFile(c.c.Out, records)
+	}
+}
+
+func (c *ip) ipRecords(ips []*apiv1.IP) ([]*models.IPRecord, error) {
+	tpl, err := template.New("name").Option("missingkey=zero").Parse(viper.GetString("name-template"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+
+	zone := strings.TrimSuffix(viper.GetString("zone"), ".")
+
+	var records []*models.IPRecord
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip.Ip)
+		if err != nil {
+			_, _ = fmt.Fprintf(c.c.Err, "%s skipping ip %s: %s\n", color.YellowString("⚠"), ip.Uuid, err)
+			continue
+		}
+
+		var buf bytes.Buffer
+		err = tpl.Execute(&buf, ipRecordTemplateData{
+			Name:        ip.Name,
+			Uuid:        ip.Uuid,
+			IP:          ip.Ip,
+			Project:     ip.Project,
+			Description: ip.Description,
+			Tags:        tag.NewTagMap(ip.Tags),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to render name template for ip %s: %w", ip.Ip, err)
+		}
+
+		name := normalizeRecordName(buf.String(), zone)
+		if name == "" {
+			_, _ = fmt.Fprintf(c.c.Err, "%s skipping ip %s as it has no name\n", color.YellowString("⚠"), ip.Ip)
+			continue
+		}
+
+		records = append(records, &models.IPRecord{
+			Name:    name,
+			Type:    recordType(addr),
+			Address: addr.String(),
+			TTL:     viper.GetInt("ttl"),
+			Uuid:    ip.Uuid,
+		})
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Address < records[j].Address
+	})
+
+	return records, nil
+}
+
+func recordType(addr netip.Addr) string {
+	if addr.Is6() && !addr.Is4In6() {
+		return "AAAA"
+	}
+	return "A"
+}
+
+// normalizeRecordName returns a valid record name relative to the zone, the apex of the zone is returned as @
+func normalizeRecordName(name, zone string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "@" {
+		return name
+	}
+
+	name = strings.TrimSuffix(name, ".")
+
+	if zone != "" {
+		zone = strings.ToLower(zone)
+		if name == zone {
+			return "@"
+		}
+		name = strings.TrimSuffix(name, "."+zone)
+	}
+
+	name = invalidRecordNameChars.ReplaceAllString(name, "-")
+
+	return strings.Trim(name, "-.")
+}
+
+func writeZoneFile(w io.Writer, records []*models.IPRecord) error {
+	if zone := viper.GetString("zone"); zone != "" {
+		_, _ = fmt.Fprintf(w, "$ORIGIN %s.\n", strings.TrimSuffix(zone, "."))
+	}
+	_, _ = fmt.Fprintf(w, "$TTL %d\n", viper.GetInt("ttl"))
+
+	for _, r := range records {
+		_, err := fmt.Fprintf(w, "%s\tIN\t%s\t%s\t; %s%s\n", r.Name, r.Type, r.Address, ipRecordMarker, r.Uuid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeHostsFile(w io.Writer, records []*models.IPRecord) error {
+	zone := strings.TrimSuffix(viper.GetString("zone"), ".")
+
+	for _, r := range records {
+		names := r.Name
+		if zone != "" {
+			names = r.Name + "." + zone + " " + r.Name
+			if r.Name == "@" {
+				names = zone
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "%s\t%s\t# %s%s\n", r.Address, names, ipRecordMarker, r.Uuid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseRecordFile reads the a and aaaa records of a zone or hosts file, names are made relative to the given zone.
+// In zone files, relative names are qualified with the $ORIGIN of the file, the first origin is used as zone if no zone is given.
+func parseRecordFile(r io.Reader, format, zone string) ([]*models.IPRecord, error) {
+	var (
+		records  []*models.IPRecord
+		scanner  = bufio.NewScanner(r)
+		previous string
+		origin   string
+	)
+
+	// qualify returns the absolute name of an owner name of a zone file
+	qualify := func(name string) string {
+		switch {
+		case strings.HasSuffix(name, "."):
+			return name
+		case origin == "":
+			return name
+		case name == "@":
+			return origin + "."
+		default:
+			return name + "." + origin + "."
+		}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch format {
+		case "hosts":
+			line, comment, _ := strings.Cut(line, "#")
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+
+			addr, err := netip.ParseAddr(fields[0])
+			if err != nil {
+				continue
+			}
+
+			records = append(records, &models.IPRecord{Name: normalizeRecordName(fields[1], zone), Type: recordType(addr), Address: addr.String(), Uuid: recordMarker(comment)})
+
+		default:
+			line, comment, _ := strings.Cut(line, ";")
+
+			if directive, value, ok := strings.Cut(strings.TrimSpace(line), " "); ok && strings.EqualFold(directive, "$ORIGIN") {
+				value = strings.ToLower(strings.TrimSpace(value))
+				if !strings.HasSuffix(value, ".") && origin != "" {
+					value = value + "." + origin
+				}
+				origin = strings.TrimSuffix(value, ".")
+
+				if zone == "" {
+					zone = origin
+				}
+				continue
+			}
+			if strings.HasPrefix(line, "$") {
+				continue
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+
+			// lines starting with whitespace belong to the previous owner name
+			name := previous
+			if line[0] != ' ' && line[0] != '\t' {
+				name = qualify(fields[0])
+				fields = fields[1:]
+			}
+			previous = name
+
+			for i, field := range fields {
+				typ := strings.ToUpper(field)
+				if (typ != "A" && typ != "AAAA") || i+1 >= len(fields) {
+					continue
+				}
+
+				addr, err := netip.ParseAddr(fields[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid address %q for record %q", fields[i+1], name)
+				}
+
+				records = append(records, &models.IPRecord{Name: normalizeRecordName(name, zone), Type: typ, Address: addr.String(), Uuid: recordMarker(comment)})
+
+				break
+			}
+		}
+	}
+
+	return records, scanner.Err()
+}
+
+// recordMarker returns the ip uuid of the export marker in the given comment or an empty string if the comment contains no marker
+func recordMarker(comment string) string {
+	for _, field := range strings.Fields(comment) {
+		if uuid, ok := strings.CutPrefix(field, ipRecordMarker); ok {
+			return uuid
+		}
+	}
+	return ""
+}
+
+func (c *ip) checkRecords(path, format string, allocated []*models.IPRecord) error {
+	if format == "json" {
+		return fmt.Errorf("check is only supported for zonefile and hosts format")
+	}
+
+	f, err := c.c.Fs.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open file: %w", err)
+	}
+	defer f.Close()
+
+	existing, err := parseRecordFile(f, format, strings.TrimSuffix(viper.GetString("zone"), "."))
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+
+	diffs := diffRecords(allocated, existing)
+	if len(diffs) == 0 {
+		_, _ = fmt.Fprintf(c.c.Out, "%s %s is in sync with the allocated ips\n", color.GreenString("✔"), path)
+		return nil
+	}
+
+	err = c.c.ListPrinter.Print(diffs)
+	if err != nil {
+		return err
+	}
+
+	return &helpers.ExitCodeError{Code: 1, Err: fmt.Errorf("%s is out of sync with the allocated ips, %d difference(s) found", path, len(diffs))}
+}
+
+// diffRecords compares the records by name and type, a single differing address on both sides is reported as changed.
+// Existing records are only taken into account if their name is allocated or if they carry the marker of the export.
+func diffRecords(allocated, existing []*models.IPRecord) []*models.IPRecordDiff {
+	type key struct{ name, recordType string }
+
+	managed := map[string]bool{}
+	for _, r := range allocated {
+		managed[r.Name] = true
+	}
+
+	existing = slices.DeleteFunc(slices.Clone(existing), func(r *models.IPRecord) bool {
+		return r.Uuid == "" && !managed[r.Name]
+	})
+
+	var (
+		keys         []key
+		allocatedBy  = map[key][]string{}
+		existingBy   = map[key][]string{}
+		addKeyIfMiss = func(k key) {
+			if _, ok := allocatedBy[k]; ok {
+				return
+			}
+			if _, ok := existingBy[k]; ok {
+				return
+			}
+			keys = append(keys, k)
+		}
+	)
+
+	for _, r := range allocated {
+		k := key{r.Name, r.Type}
+		addKeyIfMiss(k)
+		allocatedBy[k] = append(allocatedBy[k], r.Address)
+	}
+	for _, r := range existing {
+		k := key{r.Name, r.Type}
+		addKeyIfMiss(k)
+		existingBy[k] = append(existingBy[k], r.Address)
+	}
+
+	var diffs []*models.IPRecordDiff
+	for _, k := range keys {
+		var onlyAllocated, onlyExisting []string
+		for _, addr := range allocatedBy[k] {
+			if !slices.Contains(existingBy[k], addr) {
+				onlyAllocated = append(onlyAllocated, addr)
+			}
+		}
+		for _, addr := range existingBy[k] {
+			if !slices.Contains(allocatedBy[k], addr) {
+				onlyExisting = append(onlyExisting, addr)
+			}
+		}
+
+		if len(onlyAllocated) == 1 && len(onlyExisting) == 1 {
+			diffs = append(diffs, &models.IPRecordDiff{Name: k.name, Type: k.recordType, Address: onlyAllocated[0], FileAddress: onlyExisting[0], Status: models.IPRecordDiffChanged})
+			continue
+		}
+
+		for _, addr := range onlyAllocated {
+			diffs = append(diffs, &models.IPRecordDiff{Name: k.name, Type: k.recordType, Address: addr, Status: models.IPRecordDiffMissing})
+		}
+		for _, addr := range onlyExisting {
+			diffs = append(diffs, &models.IPRecordDiff{Name: k.name, Type: k.recordType, FileAddress: addr, Status: models.IPRecordDiffStale})
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	return diffs
+}
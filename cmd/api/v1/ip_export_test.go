@@ -0,0 +1,215 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_normalizeRecordName(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		zone   string
+		want   string
+	}{
+		{name: "plain name", record: "Ingress", want: "ingress"},
+		{name: "invalid characters", record: " my_ingress (prod) ", want: "my-ingress-prod"},
+		{name: "only invalid characters", record: "__", want: ""},
+		{name: "absolute name in zone", record: "ingress.example.com.", zone: "example.com", want: "ingress"},
+		{name: "name in zone", record: "ingress.Example.com", zone: "example.com", want: "ingress"},
+		{name: "name outside of zone", record: "ingress.example.org", zone: "example.com", want: "ingress.example.org"},
+		{name: "apex as absolute name", record: "example.com.", zone: "example.com", want: "@"},
+		{name: "apex as at sign", record: "@", zone: "example.com", want: "@"},
+		{name: "apex as at sign without zone", record: " @ ", want: "@"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, normalizeRecordName(tt.record, tt.zone))
+		})
+	}
+}
+
+func Test_parseRecordFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		zone    string
+		want    []*models.IPRecord
+		wantErr string
+	}{
+		{
+			name: "zone file",
+			content: `$ORIGIN example.com.
+$TTL 300
+@	IN	A	1.1.1.1 ; the apex
+ingress	IN	A	1.1.1.2
+ingress.example.com.	300	IN	AAAA	2001:db8::1
+mail	IN	MX	10 mx.example.com.
+`,
+			format: "zonefile",
+			zone:   "example.com",
+			want: []*models.IPRecord{
+				{Name: "@", Type: "A", Address: "1.1.1.1"},
+				{Name: "ingress", Type: "A", Address: "1.1.1.2"},
+				{Name: "ingress", Type: "AAAA", Address: "2001:db8::1"},
+			},
+		},
+		{
+			name: "zone file with continuation lines",
+			content: `ingress	IN	A	1.1.1.1
+	IN	A	1.1.1.2
+	300	IN	AAAA	2001:db8::1
+
+@	IN	A	1.1.1.3
+  IN	A	1.1.1.4
+`,
+			format: "zonefile",
+			zone:   "example.com",
+			want: []*models.IPRecord{
+				{Name: "ingress", Type: "A", Address: "1.1.1.1"},
+				{Name: "ingress", Type: "A", Address: "1.1.1.2"},
+				{Name: "ingress", Type: "AAAA", Address: "2001:db8::1"},
+				{Name: "@", Type: "A", Address: "1.1.1.3"},
+				{Name: "@", Type: "A", Address: "1.1.1.4"},
+			},
+		},
+		{
+			name: "zone file with origins and markers",
+			content: `$ORIGIN example.com.
+@	IN	A	1.1.1.1	; ip-uuid=2e0144a2-09ef-42b7-b629-4263295db6e8
+$ORIGIN dev
+ingress	IN	A	1.1.1.2 ; managed ip-uuid=9cef40ec-29c6-4dfa-aee8-47ee1f49223d
+$ORIGIN other.org.
+www	IN	A	1.1.1.3
+`,
+			format: "zonefile",
+			want: []*models.IPRecord{
+				{Name: "@", Type: "A", Address: "1.1.1.1", Uuid: "2e0144a2-09ef-42b7-b629-4263295db6e8"},
+				{Name: "ingress.dev", Type: "A", Address: "1.1.1.2", Uuid: "9cef40ec-29c6-4dfa-aee8-47ee1f49223d"},
+				{Name: "www.other.org", Type: "A", Address: "1.1.1.3"},
+			},
+		},
+		{
+			name: "zone file with origin below the zone",
+			content: `$ORIGIN dev.example.com.
+ingress	IN	A	1.1.1.2
+@	IN	A	1.1.1.3
+`,
+			format: "zonefile",
+			zone:   "example.com",
+			want: []*models.IPRecord{
+				{Name: "ingress.dev", Type: "A", Address: "1.1.1.2"},
+				{Name: "dev", Type: "A", Address: "1.1.1.3"},
+			},
+		},
+		{
+			name:    "zone file with invalid address",
+			content: "ingress	IN	A	1.1.1\n",
+			format:  "zonefile",
+			wantErr: `invalid address "1.1.1" for record "ingress"`,
+		},
+		{
+			name: "hosts file with aliases",
+			content: `# generated
+127.0.0.1	localhost
+1.1.1.1	example.com
+1.1.1.2	ingress.example.com ingress www.example.com # the ingress
+2001:db8::1	ingress.example.com ingress	# ip-uuid=9cef40ec-29c6-4dfa-aee8-47ee1f49223d
+not-an-ip	broken
+`,
+			format: "hosts",
+			zone:   "example.com",
+			want: []*models.IPRecord{
+				{Name: "localhost", Type: "A", Address: "127.0.0.1"},
+				{Name: "@", Type: "A", Address: "1.1.1.1"},
+				{Name: "ingress", Type: "A", Address: "1.1.1.2"},
+				{Name: "ingress", Type: "AAAA", Address: "2001:db8::1", Uuid: "9cef40ec-29c6-4dfa-aee8-47ee1f49223d"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRecordFile(strings.NewReader(tt.content), tt.format, tt.zone)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_diffRecords(t *testing.T) {
+	record := func(name, typ, addr string) *models.IPRecord {
+		return &models.IPRecord{Name: name, Type: typ, Address: addr}
+	}
+	marked := func(r *models.IPRecord) *models.IPRecord {
+		r.Uuid = "2e0144a2-09ef-42b7-b629-4263295db6e8"
+		return r
+	}
+
+	tests := []struct {
+		name      string
+		allocated []*models.IPRecord
+		existing  []*models.IPRecord
+		want      []*models.IPRecordDiff
+	}{
+		{
+			name:      "in sync",
+			allocated: []*models.IPRecord{record("@", "A", "1.1.1.1"), record("a", "A", "1.1.1.2")},
+			existing:  []*models.IPRecord{record("a", "A", "1.1.1.2"), record("@", "A", "1.1.1.1")},
+		},
+		{
+			name:      "missing, stale and changed",
+			allocated: []*models.IPRecord{record("a", "A", "1.1.1.1"), record("b", "A", "1.1.1.2"), record("@", "AAAA", "2001:db8::1")},
+			existing:  []*models.IPRecord{record("b", "A", "1.1.1.3"), marked(record("c", "A", "1.1.1.4")), record("@", "AAAA", "2001:db8::1")},
+			want: []*models.IPRecordDiff{
+				{Name: "a", Type: "A", Address: "1.1.1.1", Status: models.IPRecordDiffMissing},
+				{Name: "b", Type: "A", Address: "1.1.1.2", FileAddress: "1.1.1.3", Status: models.IPRecordDiffChanged},
+				{Name: "c", Type: "A", FileAddress: "1.1.1.4", Status: models.IPRecordDiffStale},
+			},
+		},
+		{
+			name:      "multiple addresses of a name are not reported as changed",
+			allocated: []*models.IPRecord{record("a", "A", "1.1.1.1"), record("a", "A", "1.1.1.2")},
+			existing:  []*models.IPRecord{record("a", "A", "1.1.1.3"), record("a", "A", "1.1.1.4"), record("a", "AAAA", "2001:db8::1")},
+			want: []*models.IPRecordDiff{
+				{Name: "a", Type: "A", Address: "1.1.1.1", Status: models.IPRecordDiffMissing},
+				{Name: "a", Type: "A", Address: "1.1.1.2", Status: models.IPRecordDiffMissing},
+				{Name: "a", Type: "A", FileAddress: "1.1.1.3", Status: models.IPRecordDiffStale},
+				{Name: "a", Type: "A", FileAddress: "1.1.1.4", Status: models.IPRecordDiffStale},
+				{Name: "a", Type: "AAAA", FileAddress: "2001:db8::1", Status: models.IPRecordDiffStale},
+			},
+		},
+		{
+			name:      "records of other names are only reported if they were exported",
+			allocated: []*models.IPRecord{record("a", "A", "1.1.1.1")},
+			existing: []*models.IPRecord{
+				record("a", "A", "1.1.1.1"),
+				record("localhost", "A", "127.0.0.1"),
+				record("localhost", "AAAA", "::1"),
+				record("manual", "A", "1.1.1.5"),
+				marked(record("released", "A", "1.1.1.6")),
+			},
+			want: []*models.IPRecordDiff{
+				{Name: "released", Type: "A", FileAddress: "1.1.1.6", Status: models.IPRecordDiffStale},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, diffRecords(tt.allocated, tt.existing)); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
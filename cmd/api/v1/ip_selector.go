@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/spf13/viper"
+)
+
+func (c *ip) updateSelected(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("either an ip or a selector can be given, not both")
+	}
+
+	ips, err := c.selectAndConfirm("update")
+	if err != nil {
+		return err
+	}
+
+	var (
+		updated  []*apiv1.IP
+		failures []error
+	)
+
+	for _, ip := range ips {
+		if viper.IsSet("description") {
+			ip.Description = viper.GetString("description")
+		}
+		if viper.IsSet("static") {
+			ip.Type = ipStaticToType(viper.GetBool("static"))
+		}
+		if viper.IsSet("tags") {
+			ip.Tags = viper.GetStringSlice("tags")
+		}
+
+		resp, err := c.Update(IpResponseToUpdate(ip))
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to update ip %s: %w", ip.Ip, err))
+			continue
+		}
+
+		updated = append(updated, resp)
+	}
+
+	return c.printSelectedResult(updated, failures, len(ips))
+}
+
+func (c *ip) deleteSelected(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("either an ip or a selector can be given, not both")
+	}
+
+	ips, err := c.selectAndConfirm("delete")
+	if err != nil {
+		return err
+	}
+
+	var (
+		deleted  []*apiv1.IP
+		failures []error
+	)
+
+	for _, ip := range ips {
+		resp, err := c.Delete(ip.Uuid)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to delete ip %s: %w", ip.Ip, err))
+			continue
+		}
+
+		deleted = append(deleted, resp)
+	}
+
+	return c.printSelectedResult(deleted, failures, len(ips))
+}
+
+// selectAndConfirm returns the ips matching the selector after the user confirmed the summary of affected ips
+func (c *ip) selectAndConfirm(action string) ([]*apiv1.IP, error) {
+	selectors := viper.GetStringSlice("selector")
+
+	ips, err := c.listSelected(selectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no ips match the selector %q", strings.Join(selectors, ","))
+	}
+
+	if viper.GetBool("skip-security-prompts") {
+		return ips, nil
+	}
+
+	_, _ = fmt.Fprintf(c.c.PromptOut, "The following %d ips match the selector %q:\n\n", len(ips), strings.Join(selectors, ","))
+
+	for _, ip := range ips {
+		_, _ = fmt.Fprintf(c.c.PromptOut, "  %s\t%s\t%s\n", ip.Ip, ip.Name, strings.Join(ip.Tags, ","))
+	}
+
+	_, _ = fmt.Fprintln(c.c.PromptOut)
+
+	err = genericcli.PromptCustom(&genericcli.PromptConfig{
+		Message:         fmt.Sprintf("Do you want to %s these %d ips?", action, len(ips)),
+		ShowAnswers:     true,
+		AcceptedAnswers: genericcli.PromptDefaultAnswers(),
+		DefaultAnswer:   "n",
+		No:              "n",
+		In:              c.c.In,
+		Out:             c.c.PromptOut,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ips, nil
+}
+
+func (c *ip) printSelectedResult(ips []*apiv1.IP, failures []error, total int) error {
+	if len(ips) > 0 {
+		err := c.c.ListPrinter.Print(ips)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d ips failed: %w", len(failures), total, errors.Join(failures...))
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/kubernetes"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ipUsageCredentialsExpiration is the lifetime of the cluster credentials requested for inspecting the services
+const ipUsageCredentialsExpiration = 10 * time.Minute
+
+type ipUsageCluster struct {
+	cluster  *apiv1.Cluster
+	services []kubernetes.ServiceAddresses
+}
+
+func (c *ip) newUsageCmd() *cobra.Command {
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "shows which ips are used by load balancer services of the clusters in the project",
+		Long: `shows which ips are used by load balancer services of the clusters in the project.
+
+The services of every cluster in the project are inspected with short-lived cluster credentials. An ip is used when it is the ingress address, the requested load balancer ip or an external ip of a service. Unused ephemeral ips are flagged for cleanup. If a cluster cannot be inspected, unused ips are reported with status unknown.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.usage()
+		},
+	}
+
+	usageCmd.Flags().StringP("project", "p", "", "project of the ips")
+	usageCmd.Flags().Bool("unused", false, "only shows ips that are not used by any service")
+	usageCmd.Flags().Int("parallelism", 4, "the maximum amount of clusters inspected concurrently")
+
+	genericcli.Must(usageCmd.RegisterFlagCompletionFunc("project", c.c.Completion.ProjectListCompletion))
+
+	return usageCmd
+}
+
+func (c *ip) usage() error {
+	ips, err := c.listSelected()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	clusterResp, err := c.c.Client.Apiv1().Cluster().List(ctx, connect.NewRequest(&apiv1.ClusterServiceListRequest{
+		Project: c.c.GetProject(),
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	clusters, errs := helpers.RunParallel(clusterResp.Msg.Clusters, viper.GetInt("parallelism"), func(_ int, cluster *apiv1.Cluster) (*ipUsageCluster, error) {
+		services, err := c.clusterServices(cluster)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ipUsageCluster{cluster: cluster, services: services}, nil
+	})
+
+	complete := true
+	for i, err := range errs {
+		if err != nil {
+			complete = false
+			_, _ = fmt.Fprintf(c.c.Err, "%s unable to inspect cluster %s (%s): %s\n", color.YellowString("⚠"), clusterResp.Msg.Clusters[i].Name, clusterResp.Msg.Clusters[i].Uuid, err)
+		}
+	}
+
+	var usages []*models.IPUsage
+	for _, ip := range ips {
+		base := models.IPUsage{
+			IP:   ip.Ip,
+			Uuid: ip.Uuid,
+			Name: ip.Name,
+			Type: ipTypeString(ip.Type),
+		}
+
+		used := false
+		for _, cl := range clusters {
+			if cl == nil {
+				continue
+			}
+
+			for _, svc := range cl.services {
+				for _, addr := range svc.IPs {
+					if addr != ip.Ip {
+						continue
+					}
+
+					used = true
+
+					usage := base
+					usage.Cluster = cl.cluster.Name
+					usage.ClusterID = cl.cluster.Uuid
+					usage.Namespace = svc.Namespace
+					usage.Service = svc.Name
+					usage.Status = models.IPUsageStatusUsed
+
+					if !viper.GetBool("unused") {
+						usages = append(usages, &usage)
+					}
+				}
+			}
+		}
+
+		if used {
+			continue
+		}
+
+		usage := base
+		switch {
+		case !complete:
+			usage.Status = models.IPUsageStatusUnknown
+		case ip.Type == apiv1.IPType_IP_TYPE_EPHEMERAL:
+			usage.Status = models.IPUsageStatusCleanup
+		default:
+			usage.Status = models.IPUsageStatusUnused
+		}
+
+		usages = append(usages, &usage)
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool {
+		a, errA := netip.ParseAddr(usages[i].IP)
+		b, errB := netip.ParseAddr(usages[j].IP)
+		if errA != nil || errB != nil {
+			return usages[i].IP < usages[j].IP
+		}
+		return a.Less(b)
+	})
+
+	return c.c.ListPrinter.Print(usages)
+}
+
+func (c *ip) clusterServices(cluster *apiv1.Cluster) ([]kubernetes.ServiceAddresses, error) {
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := c.c.Client.Apiv1().Cluster().GetCredentials(ctx, connect.NewRequest(&apiv1.ClusterServiceGetCredentialsRequest{
+		Uuid:       cluster.Uuid,
+		Project:    cluster.Project,
+		Expiration: durationpb.New(ipUsageCredentialsExpiration),
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster credentials: %w", err)
+	}
+
+	return kubernetes.LoadBalancerAddresses(ctx, []byte(resp.Msg.Kubeconfig))
+}
+
+func ipTypeString(t apiv1.IPType) string {
+	switch t {
+	case apiv1.IPType_IP_TYPE_EPHEMERAL:
+		return "ephemeral"
+	case apiv1.IPType_IP_TYPE_STATIC:
+		return "static"
+	case apiv1.IPType_IP_TYPE_UNSPECIFIED:
+		return "unspecified"
+	default:
+		return t.String()
+	}
+}
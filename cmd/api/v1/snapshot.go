@@ -36,14 +36,27 @@ func newSnapshotCmd(c *config.Config) *cobra.Command {
 			cmd.Flags().StringP("name", "", "", "filter by name")
 			cmd.Flags().StringP("partition", "", "", "filter by partition")
 			cmd.Flags().StringP("project", "p", "", "filter by project")
+			cmd.Flags().StringSlice("selector", nil, "filter by labels of the source volume, e.g. app=db, env!=dev, backup or !temporary")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("partition", c.Completion.PartitionAssetListCompletion))
 		},
 		DeleteCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "filter by project")
+			cmd.Flags().StringSlice("selector", nil, "deletes all snapshots whose source volume matches the given label selectors instead of a single snapshot, e.g. app=db, env!=dev, backup or !temporary")
+
+			cmd.MarkFlagsMutuallyExclusive("selector", "file")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+
+			deleteRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.IsSet("selector") {
+					return w.deleteSelected(args)
+				}
+
+				return deleteRunE(cmd, args)
+			}
 		},
 		DescribeCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "filter by project")
@@ -52,7 +65,7 @@ func newSnapshotCmd(c *config.Config) *cobra.Command {
 		},
 		OnlyCmds: genericcli.OnlyCmds(genericcli.ListCmd, genericcli.DeleteCmd, genericcli.DescribeCmd),
 	}
-	return genericcli.NewCmds(cmdsConfig)
+	return genericcli.NewCmds(cmdsConfig, w.newPruneCmd())
 }
 
 func (s *snapshot) Create(rq any) (*apiv1.Snapshot, error) {
@@ -115,7 +128,7 @@ func (c *snapshot) List() ([]*apiv1.Snapshot, error) {
 		return nil, fmt.Errorf("failed to get snapshots: %w", err)
 	}
 
-	return resp.Msg.Snapshots, nil
+	return c.selectSnapshots(resp.Msg.Snapshots, viper.GetStringSlice("selector")...)
 }
 
 func (c *snapshot) Convert(r *apiv1.Snapshot) (string, any, any, error) {
@@ -0,0 +1,189 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (c *snapshot) newPruneCmd() *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "deletes snapshots according to retention rules",
+		Long: `deletes snapshots according to retention rules.
+
+The rules are applied per source volume: the newest --keep-last snapshots of a volume are always kept. With --older-than, the remaining snapshots are deleted when they are older than the given age, otherwise all remaining snapshots are deleted. The newest snapshot of a volume is never deleted because of its age. The plan is shown and has to be confirmed before any snapshot is deleted. Snapshots can be restricted to volumes matching the --selector on their labels.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.prune()
+		},
+	}
+
+	pruneCmd.Flags().StringP("project", "p", "", "project of the snapshots")
+	pruneCmd.Flags().Int("keep-last", 0, "the amount of newest snapshots kept per volume, 0 disables this rule")
+	pruneCmd.Flags().String("older-than", "", "deletes snapshots older than the given age, e.g. 30d or 2w")
+	pruneCmd.Flags().StringSlice("selector", nil, "only prunes snapshots of volumes matching the labels, e.g. app=db, env!=dev, backup or !temporary")
+	pruneCmd.Flags().Int("parallelism", 4, "the maximum amount of snapshots deleted concurrently")
+	pruneCmd.Flags().Bool("dry-run", false, "only shows the plan without deleting any snapshot")
+	pruneCmd.Flags().Bool("skip-security-prompts", false, "skips the confirmation of the deletion")
+
+	genericcli.Must(pruneCmd.RegisterFlagCompletionFunc("project", c.c.Completion.ProjectListCompletion))
+
+	return pruneCmd
+}
+
+func (c *snapshot) prune() error {
+	keepLast := viper.GetInt("keep-last")
+	if keepLast < 0 {
+		return fmt.Errorf("keep-last must not be negative")
+	}
+
+	var olderThan time.Duration
+	if viper.IsSet("older-than") {
+		var err error
+		olderThan, err = helpers.ParseDuration(viper.GetString("older-than"))
+		if err != nil {
+			return err
+		}
+	}
+
+	if keepLast == 0 && olderThan == 0 {
+		return fmt.Errorf("at least one of --keep-last or --older-than must be given")
+	}
+
+	snapshots, err := c.List()
+	if err != nil {
+		return err
+	}
+
+	plan := planSnapshotPrune(snapshots, keepLast, olderThan, time.Now())
+
+	var deletions []*models.SnapshotPrune
+	for _, p := range plan {
+		if p.Action == models.SnapshotPruneActionDelete {
+			deletions = append(deletions, p)
+		}
+	}
+
+	err = c.c.ListPrinter.Print(plan)
+	if err != nil {
+		return err
+	}
+
+	if len(deletions) == 0 {
+		_, _ = fmt.Fprintf(c.c.Out, "%s no snapshots to prune\n", color.GreenString("✔"))
+		return nil
+	}
+
+	if viper.GetBool("dry-run") {
+		return nil
+	}
+
+	if !viper.GetBool("skip-security-prompts") {
+		err = genericcli.PromptCustom(&genericcli.PromptConfig{
+			Message:         fmt.Sprintf("Do you want to delete %d of %d snapshots?", len(deletions), len(plan)),
+			ShowAnswers:     true,
+			AcceptedAnswers: genericcli.PromptDefaultAnswers(),
+			DefaultAnswer:   "n",
+			No:              "n",
+			In:              c.c.In,
+			Out:             c.c.PromptOut,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, errs := helpers.RunParallel(deletions, viper.GetInt("parallelism"), func(_ int, p *models.SnapshotPrune) (*apiv1.Snapshot, error) {
+		return c.Delete(p.Uuid)
+	})
+
+	var (
+		failures []error
+		freed    uint64
+		volumes  = map[string]bool{}
+	)
+
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to delete snapshot %s: %w", deletions[i].Uuid, err))
+			continue
+		}
+
+		freed += deletions[i].Size
+		volumes[deletions[i].SourceVolumeUuid] = true
+	}
+
+	_, _ = fmt.Fprintf(c.c.Out, "%s deleted %d of %d snapshots of %d volumes, %s freed\n", color.GreenString("✔"), len(deletions)-len(failures), len(deletions), len(volumes), humanize.IBytes(freed))
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d snapshots could not be deleted: %w", len(failures), len(deletions), errors.Join(failures...))
+	}
+
+	return nil
+}
+
+// planSnapshotPrune decides per source volume which snapshots are kept and which are deleted, keep last is a floor
+// which protects the newest snapshots from being deleted by age. The plan is ordered by volume and from the newest to the oldest snapshot.
+func planSnapshotPrune(snapshots []*apiv1.Snapshot, keepLast int, olderThan time.Duration, now time.Time) []*models.SnapshotPrune {
+	byVolume := map[string][]*apiv1.Snapshot{}
+	for _, s := range snapshots {
+		byVolume[s.SourceVolumeUuid] = append(byVolume[s.SourceVolumeUuid], s)
+	}
+
+	var volumes []string
+	for v := range byVolume {
+		volumes = append(volumes, v)
+	}
+	sort.Strings(volumes)
+
+	var plan []*models.SnapshotPrune
+	for _, v := range volumes {
+		list := byVolume[v]
+
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].CreatedAt.AsTime().After(list[j].CreatedAt.AsTime())
+		})
+
+		for i, s := range list {
+			p := &models.SnapshotPrune{
+				Uuid:             s.Uuid,
+				Name:             s.Name,
+				SourceVolumeUuid: s.SourceVolumeUuid,
+				SourceVolumeName: s.SourceVolumeName,
+				Size:             s.Size,
+				Action:           models.SnapshotPruneActionKeep,
+			}
+
+			if s.CreatedAt != nil {
+				p.CreatedAt = pointer.Pointer(s.CreatedAt.AsTime())
+			}
+
+			switch {
+			case i < keepLast, olderThan > 0 && i == 0:
+				// the newest snapshots of a volume are never deleted, regardless of their age
+			case olderThan > 0 && p.CreatedAt != nil && now.Sub(*p.CreatedAt) > olderThan:
+				p.Action = models.SnapshotPruneActionDelete
+				p.Reason = "older than " + strings.TrimSpace(humanize.RelTime(now.Add(-olderThan), now, "", ""))
+			case olderThan == 0 && keepLast > 0:
+				p.Action = models.SnapshotPruneActionDelete
+				p.Reason = fmt.Sprintf("exceeds the newest %d", keepLast)
+			}
+
+			plan = append(plan, p)
+		}
+	}
+
+	return plan
+}
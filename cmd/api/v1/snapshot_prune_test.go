@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_planSnapshotPrune(t *testing.T) {
+	now := time.Date(2022, 5, 19, 1, 2, 3, 0, time.UTC)
+
+	snapshot := func(uuid, volume string, age time.Duration) *apiv1.Snapshot {
+		return &apiv1.Snapshot{Uuid: uuid, SourceVolumeUuid: volume, CreatedAt: timestamppb.New(now.Add(-age))}
+	}
+
+	var (
+		day       = 24 * time.Hour
+		snapshots = []*apiv1.Snapshot{
+			snapshot("a-old", "a", 40*day),
+			snapshot("b-new", "b", 1*day),
+			snapshot("a-new", "a", 1*day),
+			snapshot("a-mid", "a", 10*day),
+			snapshot("b-old", "b", 60*day),
+		}
+	)
+
+	type action struct {
+		uuid   string
+		action string
+		reason string
+	}
+
+	tests := []struct {
+		name      string
+		snapshots []*apiv1.Snapshot
+		keepLast  int
+		olderThan time.Duration
+		want      []action
+	}{
+		{
+			name:      "nothing to prune",
+			snapshots: snapshots,
+			want: []action{
+				{uuid: "a-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-mid", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-old", action: models.SnapshotPruneActionKeep},
+				{uuid: "b-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "b-old", action: models.SnapshotPruneActionKeep},
+			},
+		},
+		{
+			name:      "keep last only",
+			snapshots: snapshots,
+			keepLast:  1,
+			want: []action{
+				{uuid: "a-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-mid", action: models.SnapshotPruneActionDelete, reason: "exceeds the newest 1"},
+				{uuid: "a-old", action: models.SnapshotPruneActionDelete, reason: "exceeds the newest 1"},
+				{uuid: "b-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "b-old", action: models.SnapshotPruneActionDelete, reason: "exceeds the newest 1"},
+			},
+		},
+		{
+			name:      "older than only",
+			snapshots: snapshots,
+			olderThan: 30 * day,
+			want: []action{
+				{uuid: "a-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-mid", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-old", action: models.SnapshotPruneActionDelete, reason: "older than 1 month"},
+				{uuid: "b-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "b-old", action: models.SnapshotPruneActionDelete, reason: "older than 1 month"},
+			},
+		},
+		{
+			name:      "keep last and older than combined",
+			snapshots: snapshots,
+			keepLast:  2,
+			olderThan: 5 * day,
+			want: []action{
+				{uuid: "a-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-mid", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-old", action: models.SnapshotPruneActionDelete, reason: "older than 5 days"},
+				{uuid: "b-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "b-old", action: models.SnapshotPruneActionKeep},
+			},
+		},
+		{
+			name: "keep last protects old snapshots from being deleted by age",
+			snapshots: []*apiv1.Snapshot{
+				snapshot("a-1", "a", 50*day),
+				snapshot("a-2", "a", 40*day),
+				snapshot("a-3", "a", 2*day),
+				snapshot("a-4", "a", 60*day),
+			},
+			keepLast:  2,
+			olderThan: 30 * day,
+			want: []action{
+				{uuid: "a-3", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-2", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-1", action: models.SnapshotPruneActionDelete, reason: "older than 1 month"},
+				{uuid: "a-4", action: models.SnapshotPruneActionDelete, reason: "older than 1 month"},
+			},
+		},
+		{
+			name: "snapshots beyond keep last are kept while they are young",
+			snapshots: []*apiv1.Snapshot{
+				snapshot("a-1", "a", 1*day),
+				snapshot("a-2", "a", 2*day),
+				snapshot("a-3", "a", 3*day),
+			},
+			keepLast:  1,
+			olderThan: 30 * day,
+			want: []action{
+				{uuid: "a-1", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-2", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-3", action: models.SnapshotPruneActionKeep},
+			},
+		},
+		{
+			name: "older than never deletes the last snapshot of a volume",
+			snapshots: []*apiv1.Snapshot{
+				snapshot("a-old", "a", 60*day),
+				snapshot("b-older", "b", 90*day),
+				snapshot("b-old", "b", 60*day),
+			},
+			olderThan: 30 * day,
+			want: []action{
+				{uuid: "a-old", action: models.SnapshotPruneActionKeep},
+				{uuid: "b-old", action: models.SnapshotPruneActionKeep},
+				{uuid: "b-older", action: models.SnapshotPruneActionDelete, reason: "older than 1 month"},
+			},
+		},
+		{
+			name: "snapshots without creation time are never too old and sorted last",
+			snapshots: []*apiv1.Snapshot{
+				{Uuid: "a-unknown", SourceVolumeUuid: "a"},
+				snapshot("a-new", "a", 1*day),
+			},
+			olderThan: 5 * day,
+			want: []action{
+				{uuid: "a-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-unknown", action: models.SnapshotPruneActionKeep},
+			},
+		},
+		{
+			name: "snapshots without creation time count for keep last",
+			snapshots: []*apiv1.Snapshot{
+				{Uuid: "a-unknown", SourceVolumeUuid: "a"},
+				snapshot("a-new", "a", 1*day),
+			},
+			keepLast: 1,
+			want: []action{
+				{uuid: "a-new", action: models.SnapshotPruneActionKeep},
+				{uuid: "a-unknown", action: models.SnapshotPruneActionDelete, reason: "exceeds the newest 1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []action
+			for _, p := range planSnapshotPrune(tt.snapshots, tt.keepLast, tt.olderThan, now) {
+				got = append(got, action{uuid: p.Uuid, action: p.Action, reason: p.Reason})
+			}
+
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("creation time is taken over", func(t *testing.T) {
+		plan := planSnapshotPrune([]*apiv1.Snapshot{{Uuid: "a-unknown", SourceVolumeUuid: "a"}, snapshot("a-new", "a", day)}, 0, 0, now)
+		require.Len(t, plan, 2)
+		require.Equal(t, now.Add(-day), *plan[0].CreatedAt)
+		require.Nil(t, plan[1].CreatedAt)
+	})
+}
@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/spf13/viper"
+)
+
+// selectSnapshots returns the snapshots whose source volume matches the given label selectors,
+// snapshots of deleted volumes never match a selector
+func (c *snapshot) selectSnapshots(snapshots []*apiv1.Snapshot, selectors ...string) ([]*apiv1.Snapshot, error) {
+	if len(selectors) == 0 {
+		return snapshots, nil
+	}
+
+	volumes, err := (&volume{c: c.c}).listSelected(selectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := map[string]bool{}
+	for _, v := range volumes {
+		matching[v.Uuid] = true
+	}
+
+	var result []*apiv1.Snapshot
+	for _, s := range snapshots {
+		if matching[s.SourceVolumeUuid] {
+			result = append(result, s)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *snapshot) deleteSelected(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("either a snapshot or a selector can be given, not both")
+	}
+
+	selectors := viper.GetStringSlice("selector")
+
+	snapshots, err := c.List()
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		return fmt.Errorf("no snapshots match the selector %q", strings.Join(selectors, ","))
+	}
+
+	if !viper.GetBool("skip-security-prompts") {
+		_, _ = fmt.Fprintf(c.c.PromptOut, "The following %d snapshots match the selector %q:\n\n", len(snapshots), strings.Join(selectors, ","))
+
+		for _, s := range snapshots {
+			_, _ = fmt.Fprintf(c.c.PromptOut, "  %s\t%s\t%s\n", s.Uuid, s.Name, s.SourceVolumeName)
+		}
+
+		_, _ = fmt.Fprintln(c.c.PromptOut)
+
+		err = genericcli.PromptCustom(&genericcli.PromptConfig{
+			Message:         fmt.Sprintf("Do you want to delete these %d snapshots?", len(snapshots)),
+			ShowAnswers:     true,
+			AcceptedAnswers: genericcli.PromptDefaultAnswers(),
+			DefaultAnswer:   "n",
+			No:              "n",
+			In:              c.c.In,
+			Out:             c.c.PromptOut,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		deleted  []*apiv1.Snapshot
+		failures []error
+	)
+
+	for _, s := range snapshots {
+		resp, err := c.Delete(s.Uuid)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to delete snapshot %s: %w", s.Uuid, err))
+			continue
+		}
+
+		deleted = append(deleted, resp)
+	}
+
+	if len(deleted) > 0 {
+		err = c.c.ListPrinter.Print(deleted)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d snapshots failed: %w", len(failures), len(snapshots), errors.Join(failures...))
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"connectrpc.com/connect"
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type storageOrphans struct {
+	c *config.Config
+}
+
+func newStorageOrphansCmd(c *config.Config) *cobra.Command {
+	o := &storageOrphans{
+		c: c,
+	}
+
+	orphansCmd := &cobra.Command{
+		Use:   "orphans",
+		Short: "lists volumes and snapshots left behind by deleted clusters and volumes",
+		Long: `lists volumes and snapshots left behind by deleted clusters and volumes.
+
+A volume is orphaned when it is not attached to any node and its cluster reference or one of the given cluster labels points to a cluster that does not exist anymore. Volumes without any cluster reference are never considered orphaned. A snapshot is orphaned when its source volume does not exist anymore.
+
+With --delete the orphans are deleted after confirmation, snapshots first.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.orphans()
+		},
+	}
+
+	orphansCmd.Flags().StringP("project", "p", "", "project of the volumes and snapshots")
+	orphansCmd.Flags().StringSlice("cluster-label", []string{"cluster-id", "cluster-name"}, "volume labels containing the id or name of the cluster a volume belongs to")
+	orphansCmd.Flags().Bool("delete", false, "deletes the orphaned volumes and snapshots")
+	orphansCmd.Flags().Bool("skip-security-prompts", false, "skips the confirmation of the deletion")
+
+	genericcli.Must(orphansCmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+
+	return orphansCmd
+}
+
+func (o *storageOrphans) orphans() error {
+	orphans, err := o.find()
+	if err != nil {
+		return err
+	}
+
+	if len(orphans) == 0 {
+		_, _ = fmt.Fprintf(o.c.Out, "%s no orphaned volumes or snapshots found\n", color.GreenString("✔"))
+		return nil
+	}
+
+	err = o.c.ListPrinter.Print(orphans)
+	if err != nil {
+		return err
+	}
+
+	var reclaimable uint64
+	for _, orphan := range orphans {
+		reclaimable += orphan.Size
+	}
+
+	_, _ = fmt.Fprintf(o.c.Err, "\n%d orphans, %s reclaimable\n", len(orphans), humanize.IBytes(reclaimable))
+
+	if !viper.GetBool("delete") {
+		return nil
+	}
+
+	if !viper.GetBool("skip-security-prompts") {
+		err = genericcli.PromptCustom(&genericcli.PromptConfig{
+			Message:         fmt.Sprintf("Do you want to delete these %d volumes and snapshots? This cannot be undone.", len(orphans)),
+			ShowAnswers:     true,
+			AcceptedAnswers: genericcli.PromptDefaultAnswers(),
+			DefaultAnswer:   "n",
+			No:              "n",
+			In:              o.c.In,
+			Out:             o.c.PromptOut,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return o.delete(orphans)
+}
+
+// find returns the orphaned snapshots followed by the orphaned volumes
+func (o *storageOrphans) find() ([]*models.StorageOrphan, error) {
+	ctx, cancel := o.c.NewRequestContext()
+	defer cancel()
+
+	project := o.c.GetProject()
+
+	clusterResp, err := o.c.Client.Apiv1().Cluster().List(ctx, connect.NewRequest(&apiv1.ClusterServiceListRequest{
+		Project: project,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	volumeResp, err := o.c.Client.Apiv1().Volume().List(ctx, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+		Project: project,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volumes: %w", err)
+	}
+
+	snapshotResp, err := o.c.Client.Apiv1().Snapshot().List(ctx, connect.NewRequest(&apiv1.SnapshotServiceListRequest{
+		Project: project,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots: %w", err)
+	}
+
+	clusters := map[string]bool{}
+	for _, cl := range clusterResp.Msg.Clusters {
+		clusters[cl.Uuid] = true
+		clusters[cl.Name] = true
+	}
+
+	volumes := map[string]bool{}
+	for _, v := range volumeResp.Msg.Volumes {
+		volumes[v.Uuid] = true
+	}
+
+	var snapshots, orphanedVolumes []*models.StorageOrphan
+
+	for _, s := range snapshotResp.Msg.Snapshots {
+		if s.SourceVolumeUuid == "" || volumes[s.SourceVolumeUuid] {
+			continue
+		}
+
+		reference := s.SourceVolumeUuid
+		if s.SourceVolumeName != "" {
+			reference = fmt.Sprintf("%s (%s)", s.SourceVolumeName, s.SourceVolumeUuid)
+		}
+
+		snapshots = append(snapshots, &models.StorageOrphan{
+			Kind:      models.StorageOrphanKindSnapshot,
+			Uuid:      s.Uuid,
+			Name:      s.Name,
+			Size:      s.Size,
+			Partition: s.Partition,
+			Reference: "volume " + reference,
+		})
+	}
+
+	for _, v := range volumeResp.Msg.Volumes {
+		if len(v.AttachedTo) > 0 {
+			continue
+		}
+
+		missing := missingClusterReferences(v, clusters, viper.GetStringSlice("cluster-label"))
+		if len(missing) == 0 {
+			continue
+		}
+
+		orphanedVolumes = append(orphanedVolumes, &models.StorageOrphan{
+			Kind:      models.StorageOrphanKindVolume,
+			Uuid:      v.Uuid,
+			Name:      v.Name,
+			Size:      v.Size,
+			Partition: v.Partition,
+			Reference: "cluster " + missing[0],
+		})
+	}
+
+	for _, list := range [][]*models.StorageOrphan{snapshots, orphanedVolumes} {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].Uuid < list[j].Uuid
+		})
+	}
+
+	return append(snapshots, orphanedVolumes...), nil
+}
+
+// missingClusterReferences returns the cluster references of the volume which do not point to an existing cluster,
+// volumes with at least one reference to an existing cluster are not orphaned.
+func missingClusterReferences(v *apiv1.Volume, clusters map[string]bool, labels []string) []string {
+	var references []string
+	if v.ClusterId != "" {
+		references = append(references, v.ClusterId)
+	}
+	for _, l := range v.Labels {
+		if slices.Contains(labels, l.Key) && l.Value != "" {
+			references = append(references, l.Value)
+		}
+	}
+
+	var missing []string
+	for _, ref := range references {
+		if clusters[ref] {
+			return nil
+		}
+		missing = append(missing, ref)
+	}
+
+	return missing
+}
+
+func (o *storageOrphans) delete(orphans []*models.StorageOrphan) error {
+	var failures []error
+
+	for _, orphan := range orphans {
+		err := o.deleteOrphan(orphan)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to delete %s %s: %w", orphan.Kind, orphan.Uuid, err))
+			continue
+		}
+
+		_, _ = fmt.Fprintf(o.c.Out, "%s deleted %s %s\n", color.GreenString("✔"), orphan.Kind, orphan.Uuid)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d orphans could not be deleted: %w", len(failures), len(orphans), errors.Join(failures...))
+	}
+
+	return nil
+}
+
+func (o *storageOrphans) deleteOrphan(orphan *models.StorageOrphan) error {
+	ctx, cancel := o.c.NewRequestContext()
+	defer cancel()
+
+	var err error
+	switch orphan.Kind {
+	case models.StorageOrphanKindSnapshot:
+		_, err = o.c.Client.Apiv1().Snapshot().Delete(ctx, connect.NewRequest(&apiv1.SnapshotServiceDeleteRequest{
+			Uuid:    orphan.Uuid,
+			Project: o.c.GetProject(),
+		}))
+	case models.StorageOrphanKindVolume:
+		_, err = o.c.Client.Apiv1().Volume().Delete(ctx, connect.NewRequest(&apiv1.VolumeServiceDeleteRequest{
+			Uuid:    orphan.Uuid,
+			Project: o.c.GetProject(),
+		}))
+	default:
+		err = fmt.Errorf("unknown kind %q", orphan.Kind)
+	}
+
+	return err
+}
@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"testing"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_missingClusterReferences(t *testing.T) {
+	clusters := map[string]bool{
+		"c1c7b8a5-0da1-4b8e-a1d5-0b4d0c8b1e01": true,
+		"prod":                                 true,
+	}
+	labels := []string{"cluster"}
+
+	tests := []struct {
+		name   string
+		volume *apiv1.Volume
+		want   []string
+	}{
+		{
+			name:   "no reference",
+			volume: &apiv1.Volume{Uuid: "v1"},
+		},
+		{
+			name:   "live cluster by id",
+			volume: &apiv1.Volume{Uuid: "v1", ClusterId: "c1c7b8a5-0da1-4b8e-a1d5-0b4d0c8b1e01"},
+		},
+		{
+			name:   "live cluster by name",
+			volume: &apiv1.Volume{Uuid: "v1", Labels: []*apiv1.VolumeLabel{{Key: "cluster", Value: "prod"}}},
+		},
+		{
+			name:   "dead cluster by id",
+			volume: &apiv1.Volume{Uuid: "v1", ClusterId: "d2a0c0f1-7f6b-4f55-9d36-1a8d7b1f0c02"},
+			want:   []string{"d2a0c0f1-7f6b-4f55-9d36-1a8d7b1f0c02"},
+		},
+		{
+			name:   "labels not configured as cluster label are ignored",
+			volume: &apiv1.Volume{Uuid: "v1", Labels: []*apiv1.VolumeLabel{{Key: "app", Value: "staging"}, {Key: "cluster", Value: ""}}},
+		},
+		{
+			name: "dead references only",
+			volume: &apiv1.Volume{Uuid: "v1", ClusterId: "d2a0c0f1-7f6b-4f55-9d36-1a8d7b1f0c02", Labels: []*apiv1.VolumeLabel{
+				{Key: "cluster", Value: "staging"},
+			}},
+			want: []string{"d2a0c0f1-7f6b-4f55-9d36-1a8d7b1f0c02", "staging"},
+		},
+		{
+			name: "mixed live and dead references",
+			volume: &apiv1.Volume{Uuid: "v1", ClusterId: "d2a0c0f1-7f6b-4f55-9d36-1a8d7b1f0c02", Labels: []*apiv1.VolumeLabel{
+				{Key: "cluster", Value: "prod"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, missingClusterReferences(tt.volume, clusters, labels))
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package v1
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type storageReport struct {
+	c *config.Config
+}
+
+func newStorageReportCmd(c *config.Config) *cobra.Command {
+	r := &storageReport{
+		c: c,
+	}
+
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "sums the volume and snapshot sizes by project, partition and storage class with an estimated monthly cost",
+		Long: `sums the volume and snapshot sizes by project, partition and storage class with an estimated monthly cost.
+
+All projects are reported unless a project is given. The cost is estimated from the default storage prices: a price applies to a storage class when its name or description contains the storage class, snapshots are priced by a storage price mentioning snapshots if there is one. If only a single storage price exists, it applies to all storage classes. Prices are per GiB and hour unless their unit states otherwise, a month has ` + fmt.Sprint(helpers.HoursPerMonth) + ` hours.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return r.report()
+		},
+	}
+
+	reportCmd.Flags().StringP("project", "p", "", "only reports the given project")
+	reportCmd.Flags().Bool("csv", false, "prints the report as csv instead of the output format")
+
+	genericcli.Must(reportCmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+
+	return reportCmd
+}
+
+func (r *storageReport) report() error {
+	projects, err := r.projects()
+	if err != nil {
+		return err
+	}
+
+	storagePrices, err := r.storagePrices()
+	if err != nil {
+		return err
+	}
+
+	type key struct{ project, partition, storageClass string }
+
+	var (
+		rows   []*models.StorageReportRow
+		byKey  = map[key]*models.StorageReportRow{}
+		getRow = func(project *apiv1.Project, partition, storageClass string) *models.StorageReportRow {
+			k := key{project.Uuid, partition, storageClass}
+			row, ok := byKey[k]
+			if !ok {
+				row = &models.StorageReportRow{
+					Project:      project.Uuid,
+					ProjectName:  project.Name,
+					Partition:    partition,
+					StorageClass: storageClass,
+				}
+				byKey[k] = row
+				rows = append(rows, row)
+			}
+			return row
+		}
+	)
+
+	for _, project := range projects {
+		volumes, err := r.volumes(project.Uuid)
+		if err != nil {
+			return err
+		}
+
+		snapshots, err := r.snapshots(project.Uuid)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range volumes {
+			row := getRow(project, v.Partition, v.StorageClass)
+			row.Volumes++
+			row.VolumeSize += v.Size
+		}
+
+		for _, s := range snapshots {
+			row := getRow(project, s.Partition, s.StorageClass)
+			row.Snapshots++
+			row.SnapshotSize += s.Size
+		}
+	}
+
+	unpriced := map[string]bool{}
+	for _, row := range rows {
+		volumePrice := storageClassPrice(storagePrices, row.StorageClass, false)
+		snapshotPrice := storageClassPrice(storagePrices, row.StorageClass, true)
+
+		if (row.Volumes > 0 && volumePrice == nil) || (row.Snapshots > 0 && snapshotPrice == nil) {
+			unpriced[row.StorageClass] = true
+			continue
+		}
+
+		var cost float64
+		if volumePrice != nil {
+			cost += helpers.MonthlyStorageCost(volumePrice.UnitAmountDecimal, volumePrice.UnitLabel, float64(row.VolumeSize))
+			row.Currency = strings.ToUpper(volumePrice.Currency)
+		}
+		if snapshotPrice != nil {
+			cost += helpers.MonthlyStorageCost(snapshotPrice.UnitAmountDecimal, snapshotPrice.UnitLabel, float64(row.SnapshotSize))
+			row.Currency = strings.ToUpper(snapshotPrice.Currency)
+		}
+
+		row.MonthlyCost = pointer.Pointer(cost)
+	}
+
+	for sc := range unpriced {
+		_, _ = fmt.Fprintf(r.c.Err, "%s no price found for storage class %q, its cost is not estimated\n", color.YellowString("⚠"), sc)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].ProjectName != rows[j].ProjectName {
+			return rows[i].ProjectName < rows[j].ProjectName
+		}
+		if rows[i].Partition != rows[j].Partition {
+			return rows[i].Partition < rows[j].Partition
+		}
+		return rows[i].StorageClass < rows[j].StorageClass
+	})
+
+	if viper.GetBool("csv") {
+		w := csv.NewWriter(r.c.Out)
+
+		err = w.Write(models.StorageReportCSVHeader)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			err = w.Write(row.CSV())
+			if err != nil {
+				return err
+			}
+		}
+
+		w.Flush()
+
+		return w.Error()
+	}
+
+	return r.c.ListPrinter.Print(rows)
+}
+
+func (r *storageReport) projects() ([]*apiv1.Project, error) {
+	ctx, cancel := r.c.NewRequestContext()
+	defer cancel()
+
+	if viper.IsSet("project") {
+		resp, err := r.c.Client.Apiv1().Project().Get(ctx, connect.NewRequest(&apiv1.ProjectServiceGetRequest{
+			Project: viper.GetString("project"),
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project: %w", err)
+		}
+
+		return []*apiv1.Project{resp.Msg.Project}, nil
+	}
+
+	resp, err := r.c.Client.Apiv1().Project().List(ctx, connect.NewRequest(&apiv1.ProjectServiceListRequest{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	return resp.Msg.Projects, nil
+}
+
+// storagePrices returns the default prices of storage products
+func (r *storageReport) storagePrices() ([]*apiv1.Price, error) {
+	ctx, cancel := r.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := r.c.Client.Apiv1().Payment().GetDefaultPrices(ctx, connect.NewRequest(&apiv1.PaymentServiceGetDefaultPricesRequest{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default prices: %w", err)
+	}
+
+	var prices []*apiv1.Price
+	for _, p := range resp.Msg.Prices {
+		if p.ProductType == apiv1.ProductType_PRODUCT_TYPE_STORAGE {
+			prices = append(prices, p)
+		}
+	}
+
+	return prices, nil
+}
+
+func (r *storageReport) volumes(project string) ([]*apiv1.Volume, error) {
+	ctx, cancel := r.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := r.c.Client.Apiv1().Volume().List(ctx, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+		Project: project,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volumes of project %s: %w", project, err)
+	}
+
+	return resp.Msg.Volumes, nil
+}
+
+func (r *storageReport) snapshots(project string) ([]*apiv1.Snapshot, error) {
+	ctx, cancel := r.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := r.c.Client.Apiv1().Snapshot().List(ctx, connect.NewRequest(&apiv1.SnapshotServiceListRequest{
+		Project: project,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots of project %s: %w", project, err)
+	}
+
+	return resp.Msg.Snapshots, nil
+}
+
+// storageClassPrice returns the storage price of the storage class, snapshot prices are preferred for snapshots
+// and the volume price is used if there is no dedicated snapshot price.
+func storageClassPrice(prices []*apiv1.Price, storageClass string, snapshot bool) *apiv1.Price {
+	var volumePrices, snapshotPrices []*apiv1.Price
+	for _, p := range prices {
+		if strings.Contains(strings.ToLower(p.Name+" "+p.Description), "snapshot") {
+			snapshotPrices = append(snapshotPrices, p)
+		} else {
+			volumePrices = append(volumePrices, p)
+		}
+	}
+
+	match := func(candidates []*apiv1.Price) *apiv1.Price {
+		for _, p := range candidates {
+			if storageClass != "" && strings.Contains(strings.ToLower(p.Name+" "+p.Description), strings.ToLower(storageClass)) {
+				return p
+			}
+		}
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+		return nil
+	}
+
+	if snapshot {
+		if p := match(snapshotPrices); p != nil {
+			return p
+		}
+	}
+
+	return match(volumePrices)
+}
@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"testing"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_storageClassPrice(t *testing.T) {
+	var (
+		gold             = &apiv1.Price{Name: "Storage partition-gold"}
+		silver           = &apiv1.Price{Name: "Storage", Description: "volumes of the Partition-Silver class"}
+		bronze           = &apiv1.Price{Name: "Storage partition-bronze"}
+		goldSnapshots    = &apiv1.Price{Name: "Snapshots partition-gold"}
+		genericSnapshots = &apiv1.Price{Name: "Storage", Description: "all snapshots"}
+	)
+
+	tests := []struct {
+		name         string
+		prices       []*apiv1.Price
+		storageClass string
+		snapshot     bool
+		want         *apiv1.Price
+	}{
+		{
+			name:         "no prices",
+			storageClass: "partition-gold",
+		},
+		{
+			name:         "volume price by name",
+			prices:       []*apiv1.Price{silver, gold, goldSnapshots},
+			storageClass: "partition-gold",
+			want:         gold,
+		},
+		{
+			name:         "volume price by description ignoring case",
+			prices:       []*apiv1.Price{gold, silver},
+			storageClass: "partition-silver",
+			want:         silver,
+		},
+		{
+			name:         "volumes are never priced by a snapshot price",
+			prices:       []*apiv1.Price{silver, bronze, goldSnapshots},
+			storageClass: "partition-gold",
+		},
+		{
+			name:         "single volume price applies to all storage classes",
+			prices:       []*apiv1.Price{gold, goldSnapshots},
+			storageClass: "partition-bronze",
+			want:         gold,
+		},
+		{
+			name:         "no match with multiple volume prices",
+			prices:       []*apiv1.Price{gold, silver},
+			storageClass: "partition-bronze",
+		},
+		{
+			name:         "snapshot price of the storage class",
+			prices:       []*apiv1.Price{gold, silver, goldSnapshots, genericSnapshots},
+			storageClass: "partition-gold",
+			snapshot:     true,
+			want:         goldSnapshots,
+		},
+		{
+			name:         "single snapshot price applies to all storage classes",
+			prices:       []*apiv1.Price{gold, silver, genericSnapshots},
+			storageClass: "partition-silver",
+			snapshot:     true,
+			want:         genericSnapshots,
+		},
+		{
+			name:         "snapshots fall back to the volume price",
+			prices:       []*apiv1.Price{gold, silver, goldSnapshots, genericSnapshots},
+			storageClass: "partition-silver",
+			snapshot:     true,
+			want:         silver,
+		},
+		{
+			name:     "empty storage class only matches a single price",
+			prices:   []*apiv1.Price{gold, silver},
+			snapshot: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Same(t, tt.want, storageClassPrice(tt.prices, tt.storageClass, tt.snapshot))
+		})
+	}
+}
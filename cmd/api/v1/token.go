@@ -1,20 +1,30 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"connectrpc.com/connect"
+	"github.com/fatih/color"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 	"github.com/metal-stack-cloud/cli/cmd/sorters"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
 	"github.com/metal-stack/metal-lib/pkg/genericcli"
 	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"sigs.k8s.io/yaml"
 )
 
 type token struct {
@@ -35,89 +45,76 @@ func newTokenCmd(c *config.Config) *cobra.Command {
 		Sorter:          sorters.TokenSorter(),
 		DescribePrinter: func() printers.Printer { return c.DescribePrinter },
 		ListPrinter:     func() printers.Printer { return c.ListPrinter },
-		CreateRequestFromCLI: func() (*apiv1.TokenServiceCreateRequest, error) {
-			var permissions []*apiv1.MethodPermission
-			for _, r := range viper.GetStringSlice("permissions") {
-				project, semicolonSeparatedMethods, ok := strings.Cut(r, "=")
-				if !ok {
-					return nil, fmt.Errorf("permissions must be provided in the form <project>=<methods-colon-separated>")
-				}
-
-				permissions = append(permissions, &apiv1.MethodPermission{
-					Subject: project,
-					Methods: strings.Split(semicolonSeparatedMethods, ":"),
-				})
-			}
-
-			projectRoles := map[string]apiv1.ProjectRole{}
-			for _, r := range viper.GetStringSlice("project-roles") {
-				projectID, roleString, ok := strings.Cut(r, "=")
-				if !ok {
-					return nil, fmt.Errorf("project roles must be provided in the form <project-id>=<role>")
-				}
-
-				role, ok := apiv1.ProjectRole_value[roleString]
-				if !ok {
-					return nil, fmt.Errorf("unknown role: %s", roleString)
-				}
-
-				projectRoles[projectID] = apiv1.ProjectRole(role)
-			}
-
-			tenantRoles := map[string]apiv1.TenantRole{}
-			for _, r := range viper.GetStringSlice("tenant-roles") {
-				tenantID, roleString, ok := strings.Cut(r, "=")
-				if !ok {
-					return nil, fmt.Errorf("tenant roles must be provided in the form <tenant-id>=<role>")
-				}
-
-				role, ok := apiv1.TenantRole_value[roleString]
-				if !ok {
-					return nil, fmt.Errorf("unknown role: %s", roleString)
-				}
-
-				tenantRoles[tenantID] = apiv1.TenantRole(role)
-			}
-
-			var adminRole *apiv1.AdminRole
-			if roleString := viper.GetString("admin-role"); roleString != "" {
-				role, ok := apiv1.AdminRole_value[roleString]
-				if !ok {
-					return nil, fmt.Errorf("unknown role: %s", roleString)
-				}
-
-				adminRole = pointer.Pointer(apiv1.AdminRole(role))
-			}
-
-			return &apiv1.TokenServiceCreateRequest{
-				// TODO: api should have an endpoint to list possible permissions and roles
-				Description:  viper.GetString("description"),
-				Permissions:  permissions,
-				ProjectRoles: projectRoles,
-				TenantRoles:  tenantRoles,
-				AdminRole:    adminRole,
-				Expires:      durationpb.New(viper.GetDuration("expires")),
-			}, nil
-		},
+		CreateRequestFromCLI: w.createRequestFromCLI,
 		CreateCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().String("description", "", "a short description for the intention to use this token for")
-			cmd.Flags().StringSlice("permissions", nil, "the permissions to associate with the api token in the form <project>=<methods-colon-separated>")
+			cmd.Flags().StringSlice("permissions", nil, "the permissions to associate with the api token in the form <project>=<methods-colon-separated>, methods may contain wildcards like metalstack.api.v1.ClusterService/*")
 			cmd.Flags().StringSlice("project-roles", nil, "the project roles to associate with the api token in the form <subject>=<role>")
 			cmd.Flags().StringSlice("tenant-roles", nil, "the tenant roles to associate with the api token in the form <subject>=<role>")
 			cmd.Flags().String("admin-role", "", "the admin role to associate with the api token")
 			cmd.Flags().Duration("expires", 8*time.Hour, "the duration how long the api token is valid")
+			cmd.Flags().StringSlice("presets", nil, "grants a preset of api methods in the form <project>=<preset>, available presets: "+strings.Join(tokenPresetNames(), "|"))
+			cmd.Flags().String("from-file", "", `reads the token definition from a yaml file, flags take precedence. Example:
+
+description: ci pipeline
+expires: 720h
+permissions:
+  - subject: <project-id>
+    presets: [read-only]
+    methods: [metalstack.api.v1.ClusterService/*]
+project-roles:
+  <project-id>: PROJECT_ROLE_VIEWER`)
+			cmd.Flags().Bool("interactive", false, "interactively select the permissions of the token")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("permissions", c.Completion.TokenPermissionsCompletionfunc))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project-roles", c.Completion.TokenProjectRolesCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("tenant-roles", c.Completion.TokenTenantRolesCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("admin-role", c.Completion.TokenAdminRoleCompletion))
+			genericcli.Must(cmd.RegisterFlagCompletionFunc("presets", c.Completion.TokenPresetsCompletion(tokenPresetNames())))
 		},
 		DeleteCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Aliases = append(cmd.Aliases, "revoke")
 		},
 		ValidArgsFn: w.c.Completion.TokenListCompletion,
 	}
-	return genericcli.NewCmds(cmdsConfig)
+
+	rotateCmd := &cobra.Command{
+		Use:   "rotate <id>",
+		Short: "rotates a token while keeping its permissions and roles",
+		Long: `replaces a token with a new one that has the same description, permissions and roles and revokes the old token.
+
+The progress of the rotation is recorded in the config directory, such that an interrupted rotation can be resumed by running the command again.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return w.rotate(args)
+		},
+		ValidArgsFunction: c.Completion.TokenListCompletion,
+	}
+
+	rotateCmd.Flags().Duration("expires", 0, "the duration how long the new token is valid, defaults to the validity of the old token")
+	rotateCmd.Flags().String("context", "", "the name of the context into which the secret of the new token gets written")
+	rotateCmd.Flags().String("secret-file", "", "a path to a file into which the secret of the new token gets written")
+
+	genericcli.Must(rotateCmd.RegisterFlagCompletionFunc("context", c.ContextListCompletion))
+
+	expiryReportCmd := &cobra.Command{
+		Use:   "expiry-report",
+		Short: "reports when the tokens of all contexts expire",
+		Long: `reports when the tokens of all contexts expire. For every context, the token of the context is decoded and the tokens of the user are listed from the api.
+
+The exit code can be used for alerting: 0 if all tokens are valid beyond the warning threshold, 1 if a token expires within the warning threshold, 2 if a token expires within the critical threshold or has expired, 3 if a token could not be inspected and all other tokens are valid beyond the warning threshold. The most severe state of an inspected token takes precedence.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return w.expiryReport()
+		},
+	}
+
+	expiryReportCmd.Flags().Duration("warn", 7*24*time.Hour, "tokens expiring within this duration are reported as warning")
+	expiryReportCmd.Flags().Duration("critical", 24*time.Hour, "tokens expiring within this duration are reported as critical")
+	expiryReportCmd.Flags().StringSlice("contexts", nil, "only report the given contexts, defaults to all contexts")
+	expiryReportCmd.Flags().Bool("local-only", false, "only report the tokens stored in the contexts without listing the tokens from the api")
+
+	genericcli.Must(expiryReportCmd.RegisterFlagCompletionFunc("contexts", c.ContextListCompletion))
+
+	return genericcli.NewCmds(cmdsConfig, rotateCmd, expiryReportCmd)
 }
 
 func (c *token) Get(id string) (*apiv1.Token, error) {
@@ -159,16 +156,20 @@ func (c *token) Create(rq *apiv1.TokenServiceCreateRequest) (*apiv1.Token, error
 		return nil, err
 	}
 
-	_, _ = fmt.Fprintf(c.c.Out, "Make sure to copy your personal access token now as you will not be able to see this again.\n")
-	_, _ = fmt.Fprintln(c.c.Out)
-	_, _ = fmt.Fprintln(c.c.Out, resp.Msg.GetSecret())
-	_, _ = fmt.Fprintln(c.c.Out)
+	c.printSecret(resp.Msg.GetSecret())
 
 	// TODO: allow printer in metal-lib to be silenced
 
 	return resp.Msg.GetToken(), nil
 }
 
+func (c *token) printSecret(secret string) {
+	_, _ = fmt.Fprintf(c.c.Out, "Make sure to copy your personal access token now as you will not be able to see this again.\n")
+	_, _ = fmt.Fprintln(c.c.Out)
+	_, _ = fmt.Fprintln(c.c.Out, secret)
+	_, _ = fmt.Fprintln(c.c.Out)
+}
+
 func (c *token) Delete(id string) (*apiv1.Token, error) {
 	ctx, cancel := c.c.NewRequestContext()
 	defer cancel()
@@ -215,3 +216,333 @@ func (c *token) Convert(r *apiv1.Token) (string, *apiv1.TokenServiceCreateReques
 			AdminRole:    r.AdminRole,
 		}, nil
 }
+
+// tokenRotation is the persisted progress of a token rotation, it never contains the secret of the new token
+type tokenRotation struct {
+	OldUuid       string `json:"old-uuid"`
+	NewUuid       string `json:"new-uuid,omitempty"`
+	SecretWritten bool   `json:"secret-written"`
+}
+
+func (c *token) rotate(args []string) error {
+	id, err := genericcli.GetExactlyOneArg(args)
+	if err != nil {
+		return err
+	}
+
+	statePath, err := tokenRotationStatePath(id)
+	if err != nil {
+		return err
+	}
+
+	state, err := c.readRotationState(statePath)
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		state = &tokenRotation{OldUuid: id}
+
+		old, err := c.Get(id)
+		if err != nil {
+			return err
+		}
+
+		expires := viper.GetDuration("expires")
+		if expires <= 0 {
+			expires = old.GetExpires().AsTime().Sub(old.GetIssuedAt().AsTime())
+		}
+		if expires <= 0 {
+			return fmt.Errorf("unable to derive validity of token %q, please provide --expires", id)
+		}
+
+		ctx, cancel := c.c.NewRequestContext()
+		defer cancel()
+
+		resp, err := c.c.Client.Apiv1().Token().Create(ctx, connect.NewRequest(&apiv1.TokenServiceCreateRequest{
+			Description:  old.GetDescription(),
+			Permissions:  old.GetPermissions(),
+			ProjectRoles: old.GetProjectRoles(),
+			TenantRoles:  old.GetTenantRoles(),
+			AdminRole:    old.AdminRole,
+			Expires:      durationpb.New(expires),
+		}))
+		if err != nil {
+			return fmt.Errorf("failed to create token: %w", err)
+		}
+
+		state.NewUuid = resp.Msg.GetToken().GetUuid()
+
+		err = c.writeRotatedSecret(resp.Msg.GetSecret())
+		if err != nil {
+			// the secret cannot be recovered, so the new token is useless
+			if _, revokeErr := c.Delete(state.NewUuid); revokeErr != nil {
+				return fmt.Errorf("unable to write secret of new token %s, please revoke it manually: %w", state.NewUuid, err)
+			}
+			return fmt.Errorf("unable to write secret of new token, the new token was revoked: %w", err)
+		}
+
+		state.SecretWritten = true
+
+		err = c.writeRotationState(statePath, state)
+		if err != nil {
+			return err
+		}
+	} else {
+		if !state.SecretWritten {
+			return fmt.Errorf("the secret of new token %s was never written and cannot be recovered, please revoke the token and remove %s to restart the rotation", state.NewUuid, statePath)
+		}
+
+		_, _ = fmt.Fprintf(c.c.Out, "%s resuming rotation of token %s, new token is %s\n", color.YellowString("⚠"), state.OldUuid, state.NewUuid)
+	}
+
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	_, err = c.c.Client.Apiv1().Token().Revoke(ctx, connect.NewRequest(&apiv1.TokenServiceRevokeRequest{
+		Uuid: state.OldUuid,
+	}))
+	if err != nil && connect.CodeOf(err) != connect.CodeNotFound {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	err = c.c.Fs.Remove(statePath)
+	if err != nil {
+		return fmt.Errorf("unable to cleanup rotation state: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(c.c.Out, "%s rotated token %s, new token is %s\n", color.GreenString("✔"), state.OldUuid, color.GreenString(state.NewUuid))
+
+	return nil
+}
+
+func (c *token) writeRotatedSecret(secret string) error {
+	if !viper.IsSet("context") && !viper.IsSet("secret-file") {
+		c.printSecret(secret)
+		return nil
+	}
+
+	if viper.IsSet("secret-file") {
+		err := afero.WriteFile(c.c.Fs, viper.GetString("secret-file"), []byte(secret), 0600)
+		if err != nil {
+			return fmt.Errorf("unable to write secret file: %w", err)
+		}
+	}
+
+	if viper.IsSet("context") {
+		ctxs, err := c.c.GetContexts()
+		if err != nil {
+			return err
+		}
+
+		ctx, ok := ctxs.Get(viper.GetString("context"))
+		if !ok {
+			return fmt.Errorf("no context with name %q found", viper.GetString("context"))
+		}
+
+		err = c.c.StoreToken(ctxs, ctx, secret)
+		if err != nil {
+			return err
+		}
+
+		err = c.c.WriteContexts(ctxs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tokenRotationStatePath(id string) (string, error) {
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(path.Dir(configPath), "token-rotations", id+".yaml"), nil
+}
+
+func (c *token) readRotationState(statePath string) (*tokenRotation, error) {
+	raw, err := afero.ReadFile(c.c.Fs, statePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read rotation state: %w", err)
+	}
+
+	var state tokenRotation
+	err = yaml.Unmarshal(raw, &state)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse rotation state %s: %w", statePath, err)
+	}
+
+	return &state, nil
+}
+
+func (c *token) writeRotationState(statePath string, state *tokenRotation) error {
+	raw, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	err = c.c.Fs.MkdirAll(path.Dir(statePath), 0700)
+	if err != nil {
+		return fmt.Errorf("unable to create rotation state directory: %w", err)
+	}
+
+	err = afero.WriteFile(c.c.Fs, statePath, raw, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write rotation state: %w", err)
+	}
+
+	return nil
+}
+
+func (c *token) expiryReport() error {
+	ctxs, err := c.c.GetContexts()
+	if err != nil {
+		return err
+	}
+
+	var (
+		warn     = viper.GetDuration("warn")
+		critical = viper.GetDuration("critical")
+		only     = viper.GetStringSlice("contexts")
+		report   []*models.TokenExpiry
+	)
+
+	for _, ctx := range ctxs.Contexts {
+		if len(only) > 0 && !slices.Contains(only, ctx.Name) {
+			continue
+		}
+
+		report = append(report, c.contextTokenExpiry(ctxs, ctx)...)
+	}
+
+	for _, r := range report {
+		if r.Expires == nil {
+			r.Status = models.TokenExpiryStatusUnknown
+			continue
+		}
+
+		switch remaining := time.Until(*r.Expires); {
+		case remaining <= 0:
+			r.Status = models.TokenExpiryStatusExpired
+		case remaining < critical:
+			r.Status = models.TokenExpiryStatusCritical
+		case remaining < warn:
+			r.Status = models.TokenExpiryStatusWarning
+		default:
+			r.Status = models.TokenExpiryStatusOK
+		}
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].Expires == nil || report[j].Expires == nil {
+			return report[j].Expires == nil && report[i].Expires != nil
+		}
+		return report[i].Expires.Before(*report[j].Expires)
+	})
+
+	err = c.c.ListPrinter.Print(report)
+	if err != nil {
+		return err
+	}
+
+	return expiryReportResult(report, warn, critical)
+}
+
+// expiryReportResult returns an exit code error for the most severe known state of the report,
+// tokens that could not be inspected only determine the exit code if all other tokens are fine.
+func expiryReportResult(report []*models.TokenExpiry, warn, critical time.Duration) error {
+	count := map[string]int{}
+	for _, r := range report {
+		count[r.Status]++
+	}
+
+	var unknown string
+	if n := count[models.TokenExpiryStatusUnknown]; n > 0 {
+		unknown = fmt.Sprintf(", %d token(s) could not be inspected", n)
+	}
+
+	switch {
+	case count[models.TokenExpiryStatusExpired]+count[models.TokenExpiryStatusCritical] > 0:
+		return &helpers.ExitCodeError{Code: 2, Err: fmt.Errorf("%d token(s) expired or expiring within %s%s", count[models.TokenExpiryStatusExpired]+count[models.TokenExpiryStatusCritical], helpers.HumanizeDuration(critical), unknown)}
+	case count[models.TokenExpiryStatusWarning] > 0:
+		return &helpers.ExitCodeError{Code: 1, Err: fmt.Errorf("%d token(s) expiring within %s%s", count[models.TokenExpiryStatusWarning], helpers.HumanizeDuration(warn), unknown)}
+	case count[models.TokenExpiryStatusUnknown] > 0:
+		return &helpers.ExitCodeError{Code: 3, Err: fmt.Errorf("%d token(s) could not be inspected", count[models.TokenExpiryStatusUnknown])}
+	default:
+		return nil
+	}
+}
+
+func (c *token) contextTokenExpiry(ctxs *config.Contexts, ctx *config.Context) []*models.TokenExpiry {
+	local := &models.TokenExpiry{
+		Context: ctx.Name,
+		Source:  "context",
+	}
+
+	secret, err := c.c.LookupToken(ctxs, ctx)
+	if err != nil {
+		local.Error = err.Error()
+		return []*models.TokenExpiry{local}
+	}
+	if secret == "" {
+		return nil
+	}
+
+	claims, err := helpers.ParseTokenClaims(secret)
+	if err != nil {
+		local.Error = fmt.Sprintf("unable to decode token: %s", err)
+		return []*models.TokenExpiry{local}
+	}
+
+	local.ID = claims.ID
+	local.Type = claims.Type
+	if claims.ExpiresAt != nil {
+		local.Expires = pointer.Pointer(claims.ExpiresAt.Time)
+	}
+
+	result := []*models.TokenExpiry{local}
+
+	if viper.GetBool("local-only") || (local.Expires != nil && local.Expires.Before(time.Now())) {
+		return result
+	}
+
+	apiURL := pointer.SafeDeref(ctx.ApiURL)
+	if apiURL == "" {
+		apiURL = viper.GetString("api-url")
+	}
+
+	reqCtx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := config.NewApiClient(apiURL, secret).Apiv1().Token().List(reqCtx, connect.NewRequest(&apiv1.TokenServiceListRequest{}))
+	if err != nil {
+		return append(result, &models.TokenExpiry{
+			Context: ctx.Name,
+			Source:  "api",
+			Error:   fmt.Sprintf("failed to list tokens: %s", err),
+		})
+	}
+
+	for _, t := range resp.Msg.GetTokens() {
+		if t.Uuid == local.ID {
+			continue
+		}
+
+		result = append(result, &models.TokenExpiry{
+			Context:     ctx.Name,
+			Source:      "api",
+			ID:          t.Uuid,
+			Type:        t.TokenType.String(),
+			Description: t.Description,
+			Expires:     pointer.Pointer(t.GetExpires().AsTime()),
+		})
+	}
+
+	return result
+}
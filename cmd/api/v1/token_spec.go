@@ -0,0 +1,393 @@
+package v1
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"sigs.k8s.io/yaml"
+)
+
+// tokenPresets are sets of method patterns that can be granted to a token with a single name
+var tokenPresets = map[string][]string{
+	"read-only": {
+		"*/Get",
+		"*/List",
+	},
+	"cluster-operator": {
+		"metalstack.api.v1.ClusterService/*",
+		"metalstack.api.v1.IPService/*",
+		"metalstack.api.v1.VolumeService/*",
+		"metalstack.api.v1.SnapshotService/*",
+		"metalstack.api.v1.AssetService/List",
+		"metalstack.api.v1.HealthService/Get",
+	},
+}
+
+func tokenPresetNames() []string {
+	var names []string
+	for name := range tokenPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// tokenSpec is a token definition as it can be provided through --from-file
+//
+//	description: ci pipeline
+//	expires: 720h
+//	permissions:
+//	  - subject: <project-id>
+//	    presets: [read-only]
+//	    methods: [metalstack.api.v1.ClusterService/*]
+//	project-roles:
+//	  <project-id>: PROJECT_ROLE_VIEWER
+//	tenant-roles:
+//	  <tenant-id>: TENANT_ROLE_VIEWER
+type tokenSpec struct {
+	Description  string                `json:"description,omitempty"`
+	Expires      string                `json:"expires,omitempty"`
+	Permissions  []tokenSpecPermission `json:"permissions,omitempty"`
+	ProjectRoles map[string]string     `json:"project-roles,omitempty"`
+	TenantRoles  map[string]string     `json:"tenant-roles,omitempty"`
+	AdminRole    string                `json:"admin-role,omitempty"`
+}
+
+type tokenSpecPermission struct {
+	Subject string   `json:"subject"`
+	Methods []string `json:"methods,omitempty"`
+	Presets []string `json:"presets,omitempty"`
+}
+
+func (c *token) createRequestFromCLI() (*apiv1.TokenServiceCreateRequest, error) {
+	spec := &tokenSpec{}
+
+	if viper.IsSet("from-file") {
+		raw, err := afero.ReadFile(c.c.Fs, viper.GetString("from-file"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read token spec: %w", err)
+		}
+
+		err = yaml.UnmarshalStrict(raw, spec)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse token spec: %w", err)
+		}
+	}
+
+	if viper.IsSet("description") || spec.Description == "" {
+		spec.Description = viper.GetString("description")
+	}
+	if viper.IsSet("expires") || spec.Expires == "" {
+		spec.Expires = viper.GetDuration("expires").String()
+	}
+	if viper.IsSet("admin-role") {
+		spec.AdminRole = viper.GetString("admin-role")
+	}
+
+	for _, r := range viper.GetStringSlice("permissions") {
+		project, semicolonSeparatedMethods, ok := strings.Cut(r, "=")
+		if !ok {
+			return nil, fmt.Errorf("permissions must be provided in the form <project>=<methods-colon-separated>")
+		}
+
+		spec.Permissions = append(spec.Permissions, tokenSpecPermission{
+			Subject: project,
+			Methods: strings.Split(semicolonSeparatedMethods, ":"),
+		})
+	}
+
+	for _, r := range viper.GetStringSlice("presets") {
+		project, preset, ok := strings.Cut(r, "=")
+		if !ok {
+			return nil, fmt.Errorf("presets must be provided in the form <project>=<preset>")
+		}
+
+		spec.Permissions = append(spec.Permissions, tokenSpecPermission{
+			Subject: project,
+			Presets: []string{preset},
+		})
+	}
+
+	for key, target := range map[string]*map[string]string{"project-roles": &spec.ProjectRoles, "tenant-roles": &spec.TenantRoles} {
+		for _, r := range viper.GetStringSlice(key) {
+			subject, role, ok := strings.Cut(r, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s must be provided in the form <subject>=<role>", strings.ReplaceAll(key, "-", " "))
+			}
+
+			if *target == nil {
+				*target = map[string]string{}
+			}
+
+			(*target)[subject] = role
+		}
+	}
+
+	if viper.GetBool("interactive") {
+		err := c.pickInteractive(spec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c.createRequestFromSpec(spec)
+}
+
+func (c *token) createRequestFromSpec(spec *tokenSpec) (*apiv1.TokenServiceCreateRequest, error) {
+	expires, err := time.ParseDuration(spec.Expires)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expires duration: %w", err)
+	}
+
+	var permissions []*apiv1.MethodPermission
+	if len(spec.Permissions) > 0 {
+		available, err := c.listMethods()
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			patternsBySubject = map[string][]string{}
+			presetsBySubject  = map[string][]string{}
+			subjects          []string
+		)
+
+		for _, p := range spec.Permissions {
+			if p.Subject == "" {
+				return nil, fmt.Errorf("permissions require a subject")
+			}
+
+			if !slices.Contains(subjects, p.Subject) {
+				subjects = append(subjects, p.Subject)
+			}
+
+			patternsBySubject[p.Subject] = append(patternsBySubject[p.Subject], p.Methods...)
+
+			for _, preset := range p.Presets {
+				if _, ok := tokenPresets[preset]; !ok {
+					return nil, fmt.Errorf("unknown preset %q, must be one of %s", preset, strings.Join(tokenPresetNames(), "|"))
+				}
+
+				presetsBySubject[p.Subject] = append(presetsBySubject[p.Subject], preset)
+			}
+		}
+
+		for _, subject := range subjects {
+			// the patterns given by the user must all exist
+			methods, err := helpers.ExpandMethodPatterns(patternsBySubject[subject], available)
+			if err != nil {
+				return nil, fmt.Errorf("invalid permissions for %q: %w", subject, err)
+			}
+
+			// presets are expanded leniently as not every api offers all of the methods of a preset
+			for _, preset := range presetsBySubject[subject] {
+				presetMethods, unmatched, err := helpers.MatchMethodPatterns(tokenPresets[preset], available)
+				if err != nil {
+					return nil, err
+				}
+
+				if len(unmatched) > 0 {
+					_, _ = fmt.Fprintf(c.c.Err, "%s skipping %s of preset %q for %q, no such api methods found\n", color.YellowString("⚠"), strings.Join(unmatched, ", "), preset, subject)
+				}
+
+				methods = append(methods, presetMethods...)
+			}
+
+			slices.Sort(methods)
+
+			permissions = append(permissions, &apiv1.MethodPermission{
+				Subject: subject,
+				Methods: slices.Compact(methods),
+			})
+		}
+	}
+
+	projectRoles := map[string]apiv1.ProjectRole{}
+	for projectID, roleString := range spec.ProjectRoles {
+		role, ok := apiv1.ProjectRole_value[roleString]
+		if !ok {
+			return nil, fmt.Errorf("unknown role: %s", roleString)
+		}
+
+		projectRoles[projectID] = apiv1.ProjectRole(role)
+	}
+
+	tenantRoles := map[string]apiv1.TenantRole{}
+	for tenantID, roleString := range spec.TenantRoles {
+		role, ok := apiv1.TenantRole_value[roleString]
+		if !ok {
+			return nil, fmt.Errorf("unknown role: %s", roleString)
+		}
+
+		tenantRoles[tenantID] = apiv1.TenantRole(role)
+	}
+
+	var adminRole *apiv1.AdminRole
+	if spec.AdminRole != "" {
+		role, ok := apiv1.AdminRole_value[spec.AdminRole]
+		if !ok {
+			return nil, fmt.Errorf("unknown role: %s", spec.AdminRole)
+		}
+
+		adminRole = pointer.Pointer(apiv1.AdminRole(role))
+	}
+
+	return &apiv1.TokenServiceCreateRequest{
+		Description:  spec.Description,
+		Permissions:  permissions,
+		ProjectRoles: projectRoles,
+		TenantRoles:  tenantRoles,
+		AdminRole:    adminRole,
+		Expires:      durationpb.New(expires),
+	}, nil
+}
+
+func (c *token) listMethods() ([]string, error) {
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := c.c.Client.Apiv1().Method().List(ctx, connect.NewRequest(&apiv1.MethodServiceListRequest{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list methods: %w", err)
+	}
+
+	return resp.Msg.GetMethods(), nil
+}
+
+// pickInteractive asks the user for the token definition, values already contained in the spec are used as defaults
+func (c *token) pickInteractive(spec *tokenSpec) error {
+	var (
+		in  = bufio.NewReader(c.c.In)
+		out = c.c.PromptOut
+	)
+
+	ask := func(question, defaultAnswer string) (string, error) {
+		if defaultAnswer != "" {
+			_, _ = fmt.Fprintf(out, "%s [%s]: ", question, defaultAnswer)
+		} else {
+			_, _ = fmt.Fprintf(out, "%s: ", question)
+		}
+
+		answer, err := in.ReadString('\n')
+		if err != nil && answer == "" {
+			return "", fmt.Errorf("unable to read answer: %w", err)
+		}
+
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			return defaultAnswer, nil
+		}
+
+		return answer, nil
+	}
+
+	var err error
+
+	spec.Description, err = ask("Description", spec.Description)
+	if err != nil {
+		return err
+	}
+
+	spec.Expires, err = ask("Expires after", spec.Expires)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	projectResp, err := c.c.Client.Apiv1().Project().List(ctx, connect.NewRequest(&apiv1.ProjectServiceListRequest{}))
+	if err != nil {
+		return fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	available, err := c.listMethods()
+	if err != nil {
+		return err
+	}
+
+	var services []string
+	for _, method := range available {
+		service, _, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+		if ok {
+			services = append(services, service+"/*")
+		}
+	}
+	slices.Sort(services)
+	services = slices.Compact(services)
+
+	choices := append(tokenPresetNames(), services...)
+
+	for {
+		_, _ = fmt.Fprintln(out, "Projects:")
+		for i, p := range projectResp.Msg.GetProjects() {
+			_, _ = fmt.Fprintf(out, "  %2d) %s (%s)\n", i+1, p.Name, p.Uuid)
+		}
+
+		answer, err := ask("Grant permissions for project (number or id, empty to finish)", "")
+		if err != nil {
+			return err
+		}
+		if answer == "" {
+			return nil
+		}
+
+		subject := answer
+		if idx, err := strconv.Atoi(answer); err == nil {
+			if idx < 1 || idx > len(projectResp.Msg.GetProjects()) {
+				_, _ = fmt.Fprintf(out, "invalid choice %d\n", idx)
+				continue
+			}
+
+			subject = projectResp.Msg.GetProjects()[idx-1].Uuid
+		}
+
+		_, _ = fmt.Fprintln(out, "Presets and services:")
+		for i, choice := range choices {
+			_, _ = fmt.Fprintf(out, "  %2d) %s\n", i+1, choice)
+		}
+
+		answer, err = ask("Select comma-separated numbers or method patterns", "")
+		if err != nil {
+			return err
+		}
+
+		permission := tokenSpecPermission{Subject: subject}
+
+		for _, selection := range strings.Split(answer, ",") {
+			selection = strings.TrimSpace(selection)
+			if selection == "" {
+				continue
+			}
+
+			if idx, err := strconv.Atoi(selection); err == nil && idx >= 1 && idx <= len(choices) {
+				selection = choices[idx-1]
+			}
+
+			if _, ok := tokenPresets[selection]; ok {
+				permission.Presets = append(permission.Presets, selection)
+			} else {
+				permission.Methods = append(permission.Methods, selection)
+			}
+		}
+
+		if _, err := helpers.ExpandMethodPatterns(permission.Methods, available); err != nil {
+			_, _ = fmt.Fprintf(out, "%s\n", err)
+			continue
+		}
+
+		spec.Permissions = append(spec.Permissions, permission)
+	}
+}
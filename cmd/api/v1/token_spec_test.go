@@ -0,0 +1,112 @@
+package v1
+
+import (
+	"bytes"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/google/go-cmp/cmp"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	apitests "github.com/metal-stack-cloud/api/go/tests"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_token_createRequestFromSpec(t *testing.T) {
+	// the api does not offer every method contained in the presets
+	available := []string{
+		"/metalstack.api.v1.ClusterService/Get",
+		"/metalstack.api.v1.ClusterService/List",
+		"/metalstack.api.v1.IPService/Get",
+		"/metalstack.api.v1.AssetService/List",
+	}
+
+	tests := []struct {
+		name        string
+		spec        *tokenSpec
+		want        []*apiv1.MethodPermission
+		wantWarning string
+		wantErr     string
+	}{
+		{
+			name: "preset with methods missing in the api",
+			spec: &tokenSpec{
+				Expires:     "1h",
+				Permissions: []tokenSpecPermission{{Subject: "p1", Presets: []string{"cluster-operator"}}},
+			},
+			want: []*apiv1.MethodPermission{{
+				Subject: "p1",
+				Methods: []string{
+					"/metalstack.api.v1.AssetService/List",
+					"/metalstack.api.v1.ClusterService/Get",
+					"/metalstack.api.v1.ClusterService/List",
+					"/metalstack.api.v1.IPService/Get",
+				},
+			}},
+			wantWarning: `skipping metalstack.api.v1.VolumeService/*, metalstack.api.v1.SnapshotService/*, metalstack.api.v1.HealthService/Get of preset "cluster-operator" for "p1"`,
+		},
+		{
+			name: "preset combined with methods",
+			spec: &tokenSpec{
+				Expires:     "1h",
+				Permissions: []tokenSpecPermission{{Subject: "p1", Presets: []string{"read-only"}, Methods: []string{"metalstack.api.v1.IPService/Get"}}},
+			},
+			want: []*apiv1.MethodPermission{{
+				Subject: "p1",
+				Methods: []string{
+					"/metalstack.api.v1.AssetService/List",
+					"/metalstack.api.v1.ClusterService/Get",
+					"/metalstack.api.v1.ClusterService/List",
+					"/metalstack.api.v1.IPService/Get",
+				},
+			}},
+		},
+		{
+			name: "methods given by the user must exist",
+			spec: &tokenSpec{
+				Expires:     "1h",
+				Permissions: []tokenSpecPermission{{Subject: "p1", Presets: []string{"read-only"}, Methods: []string{"metalstack.api.v1.HealthService/Get"}}},
+			},
+			wantErr: `invalid permissions for "p1": no api methods found for metalstack.api.v1.HealthService/Get, see api-methods for available methods`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errOut bytes.Buffer
+
+			c := &token{
+				c: &config.Config{
+					Err: &errOut,
+					Client: apitests.New(t).Client(&apitests.ClientMockFns{
+						Apiv1Mocks: &apitests.Apiv1MockFns{
+							Method: func(m *mock.Mock) {
+								m.On("List", mock.Anything, connect.NewRequest(&apiv1.MethodServiceListRequest{})).Return(&connect.Response[apiv1.MethodServiceListResponse]{
+									Msg: &apiv1.MethodServiceListResponse{Methods: available},
+								}, nil)
+							},
+						},
+					}),
+				},
+			}
+
+			got, err := c.createRequestFromSpec(tt.spec)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if diff := cmp.Diff(tt.want, got.Permissions, testcommon.IgnoreUnexported()); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+
+			if tt.wantWarning == "" {
+				require.Empty(t, errOut.String())
+			} else {
+				require.Contains(t, errOut.String(), tt.wantWarning)
+			}
+		})
+	}
+}
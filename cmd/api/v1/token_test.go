@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_expiryReportResult(t *testing.T) {
+	report := func(statuses ...string) []*models.TokenExpiry {
+		var result []*models.TokenExpiry
+		for _, s := range statuses {
+			result = append(result, &models.TokenExpiry{Status: s})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		report   []*models.TokenExpiry
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:   "all ok",
+			report: report(models.TokenExpiryStatusOK, models.TokenExpiryStatusOK),
+		},
+		{
+			name:     "warning",
+			report:   report(models.TokenExpiryStatusOK, models.TokenExpiryStatusWarning),
+			wantCode: 1,
+			wantErr:  "1 token(s) expiring within 7d",
+		},
+		{
+			name:     "expired and critical",
+			report:   report(models.TokenExpiryStatusExpired, models.TokenExpiryStatusCritical, models.TokenExpiryStatusWarning),
+			wantCode: 2,
+			wantErr:  "2 token(s) expired or expiring within 1d",
+		},
+		{
+			name:     "unknown does not hide an expired token",
+			report:   report(models.TokenExpiryStatusUnknown, models.TokenExpiryStatusExpired),
+			wantCode: 2,
+			wantErr:  "1 token(s) expired or expiring within 1d, 1 token(s) could not be inspected",
+		},
+		{
+			name:     "unknown does not hide a warning",
+			report:   report(models.TokenExpiryStatusWarning, models.TokenExpiryStatusUnknown),
+			wantCode: 1,
+			wantErr:  "1 token(s) expiring within 7d, 1 token(s) could not be inspected",
+		},
+		{
+			name:     "only unknown",
+			report:   report(models.TokenExpiryStatusOK, models.TokenExpiryStatusUnknown),
+			wantCode: 3,
+			wantErr:  "1 token(s) could not be inspected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := expiryReportResult(tt.report, 7*24*time.Hour, 24*time.Hour)
+			if tt.wantCode == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var exitErr *helpers.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			require.Equal(t, tt.wantCode, exitErr.Code)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -1,13 +1,18 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/fatih/color"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/kubernetes"
 	"github.com/metal-stack-cloud/cli/cmd/sorters"
 	"github.com/metal-stack-cloud/cli/pkg/helpers"
 	"github.com/metal-stack/metal-lib/pkg/genericcli"
@@ -15,12 +20,21 @@ import (
 	"github.com/metal-stack/metal-lib/pkg/pointer"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/yaml"
 )
 
+// volumeClusterCredentialsExpiration is the lifetime of the cluster credentials requested for applying manifests and inspecting volumes
+const volumeClusterCredentialsExpiration = 10 * time.Minute
+
+var invalidManifestNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
 type volume struct {
 	c *config.Config
 }
@@ -45,14 +59,27 @@ func newVolumeCmd(c *config.Config) *cobra.Command {
 			cmd.Flags().StringP("name", "", "", "filter by name")
 			cmd.Flags().StringP("partition", "", "", "filter by partition")
 			cmd.Flags().StringP("project", "p", "", "filter by project")
+			cmd.Flags().StringSlice("selector", nil, "filter by labels, e.g. app=db, env!=dev, backup or !temporary")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("partition", c.Completion.PartitionAssetListCompletion))
 		},
 		DeleteCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "filter by project")
+			cmd.Flags().StringSlice("selector", nil, "deletes all volumes matching the given label selectors instead of a single volume, e.g. app=db, env!=dev, backup or !temporary")
+
+			cmd.MarkFlagsMutuallyExclusive("selector", "file")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+
+			deleteRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.IsSet("selector") {
+					return w.deleteSelected(args)
+				}
+
+				return deleteRunE(cmd, args)
+			}
 		},
 		DescribeCmdMutateFn: func(cmd *cobra.Command) {
 			cmd.Flags().StringP("project", "p", "", "filter by project")
@@ -63,39 +90,77 @@ func newVolumeCmd(c *config.Config) *cobra.Command {
 			cmd.Flags().StringP("project", "p", "", "filter by project")
 			cmd.Flags().StringArray("add-label", nil, "adds the volume labels in the form of <key>=<value>")
 			cmd.Flags().StringArray("remove-label", nil, "removes the volume labels with the given key")
+			cmd.Flags().StringSlice("selector", nil, "updates the labels of all volumes matching the given label selectors instead of a single volume, e.g. app=db, env!=dev, backup or !temporary")
+
+			cmd.MarkFlagsMutuallyExclusive("selector", "file")
 
 			genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+
+			updateRunE := cmd.RunE
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if viper.IsSet("selector") {
+					return w.updateSelected(args)
+				}
+
+				return updateRunE(cmd, args)
+			}
 		},
 		UpdateRequestFromCLI: w.updateFromCLI,
 		OnlyCmds:             genericcli.OnlyCmds(genericcli.ListCmd, genericcli.DescribeCmd, genericcli.DeleteCmd, genericcli.UpdateCmd),
 	}
 
 	manifestCmd := &cobra.Command{
-		Use:   "manifest",
+		Use:   "manifest [<volume-uuid>...]",
 		Short: "volume manifest",
-		Long:  "show detailed info about the storage cluster",
+		Long: `generates a PersistentVolume and a bound PersistentVolumeClaim for every given volume, e.g. to re-attach existing volumes in a new cluster.
+
+Volumes can either be given by their uuids or be selected by their labels with --selector. When a single volume is given, the manifests are named by --name, otherwise they are named after the volumes. Volume names are converted to valid object names, volumes without a valid or with a duplicate name are named by their uuid.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return w.volumeManifest(args)
 		},
+		ValidArgsFunction: c.Completion.VolumeListCompletion,
 	}
-	manifestCmd.Flags().StringP("name", "", "restored-pv", "name of the PersistentVolume")
-	manifestCmd.Flags().StringP("namespace", "", "default", "namespace for the PersistentVolume")
+	manifestCmd.Flags().StringP("name", "", "restored-pv", "name of the PersistentVolume and PersistentVolumeClaim, only applicable for a single volume")
+	manifestCmd.Flags().StringP("namespace", "", "default", "namespace for the PersistentVolumeClaim")
 	manifestCmd.Flags().StringP("project", "p", "", "project")
+	manifestCmd.Flags().StringSlice("selector", nil, "selects the volumes by labels instead of uuids, e.g. app=db, env!=dev, backup or !temporary")
+	manifestCmd.Flags().String("fs-type", "ext4", "filesystem type of the volume")
+	manifestCmd.Flags().String("access-mode", string(corev1.ReadWriteOnce), "access mode of the volume, can be ReadWriteOnce, ReadWriteOncePod, ReadOnlyMany or ReadWriteMany")
+	manifestCmd.Flags().String("reclaim-policy", string(corev1.PersistentVolumeReclaimRetain), "reclaim policy of the PersistentVolume, can be Retain or Delete")
+	manifestCmd.Flags().String("apply-to-cluster", "", "creates or updates the manifests directly in the cluster with the given id instead of printing them")
 
 	genericcli.Must(manifestCmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+	genericcli.Must(manifestCmd.RegisterFlagCompletionFunc("fs-type", cobra.FixedCompletions([]string{"ext4", "xfs"}, cobra.ShellCompDirectiveNoFileComp)))
+	genericcli.Must(manifestCmd.RegisterFlagCompletionFunc("access-mode", cobra.FixedCompletions([]string{string(corev1.ReadWriteOnce), string(corev1.ReadWriteOncePod), string(corev1.ReadOnlyMany), string(corev1.ReadWriteMany)}, cobra.ShellCompDirectiveNoFileComp)))
+	genericcli.Must(manifestCmd.RegisterFlagCompletionFunc("apply-to-cluster", c.Completion.ClusterListCompletion))
+	genericcli.Must(manifestCmd.RegisterFlagCompletionFunc("reclaim-policy", cobra.FixedCompletions([]string{string(corev1.PersistentVolumeReclaimRetain), string(corev1.PersistentVolumeReclaimDelete)}, cobra.ShellCompDirectiveNoFileComp)))
 
 	encryptionSecretCmd := &cobra.Command{
 		Use:   "encryptionsecret",
 		Short: "volume encryptionsecret template",
-		Long:  "generate volume encryptionsecret template",
+		Long: `generate volume encryptionsecret template.
+
+The passphrase is read from --passphrase-file, where - reads it from stdin, taken from an escrow file with --from-escrow, generated randomly with --generate or prompted for. With --escrow-file an encrypted copy of the passphrase is stored locally, the passphrase of the escrow file is prompted for or read from the environment variable ` + volumeEscrowPassphraseEnv + `.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return w.volumeEncryptionSecretManifest()
 		},
 	}
-	encryptionSecretCmd.Flags().StringP("passphrase", "", "", "passphrase")
+	encryptionSecretCmd.Flags().StringP("passphrase", "", "", "passphrase, discouraged as it is exposed in the shell history and the process list")
+	encryptionSecretCmd.Flags().String("passphrase-file", "", "reads the passphrase from the first line of the given file, - reads from stdin")
+	encryptionSecretCmd.Flags().Bool("generate", false, "generates a strong random passphrase")
+	encryptionSecretCmd.Flags().String("from-escrow", "", "takes the passphrase from the given escrow file")
+	encryptionSecretCmd.Flags().String("escrow-file", "", "stores an encrypted copy of the passphrase in the given file, existing files are not overwritten")
 	encryptionSecretCmd.Flags().StringP("namespace", "", "default", "namespace for the EncryptionSecret")
+	encryptionSecretCmd.Flags().StringP("project", "p", "", "project of the cluster")
+	encryptionSecretCmd.Flags().String("apply-to-cluster", "", "creates or updates the secret directly in the cluster with the given id instead of printing it")
+	encryptionSecretCmd.Flags().Bool("overwrite", false, "allows replacing an existing secret with a different passphrase when applying to a cluster, volumes encrypted with the old passphrase can not be unlocked anymore")
+
+	genericcli.Must(encryptionSecretCmd.RegisterFlagCompletionFunc("project", c.Completion.ProjectListCompletion))
+	genericcli.Must(encryptionSecretCmd.RegisterFlagCompletionFunc("apply-to-cluster", c.Completion.ClusterListCompletion))
+
+	encryptionSecretCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file", "generate", "from-escrow")
 
-	return genericcli.NewCmds(cmdsConfig, manifestCmd, encryptionSecretCmd)
+	return genericcli.NewCmds(cmdsConfig, manifestCmd, encryptionSecretCmd, w.newUsageCmd())
 }
 
 func (c *volume) Create(rq any) (*apiv1.Volume, error) {
@@ -159,7 +224,7 @@ func (c *volume) List() ([]*apiv1.Volume, error) {
 		return nil, fmt.Errorf("failed to get volumes: %w", err)
 	}
 
-	return resp.Msg.Volumes, nil
+	return selectVolumes(resp.Msg.Volumes, viper.GetStringSlice("selector")...)
 }
 
 func (c *volume) Convert(r *apiv1.Volume) (string, any, *apiv1.VolumeServiceUpdateRequest, error) {
@@ -179,69 +244,249 @@ func (c *volume) Update(rq *apiv1.VolumeServiceUpdateRequest) (*apiv1.Volume, er
 	return resp.Msg.Volume, nil
 }
 
+// manifestName returns the volume name as valid kubernetes object name, invalid characters are replaced by dashes,
+// an empty string is returned if no valid name remains
+func manifestName(name string) string {
+	name = invalidManifestNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = name[:validation.DNS1123SubdomainMaxLength]
+	}
+	name = strings.Trim(name, "-.")
+
+	if len(validation.IsDNS1123Subdomain(name)) > 0 {
+		return ""
+	}
+
+	return name
+}
+
 func (c *volume) volumeManifest(args []string) error {
-	id, err := genericcli.GetExactlyOneArg(args)
+	var (
+		accessMode    = corev1.PersistentVolumeAccessMode(viper.GetString("access-mode"))
+		reclaimPolicy = corev1.PersistentVolumeReclaimPolicy(viper.GetString("reclaim-policy"))
+		namespace     = viper.GetString("namespace")
+	)
+
+	if !slices.Contains([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadWriteOncePod, corev1.ReadOnlyMany, corev1.ReadWriteMany}, accessMode) {
+		return fmt.Errorf("unsupported access mode %q", accessMode)
+	}
+	if !slices.Contains([]corev1.PersistentVolumeReclaimPolicy{corev1.PersistentVolumeReclaimRetain, corev1.PersistentVolumeReclaimDelete}, reclaimPolicy) {
+		return fmt.Errorf("unsupported reclaim policy %q", reclaimPolicy)
+	}
+
+	volumes, err := c.manifestVolumes(args)
 	if err != nil {
 		return err
 	}
 
-	volume, err := c.Get(id)
+	if len(volumes) > 1 && viper.IsSet("name") {
+		return fmt.Errorf("--name can only be given for a single volume")
+	}
+
+	var (
+		seen    = map[string]bool{}
+		objects []runtime.Object
+	)
+
+	for _, volume := range volumes {
+		name := viper.GetString("name")
+		if len(volumes) > 1 {
+			name = manifestName(volume.Name)
+			if name == "" || seen[name] {
+				name = volume.Uuid
+			}
+		}
+		seen[name] = true
+
+		if len(volume.AttachedTo) > 0 {
+			nodes := connectedHosts(volume)
+			_, _ = fmt.Fprintf(c.c.Out, "# be cautious! at the time being your volume:%s is still attached to worker node:%s, you can not mount it twice\n", volume.Uuid, strings.Join(nodes, ","))
+		}
+
+		objects = append(objects, volumeManifests(volume, name, namespace, accessMode, reclaimPolicy, viper.GetString("fs-type"))...)
+	}
+
+	if viper.IsSet("apply-to-cluster") {
+		return c.applyToCluster(viper.GetString("apply-to-cluster"), kubernetes.ApplyOptions{}, objects...)
+	}
+
+	for _, obj := range objects {
+		y, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("unable to marshal to yaml: %w", err)
+		}
+
+		_, _ = fmt.Fprintf(c.c.Out, "---\n%s", string(y))
+	}
+
+	return nil
+}
+
+// applyToCluster creates or updates the objects in the given cluster of the project with server-side apply
+func (c *volume) applyToCluster(clusterID string, opts kubernetes.ApplyOptions, objects ...runtime.Object) error {
+	kubeconfig, err := c.clusterKubeconfig(clusterID)
 	if err != nil {
 		return err
 	}
 
-	name := viper.GetString("name")
-	namespace := viper.GetString("namespace")
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	applied, err := kubernetes.Apply(ctx, kubeconfig, opts, objects...)
+	for _, a := range applied {
+		_, _ = fmt.Fprintf(c.c.Out, "%s %s\n", color.GreenString("✔"), a)
+	}
+
+	return err
+}
+
+// clusterKubeconfig returns short-lived credentials of the given cluster of the project
+func (c *volume) clusterKubeconfig(clusterID string) ([]byte, error) {
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := c.c.Client.Apiv1().Cluster().GetCredentials(ctx, connect.NewRequest(&apiv1.ClusterServiceGetCredentialsRequest{
+		Uuid:       clusterID,
+		Project:    c.c.GetProject(),
+		Expiration: durationpb.New(volumeClusterCredentialsExpiration),
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster credentials: %w", err)
+	}
+
+	return []byte(resp.Msg.Kubeconfig), nil
+}
+
+// manifestVolumes returns the volumes given by uuid or matching the label selector
+func (c *volume) manifestVolumes(args []string) ([]*apiv1.Volume, error) {
+	selectors := viper.GetStringSlice("selector")
+
+	if len(selectors) == 0 {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("either volume uuids or a selector must be given")
+		}
+
+		var volumes []*apiv1.Volume
+		for _, id := range args {
+			volume, err := c.Get(id)
+			if err != nil {
+				return nil, err
+			}
+
+			volumes = append(volumes, volume)
+		}
+
+		return volumes, nil
+	}
+
+	if len(args) > 0 {
+		return nil, fmt.Errorf("either volume uuids or a selector can be given, not both")
+	}
+
+	volumes, err := c.listSelected(selectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(volumes) == 0 {
+		return nil, fmt.Errorf("no volumes match the selector %q", strings.Join(selectors, ","))
+	}
+
+	return volumes, nil
+}
 
-	filesystem := corev1.PersistentVolumeFilesystem
-	pv := corev1.PersistentVolume{
+// volumeManifests returns a PersistentVolume for the volume and a PersistentVolumeClaim bound to it
+func volumeManifests(volume *apiv1.Volume, name, namespace string, accessMode corev1.PersistentVolumeAccessMode, reclaimPolicy corev1.PersistentVolumeReclaimPolicy, fsType string) []runtime.Object {
+	var (
+		filesystem = corev1.PersistentVolumeFilesystem
+		capacity   = *resource.NewQuantity(int64(volume.Size), resource.BinarySI) // nolint:gosec
+	)
+
+	pv := &corev1.PersistentVolume{
 		TypeMeta:   v1.TypeMeta{Kind: "PersistentVolume", APIVersion: "v1"},
-		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace},
+		ObjectMeta: v1.ObjectMeta{Name: name},
 		Spec: corev1.PersistentVolumeSpec{
-			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
-			VolumeMode:  &filesystem,
-			// FIXME add Capacity once figured out
-			StorageClassName: volume.StorageClass,
+			AccessModes:                   []corev1.PersistentVolumeAccessMode{accessMode},
+			VolumeMode:                    &filesystem,
+			Capacity:                      corev1.ResourceList{corev1.ResourceStorage: capacity},
+			StorageClassName:              volume.StorageClass,
+			PersistentVolumeReclaimPolicy: reclaimPolicy,
+			ClaimRef: &corev1.ObjectReference{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+				Name:       name,
+				Namespace:  namespace,
+			},
 			PersistentVolumeSource: corev1.PersistentVolumeSource{
 				CSI: &corev1.CSIPersistentVolumeSource{
 					Driver:       "csi.lightbitslabs.com",
-					FSType:       "ext4",
-					ReadOnly:     false,
+					FSType:       fsType,
+					ReadOnly:     accessMode == corev1.ReadOnlyMany,
 					VolumeHandle: volume.VolumeHandle,
 				},
 			},
 		},
 	}
 
-	if len(volume.AttachedTo) > 0 {
-		nodes := connectedHosts(volume)
-		_, _ = fmt.Fprintf(c.c.Out, "# be cautious! at the time being your volume:%s is still attached to worker node:%s, you can not mount it twice\n", volume.Uuid, strings.Join(nodes, ","))
+	pvc := &corev1.PersistentVolumeClaim{
+		TypeMeta:   v1.TypeMeta{Kind: "PersistentVolumeClaim", APIVersion: "v1"},
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{accessMode},
+			VolumeMode:       &filesystem,
+			StorageClassName: pointer.Pointer(volume.StorageClass),
+			VolumeName:       name,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: capacity},
+			},
+		},
 	}
 
-	y, err := yaml.Marshal(pv)
+	return []runtime.Object{pv, pvc}
+}
+
+func (v *volume) volumeEncryptionSecretManifest() error {
+	namespace := viper.GetString("namespace")
+	passphrase, err := v.encryptionPassphrase()
 	if err != nil {
-		panic(fmt.Errorf("unable to marshal to yaml: %w", err))
+		return err
 	}
 
-	_, _ = fmt.Fprintf(c.c.Out, "---\n%s", string(y))
+	if viper.GetBool("generate") && viper.IsSet("apply-to-cluster") && !viper.IsSet("escrow-file") {
+		return fmt.Errorf("a generated passphrase is never shown when applying the secret to a cluster, --escrow-file is required to keep a copy")
+	}
 
-	return nil
-}
+	secret := kubernetes.EncryptionSecret(namespace, passphrase)
 
-func (v *volume) volumeEncryptionSecretManifest() error {
-	namespace := viper.GetString("namespace")
-	passphrase := viper.GetString("passphrase")
-	secret := corev1.Secret{
-		TypeMeta: v1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
-		ObjectMeta: v1.ObjectMeta{
-			Name:      "storage-encryption-key",
-			Namespace: namespace,
-		},
-		Type: corev1.SecretTypeOpaque,
-		StringData: map[string]string{
-			"host-encryption-passphrase": passphrase,
-		},
+	if name := viper.GetString("escrow-file"); name != "" {
+		err = v.writeEscrow(name, &volumeEscrow{
+			Secret:     secret.Name,
+			Namespace:  namespace,
+			Cluster:    viper.GetString("apply-to-cluster"),
+			Created:    time.Now(),
+			Passphrase: passphrase,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if viper.IsSet("apply-to-cluster") {
+		err = v.applyToCluster(viper.GetString("apply-to-cluster"), kubernetes.ApplyOptions{Overwrite: viper.GetBool("overwrite")}, secret)
+		if errors.Is(err, kubernetes.ErrSecretDataDiffers) {
+			return fmt.Errorf("%w, use --overwrite to replace the passphrase, volumes encrypted with the old passphrase can not be unlocked anymore", err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !viper.IsSet("escrow-file") {
+			_, _ = fmt.Fprintf(v.c.Out, "%s remember to make a safe copy of the passphrase at a secure location, once lost all your data will be lost as well\n", color.YellowString("⚠"))
+		}
+
+		return nil
 	}
+
 	y, err := yaml.Marshal(secret)
 	if err != nil {
 		return err
@@ -276,6 +521,19 @@ func (v *volume) updateFromCLI(args []string) (*apiv1.VolumeServiceUpdateRequest
 		return nil, err
 	}
 
+	updateLabels, err := updateLabelsFromCLI()
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.VolumeServiceUpdateRequest{
+		Uuid:    uuid,
+		Project: v.c.GetProject(),
+		Labels:  updateLabels,
+	}, nil
+}
+
+func updateLabelsFromCLI() (*apiv1.UpdateVolumeLabels, error) {
 	var (
 		updateLabels = &apiv1.UpdateVolumeLabels{}
 		addLabels    = viper.GetStringSlice("add-label")
@@ -295,11 +553,7 @@ func (v *volume) updateFromCLI(args []string) (*apiv1.VolumeServiceUpdateRequest
 		})
 	}
 
-	return &apiv1.VolumeServiceUpdateRequest{
-		Uuid:    uuid,
-		Project: v.c.GetProject(),
-		Labels:  updateLabels,
-	}, nil
+	return updateLabels, nil
 }
 
 func (v *volume) volumeResponseToUpdate(desired *apiv1.Volume) (*apiv1.VolumeServiceUpdateRequest, error) {
@@ -0,0 +1,211 @@
+package v1
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+const (
+	// volumeEscrowPassphraseEnv can be used to provide the passphrase of the escrow file non-interactively
+	volumeEscrowPassphraseEnv = "METAL_STACK_CLOUD_ESCROW_PASSPHRASE"
+	// volumeGeneratedPassphraseBytes is the amount of random bytes of a generated passphrase
+	volumeGeneratedPassphraseBytes = 32
+)
+
+// volumeEscrow is the content of the encrypted escrow file of an encryption secret
+type volumeEscrow struct {
+	Secret     string    `json:"secret"`
+	Namespace  string    `json:"namespace"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Created    time.Time `json:"created"`
+	Passphrase string    `json:"passphrase"`
+}
+
+// encryptionPassphrase returns the passphrase from the flag, a file, stdin, an escrow file, a generator or an interactive prompt
+func (v *volume) encryptionPassphrase() (string, error) {
+	var (
+		passphrase string
+		err        error
+	)
+
+	switch {
+	case viper.IsSet("passphrase"):
+		_, _ = fmt.Fprintf(v.c.Err, "%s passing the passphrase as flag exposes it in the shell history and the process list, consider using --passphrase-file or the prompt\n", color.YellowString("⚠"))
+		passphrase = viper.GetString("passphrase")
+	case viper.IsSet("passphrase-file"):
+		passphrase, err = v.readPassphraseFile(viper.GetString("passphrase-file"))
+	case viper.IsSet("from-escrow"):
+		var escrow *volumeEscrow
+		escrow, err = v.readEscrow(viper.GetString("from-escrow"))
+		if escrow != nil {
+			passphrase = escrow.Passphrase
+		}
+	case viper.GetBool("generate"):
+		passphrase, err = generatePassphrase()
+	default:
+		if !v.c.IsInteractive() {
+			return "", errors.New("no passphrase given, use --passphrase-file, --generate or --from-escrow when not running in a terminal")
+		}
+
+		passphrase, err = v.c.ReadPassword("Passphrase: ")
+		if err != nil {
+			return "", err
+		}
+
+		var again string
+		again, err = v.c.ReadPassword("Repeat passphrase: ")
+		if err == nil && passphrase != again {
+			err = errors.New("passphrases do not match")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(passphrase) == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+
+	return passphrase, nil
+}
+
+// readPassphraseFile reads the first line of the given file, "-" reads from stdin
+func (v *volume) readPassphraseFile(name string) (string, error) {
+	var r io.Reader
+
+	if name == "-" {
+		r = v.c.In
+	} else {
+		f, err := v.c.Fs.Open(name)
+		if err != nil {
+			return "", fmt.Errorf("unable to open passphrase file: %w", err)
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("unable to read passphrase: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func generatePassphrase() (string, error) {
+	b := make([]byte, volumeGeneratedPassphraseBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate passphrase: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// writeEscrow stores the passphrase encrypted with the escrow passphrase, existing escrow files are never overwritten
+func (v *volume) writeEscrow(name string, escrow *volumeEscrow) error {
+	exists, err := afero.Exists(v.c.Fs, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("escrow file %s already exists, refusing to overwrite it", name)
+	}
+
+	escrowPassphrase, err := v.escrowPassphrase(name, true)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(escrow)
+	if err != nil {
+		return err
+	}
+
+	raw, err := helpers.EncryptWithPassphrase(plaintext, escrowPassphrase)
+	if err != nil {
+		return err
+	}
+
+	if dir := path.Dir(name); dir != "." {
+		err = v.c.Fs.MkdirAll(dir, 0700)
+		if err != nil {
+			return fmt.Errorf("unable to ensure escrow directory: %w", err)
+		}
+	}
+
+	err = afero.WriteFile(v.c.Fs, name, raw, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write escrow file: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(v.c.Err, "%s stored an encrypted copy of the passphrase in %s, keep it and its passphrase at a secure location\n", color.GreenString("✔"), name)
+
+	return nil
+}
+
+func (v *volume) readEscrow(name string) (*volumeEscrow, error) {
+	raw, err := afero.ReadFile(v.c.Fs, name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read escrow file: %w", err)
+	}
+
+	escrowPassphrase, err := v.escrowPassphrase(name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := helpers.DecryptWithPassphrase(raw, escrowPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var escrow volumeEscrow
+	err = json.Unmarshal(plaintext, &escrow)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse escrow file: %w", err)
+	}
+
+	return &escrow, nil
+}
+
+func (v *volume) escrowPassphrase(name string, confirm bool) (string, error) {
+	if p, ok := os.LookupEnv(volumeEscrowPassphraseEnv); ok && p != "" {
+		return p, nil
+	}
+
+	p, err := v.c.ReadPassword(fmt.Sprintf("Escrow passphrase for %s: ", name))
+	if err != nil {
+		return "", fmt.Errorf("unable to read escrow passphrase, consider setting %s: %w", volumeEscrowPassphraseEnv, err)
+	}
+
+	if confirm {
+		again, err := v.c.ReadPassword("Repeat escrow passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if p != again {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	if p == "" {
+		return "", errors.New("escrow passphrase must not be empty")
+	}
+
+	return p, nil
+}
@@ -0,0 +1,239 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/spf13/viper"
+)
+
+// volumeLabelTags returns the labels of the volume in the form key=value as they are matched by selectors
+func volumeLabelTags(v *apiv1.Volume) []string {
+	var tags []string
+	for _, l := range v.Labels {
+		tags = append(tags, l.Key+"="+l.Value)
+	}
+	return tags
+}
+
+// selectVolumes returns the volumes whose labels match the given selectors
+func selectVolumes(volumes []*apiv1.Volume, selectors ...string) ([]*apiv1.Volume, error) {
+	selector, err := helpers.ParseSelector(selectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	if selector.Empty() {
+		return volumes, nil
+	}
+
+	var result []*apiv1.Volume
+	for _, v := range volumes {
+		if selector.Matches(volumeLabelTags(v)) {
+			result = append(result, v)
+		}
+	}
+
+	return result, nil
+}
+
+// listSelected lists the volumes of the project whose labels match the given selectors
+func (c *volume) listSelected(selectors ...string) ([]*apiv1.Volume, error) {
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := c.c.Client.Apiv1().Volume().List(ctx, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+		Project: c.c.GetProject(),
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volumes: %w", err)
+	}
+
+	return selectVolumes(resp.Msg.Volumes, selectors...)
+}
+
+func (c *volume) updateSelected(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("either a volume or a selector can be given, not both")
+	}
+
+	updateLabels, err := updateLabelsFromCLI()
+	if err != nil {
+		return err
+	}
+
+	volumes, err := c.selectVolumesForAction()
+	if err != nil {
+		return err
+	}
+
+	var (
+		requests []*apiv1.VolumeServiceUpdateRequest
+		changes  []string
+	)
+
+	for _, v := range volumes {
+		change := labelChanges(v.Labels, updateLabels)
+		if len(change) == 0 {
+			continue
+		}
+
+		requests = append(requests, &apiv1.VolumeServiceUpdateRequest{
+			Uuid:    v.Uuid,
+			Project: v.Project,
+			Labels:  updateLabels,
+		})
+		changes = append(changes, fmt.Sprintf("  %s\t%s\t%s", v.Uuid, v.Name, strings.Join(change, ", ")))
+	}
+
+	if len(requests) == 0 {
+		return fmt.Errorf("the labels of the %d matching volumes already are as requested", len(volumes))
+	}
+
+	err = c.confirmSelected("update the labels of", changes)
+	if err != nil {
+		return err
+	}
+
+	var (
+		updated  []*apiv1.Volume
+		failures []error
+	)
+
+	for _, rq := range requests {
+		resp, err := c.Update(rq)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to update volume %s: %w", rq.Uuid, err))
+			continue
+		}
+
+		updated = append(updated, resp)
+	}
+
+	return c.printSelectedResult(updated, failures, len(requests))
+}
+
+func (c *volume) deleteSelected(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("either a volume or a selector can be given, not both")
+	}
+
+	volumes, err := c.selectVolumesForAction()
+	if err != nil {
+		return err
+	}
+
+	var summary []string
+	for _, v := range volumes {
+		summary = append(summary, fmt.Sprintf("  %s\t%s\t%s", v.Uuid, v.Name, strings.Join(volumeLabelTags(v), ",")))
+	}
+
+	err = c.confirmSelected("delete", summary)
+	if err != nil {
+		return err
+	}
+
+	var (
+		deleted  []*apiv1.Volume
+		failures []error
+	)
+
+	for _, v := range volumes {
+		resp, err := c.Delete(v.Uuid)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("unable to delete volume %s: %w", v.Uuid, err))
+			continue
+		}
+
+		deleted = append(deleted, resp)
+	}
+
+	return c.printSelectedResult(deleted, failures, len(volumes))
+}
+
+func (c *volume) selectVolumesForAction() ([]*apiv1.Volume, error) {
+	selectors := viper.GetStringSlice("selector")
+
+	volumes, err := c.listSelected(selectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(volumes) == 0 {
+		return nil, fmt.Errorf("no volumes match the selector %q", strings.Join(selectors, ","))
+	}
+
+	return volumes, nil
+}
+
+// confirmSelected shows the summary of affected volumes and asks the user for confirmation
+func (c *volume) confirmSelected(action string, summary []string) error {
+	if viper.GetBool("skip-security-prompts") {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(c.c.PromptOut, "The following %d volumes match the selector %q:\n\n", len(summary), strings.Join(viper.GetStringSlice("selector"), ","))
+
+	for _, line := range summary {
+		_, _ = fmt.Fprintln(c.c.PromptOut, line)
+	}
+
+	_, _ = fmt.Fprintln(c.c.PromptOut)
+
+	return genericcli.PromptCustom(&genericcli.PromptConfig{
+		Message:         fmt.Sprintf("Do you want to %s these %d volumes?", action, len(summary)),
+		ShowAnswers:     true,
+		AcceptedAnswers: genericcli.PromptDefaultAnswers(),
+		DefaultAnswer:   "n",
+		No:              "n",
+		In:              c.c.In,
+		Out:             c.c.PromptOut,
+	})
+}
+
+func (c *volume) printSelectedResult(volumes []*apiv1.Volume, failures []error, total int) error {
+	if len(volumes) > 0 {
+		err := c.c.ListPrinter.Print(volumes)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d volumes failed: %w", len(failures), total, errors.Join(failures...))
+	}
+
+	return nil
+}
+
+// labelChanges describes how the update changes the given labels, e.g. +key=value, key=old->new or -key
+func labelChanges(labels []*apiv1.VolumeLabel, update *apiv1.UpdateVolumeLabels) []string {
+	current := map[string]string{}
+	for _, l := range labels {
+		current[l.Key] = l.Value
+	}
+
+	var changes []string
+	for _, key := range update.Remove {
+		if _, ok := current[key]; ok && !slices.ContainsFunc(update.Update, func(l *apiv1.VolumeLabel) bool { return l.Key == key }) {
+			changes = append(changes, "-"+key)
+		}
+	}
+	for _, l := range update.Update {
+		old, ok := current[l.Key]
+		switch {
+		case !ok:
+			changes = append(changes, "+"+l.Key+"="+l.Value)
+		case old != l.Value:
+			changes = append(changes, l.Key+"="+old+"->"+l.Value)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_manifestName(t *testing.T) {
+	tests := []struct {
+		name   string
+		volume string
+		want   string
+	}{
+		{name: "valid name", volume: "data-0", want: "data-0"},
+		{name: "uppercase", volume: "Data", want: "data"},
+		{name: "invalid characters", volume: "my_data (prod)", want: "my-data-prod"},
+		{name: "leading and trailing invalid characters", volume: "_data_", want: "data"},
+		{name: "dots are kept", volume: "data.prod", want: "data.prod"},
+		{name: "only invalid characters", volume: "___", want: ""},
+		{name: "empty", volume: "", want: ""},
+		{name: "empty label", volume: "data..prod", want: ""},
+		{name: "too long", volume: strings.Repeat("a", 300), want: strings.Repeat("a", 253)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, manifestName(tt.volume))
+		})
+	}
+}
+
+func Test_labelChanges(t *testing.T) {
+	labels := []*apiv1.VolumeLabel{
+		{Key: "app", Value: "db"},
+		{Key: "env", Value: "dev"},
+	}
+
+	tests := []struct {
+		name   string
+		update *apiv1.UpdateVolumeLabels
+		want   []string
+	}{
+		{
+			name:   "nothing to change",
+			update: &apiv1.UpdateVolumeLabels{},
+		},
+		{
+			name:   "add label",
+			update: &apiv1.UpdateVolumeLabels{Update: []*apiv1.VolumeLabel{{Key: "backup", Value: "true"}}},
+			want:   []string{"+backup=true"},
+		},
+		{
+			name:   "change label",
+			update: &apiv1.UpdateVolumeLabels{Update: []*apiv1.VolumeLabel{{Key: "env", Value: "prod"}}},
+			want:   []string{"env=dev->prod"},
+		},
+		{
+			name:   "label already as requested",
+			update: &apiv1.UpdateVolumeLabels{Update: []*apiv1.VolumeLabel{{Key: "app", Value: "db"}}},
+		},
+		{
+			name:   "remove label",
+			update: &apiv1.UpdateVolumeLabels{Remove: []string{"env"}},
+			want:   []string{"-env"},
+		},
+		{
+			name:   "remove missing label",
+			update: &apiv1.UpdateVolumeLabels{Remove: []string{"backup"}},
+		},
+		{
+			name: "removed and updated label is only updated",
+			update: &apiv1.UpdateVolumeLabels{
+				Update: []*apiv1.VolumeLabel{{Key: "env", Value: "prod"}},
+				Remove: []string{"env"},
+			},
+			want: []string{"env=dev->prod"},
+		},
+		{
+			name: "removals are listed first",
+			update: &apiv1.UpdateVolumeLabels{
+				Update: []*apiv1.VolumeLabel{{Key: "backup", Value: "true"}, {Key: "env", Value: "prod"}},
+				Remove: []string{"app"},
+			},
+			want: []string{"-app", "+backup=true", "env=dev->prod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, labelChanges(labels, tt.update))
+		})
+	}
+}
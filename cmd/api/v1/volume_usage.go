@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	"connectrpc.com/connect"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/kubernetes"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (c *volume) newUsageCmd() *cobra.Command {
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "shows which persistent volumes, claims and pods of a cluster use the volumes of the project",
+		Long: `shows which persistent volumes, claims and pods of a cluster use the volumes of the project.
+
+The cluster is inspected with short-lived cluster credentials and volumes are matched to persistent volumes by their volume handle. A volume is used when pods mount its claim, claimed when only a claim is bound, unclaimed when only a persistent volume exists and unreferenced when the cluster does not reference it at all.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.usage()
+		},
+	}
+
+	usageCmd.Flags().String("cluster", "", "the id of the cluster to inspect")
+	usageCmd.Flags().StringP("project", "p", "", "project of the volumes and the cluster")
+	usageCmd.Flags().Bool("unreferenced", false, "only shows volumes that are not referenced by the cluster")
+
+	genericcli.Must(usageCmd.MarkFlagRequired("cluster"))
+	genericcli.Must(usageCmd.RegisterFlagCompletionFunc("cluster", c.c.Completion.ClusterListCompletion))
+	genericcli.Must(usageCmd.RegisterFlagCompletionFunc("project", c.c.Completion.ProjectListCompletion))
+
+	return usageCmd
+}
+
+func (c *volume) usage() error {
+	volumes, err := c.projectVolumes()
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := c.clusterKubeconfig(viper.GetString("cluster"))
+	if err != nil {
+		return err
+	}
+
+	refs, err := c.clusterVolumeReferences(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	var usages []*models.VolumeUsage
+	for _, volume := range volumes {
+		usage := &models.VolumeUsage{
+			Uuid:         volume.Uuid,
+			Name:         volume.Name,
+			Size:         volume.Size,
+			VolumeHandle: volume.VolumeHandle,
+			Status:       models.VolumeUsageStatusUnreferenced,
+		}
+
+		if ref, ok := refs[volume.VolumeHandle]; ok && volume.VolumeHandle != "" {
+			usage.PersistentVolume = ref.PersistentVolume
+			usage.Namespace = ref.Namespace
+			usage.Claim = ref.Claim
+			usage.Pods = ref.Pods
+
+			switch {
+			case len(ref.Pods) > 0:
+				usage.Status = models.VolumeUsageStatusUsed
+			case ref.Claim != "":
+				usage.Status = models.VolumeUsageStatusClaimed
+			default:
+				usage.Status = models.VolumeUsageStatusUnclaimed
+			}
+		}
+
+		if viper.GetBool("unreferenced") && usage.Status != models.VolumeUsageStatusUnreferenced {
+			continue
+		}
+
+		usages = append(usages, usage)
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool {
+		if usages[i].Namespace != usages[j].Namespace {
+			return usages[i].Namespace < usages[j].Namespace
+		}
+		if usages[i].Claim != usages[j].Claim {
+			return usages[i].Claim < usages[j].Claim
+		}
+		return usages[i].Uuid < usages[j].Uuid
+	})
+
+	return c.c.ListPrinter.Print(usages)
+}
+
+func (c *volume) projectVolumes() ([]*apiv1.Volume, error) {
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	resp, err := c.c.Client.Apiv1().Volume().List(ctx, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+		Project: c.c.GetProject(),
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volumes: %w", err)
+	}
+
+	return resp.Msg.Volumes, nil
+}
+
+func (c *volume) clusterVolumeReferences(kubeconfig []byte) (map[string]kubernetes.VolumeReference, error) {
+	ctx, cancel := c.c.NewRequestContext()
+	defer cancel()
+
+	return kubernetes.VolumeReferences(ctx, kubeconfig)
+}
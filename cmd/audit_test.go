@@ -1,14 +1,27 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
 	"strconv"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/google/go-cmp/cmp"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 	apitests "github.com/metal-stack-cloud/api/go/tests"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -108,7 +121,7 @@ TIME                 REQUEST - ID                          USER    PROJECT    ME
 					"--body", *want[0].Body,
 					"--prettify-body",
 				}
-				AssertExhaustiveArgs(t, args, "sort-by")
+				AssertExhaustiveArgs(t, args, "sort-by", "follow", "follow-interval", "page-size", "correlate")
 				return args
 			},
 			ClientMocks: &apitests.ClientMockFns{
@@ -170,3 +183,628 @@ TIME                 REQUEST - ID                          USER    PROJECT    ME
 		tt.TestCmd(t)
 	}
 }
+
+func Test_AuditCmd_Correlate(t *testing.T) {
+	var (
+		request = &apiv1.AuditTrace{
+			Uuid:      auditTrace1.Uuid,
+			Timestamp: timestamppb.New(testTime),
+			User:      "a-user",
+			Tenant:    "a-tenant",
+			Project:   pointer.Pointer("project-a"),
+			Method:    "/apiv1/ip",
+			Body:      pointer.Pointer(`{"a": "b"}`),
+			SourceIp:  "192.168.2.1",
+			Phase:     apiv1.AuditPhase_AUDIT_PHASE_REQUEST,
+		}
+		response = &apiv1.AuditTrace{
+			Uuid:       auditTrace1.Uuid,
+			Timestamp:  timestamppb.New(testTime.Add(1500 * time.Millisecond)),
+			User:       "a-user",
+			Tenant:     "a-tenant",
+			Project:    pointer.Pointer("project-a"),
+			Method:     "/apiv1/ip",
+			Body:       pointer.Pointer(`{"c": "d"}`),
+			SourceIp:   "192.168.2.1",
+			ResultCode: pointer.Pointer(int32(codes.OK)),
+			Phase:      apiv1.AuditPhase_AUDIT_PHASE_RESPONSE,
+		}
+		unanswered = &apiv1.AuditTrace{
+			Uuid:      auditTrace2.Uuid,
+			Timestamp: timestamppb.New(testTime.Add(time.Minute)),
+			User:      "b-user",
+			Tenant:    "a-tenant",
+			Method:    "/apiv1/cluster",
+			SourceIp:  "192.168.2.3",
+			Phase:     apiv1.AuditPhase_AUDIT_PHASE_REQUEST,
+		}
+	)
+
+	listMock := func(traces ...*apiv1.AuditTrace) *apitests.ClientMockFns {
+		return &apitests.ClientMockFns{
+			Apiv1Mocks: &apitests.Apiv1MockFns{
+				Audit: func(m *mock.Mock) {
+					m.On("List", mock.Anything, connect.NewRequest(&apiv1.AuditServiceListRequest{
+						Login: "a-tenant",
+					})).
+						Return(&connect.Response[apiv1.AuditServiceListResponse]{
+							Msg: &apiv1.AuditServiceListResponse{
+								Traces: traces,
+							},
+						}, nil)
+				},
+			},
+		}
+	}
+
+	tests := []*Test[[]*models.AuditCorrelation]{
+		{
+			Name: "list request with response",
+			Cmd: func(want []*models.AuditCorrelation) []string {
+				return []string{"audit", "list", "--tenant", "a-tenant", "--correlate"}
+			},
+			ClientMocks: listMock(response, request),
+			Want: []*models.AuditCorrelation{
+				{
+					Uuid:     request.Uuid,
+					Request:  models.NewAuditRecord(request),
+					Response: models.NewAuditRecord(response),
+					Latency:  pointer.Pointer(1500 * time.Millisecond),
+				},
+			},
+			WantTable: pointer.Pointer(`
+TIME                 REQUEST - ID                          USER    PROJECT    METHOD     CODE  LATENCY  
+2022-05-19 01:02:03  c40ad996-e1fd-4511-a7bf-418219cb8d91  a-user  project-a  /apiv1/ip  OK    1.5s
+			`),
+			WantWideTable: pointer.Pointer(`
+TIME                 REQUEST - ID                          USER    PROJECT    METHOD     SOURCE - IP  CODE  LATENCY  
+2022-05-19 01:02:03  c40ad996-e1fd-4511-a7bf-418219cb8d91  a-user  project-a  /apiv1/ip  192.168.2.1  OK    1.5s
+			`),
+		},
+		{
+			Name: "list request without response",
+			Cmd: func(want []*models.AuditCorrelation) []string {
+				return []string{"audit", "list", "--tenant", "a-tenant", "--correlate"}
+			},
+			ClientMocks: listMock(response, unanswered, request),
+			Want: []*models.AuditCorrelation{
+				{
+					Uuid:    unanswered.Uuid,
+					Request: models.NewAuditRecord(unanswered),
+				},
+				{
+					Uuid:     request.Uuid,
+					Request:  models.NewAuditRecord(request),
+					Response: models.NewAuditRecord(response),
+					Latency:  pointer.Pointer(1500 * time.Millisecond),
+				},
+			},
+			WantTable: pointer.Pointer(`
+TIME                 REQUEST - ID                          USER    PROJECT    METHOD          CODE  LATENCY      
+2022-05-19 01:03:03  b5817ef7-980a-41ef-9ed3-741a143870b0  b-user             /apiv1/cluster        no response  
+2022-05-19 01:02:03  c40ad996-e1fd-4511-a7bf-418219cb8d91  a-user  project-a  /apiv1/ip       OK    1.5s
+			`),
+			WantMarkdown: pointer.Pointer(`
+| TIME                | REQUEST - ID                         | USER   | PROJECT   | METHOD         | CODE | LATENCY     |
+|---------------------|--------------------------------------|--------|-----------|----------------|------|-------------|
+| 2022-05-19 01:03:03 | b5817ef7-980a-41ef-9ed3-741a143870b0 | b-user |           | /apiv1/cluster |      | no response |
+| 2022-05-19 01:02:03 | c40ad996-e1fd-4511-a7bf-418219cb8d91 | a-user | project-a | /apiv1/ip      | OK   | 1.5s        |
+			`),
+		},
+		{
+			Name: "correlate cannot be filtered by phase",
+			Cmd: func(want []*models.AuditCorrelation) []string {
+				return []string{"audit", "list", "--tenant", "a-tenant", "--correlate", "--phase", "request"}
+			},
+			WantErr: errors.New("if any flags in the group [phase correlate] are set none of the others can be; [correlate phase] were all set"),
+		},
+	}
+	for _, tt := range tests {
+		tt.TestCmd(t)
+	}
+}
+
+func Test_AuditCmd_DescribeSideBySide(t *testing.T) {
+	request := &apiv1.AuditTrace{
+		Uuid:      auditTrace1.Uuid,
+		Timestamp: timestamppb.New(testTime),
+		User:      "a-user",
+		Tenant:    "a-tenant",
+		Project:   pointer.Pointer("project-a"),
+		Method:    "/apiv1/ip",
+		Body:      pointer.Pointer(`{"a": "b"}`),
+		SourceIp:  "192.168.2.1",
+		Phase:     apiv1.AuditPhase_AUDIT_PHASE_REQUEST,
+	}
+	response := &apiv1.AuditTrace{
+		Uuid:       auditTrace1.Uuid,
+		Timestamp:  timestamppb.New(testTime.Add(1500 * time.Millisecond)),
+		User:       "a-user",
+		Tenant:     "a-tenant",
+		Project:    pointer.Pointer("project-a"),
+		Method:     "/apiv1/ip",
+		Body:       pointer.Pointer(`{"c": "d"}`),
+		SourceIp:   "192.168.2.1",
+		ResultCode: pointer.Pointer(int32(codes.OK)),
+		Phase:      apiv1.AuditPhase_AUDIT_PHASE_RESPONSE,
+	}
+
+	tests := []struct {
+		name     string
+		response *apiv1.AuditTrace
+		want     string
+	}{
+		{
+			name:     "request with response",
+			response: response,
+			want: `Request-Id: c40ad996-e1fd-4511-a7bf-418219cb8d91
+Time:       2022-05-19 01:02:03
+Method:     /apiv1/ip
+User:       a-user
+Project:    project-a
+Source-Ip:  192.168.2.1
+Code:       OK
+Latency:    1.5s
+
+REQUEST                                                                        │ RESPONSE
+                                                                               │
+{                                                                              │ {
+    "a": "b"                                                                   │     "c": "d"
+}                                                                              │ }
+`,
+		},
+		{
+			name: "request without response",
+			want: `Request-Id: c40ad996-e1fd-4511-a7bf-418219cb8d91
+Time:       2022-05-19 01:02:03
+Method:     /apiv1/ip
+User:       a-user
+Project:    project-a
+Source-Ip:  192.168.2.1
+Code:       
+Latency:    no response
+
+REQUEST                                                                        │ RESPONSE
+                                                                               │
+{                                                                              │ no response recorded
+    "a": "b"                                                                   │
+}                                                                              │
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Test[*apiv1.AuditTrace]{
+				ClientMocks: &apitests.ClientMockFns{
+					Apiv1Mocks: &apitests.Apiv1MockFns{
+						Audit: func(m *mock.Mock) {
+							m.On("Get", mock.Anything, connect.NewRequest(&apiv1.AuditServiceGetRequest{
+								Login: "a-tenant",
+								Uuid:  request.Uuid,
+								Phase: pointer.Pointer(apiv1.AuditPhase_AUDIT_PHASE_REQUEST),
+							})).Return(&connect.Response[apiv1.AuditServiceGetResponse]{
+								Msg: &apiv1.AuditServiceGetResponse{Trace: request},
+							}, nil)
+
+							call := m.On("Get", mock.Anything, connect.NewRequest(&apiv1.AuditServiceGetRequest{
+								Login: "a-tenant",
+								Uuid:  request.Uuid,
+								Phase: pointer.Pointer(apiv1.AuditPhase_AUDIT_PHASE_RESPONSE),
+							}))
+							if tt.response == nil {
+								call.Return(nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("no audit trace found")))
+							} else {
+								call.Return(&connect.Response[apiv1.AuditServiceGetResponse]{
+									Msg: &apiv1.AuditServiceGetResponse{Trace: tt.response},
+								}, nil)
+							}
+						},
+					},
+				},
+			}
+
+			_, out, conf := c.newMockConfig(t)
+
+			cmd := newRootCmd(conf)
+			os.Args = []string{config.BinaryName, "audit", "describe", "--tenant", "a-tenant", "--side-by-side", request.Uuid}
+
+			require.NoError(t, cmd.Execute())
+
+			if diff := cmp.Diff(tt.want, out.String()); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_AuditCmd_History(t *testing.T) {
+	var (
+		id    = "2e0144a2-09ef-42b7-b629-4263295db6e8"
+		from  = time.Date(2022, 5, 18, 0, 0, 0, 0, time.UTC)
+		to    = time.Date(2022, 5, 19, 0, 0, 0, 0, time.UTC)
+		start = time.Date(2022, 5, 18, 10, 0, 0, 0, time.UTC)
+
+		createRequest = &apiv1.AuditTrace{
+			Uuid:      "c40ad996-e1fd-4511-a7bf-418219cb8d91",
+			Timestamp: timestamppb.New(start),
+			User:      "a-user",
+			Tenant:    "a-tenant",
+			Method:    "/metalstack.api.v1.IPService/Allocate",
+			Body:      pointer.Pointer(`{"name":"a"}`),
+			Phase:     apiv1.AuditPhase_AUDIT_PHASE_REQUEST,
+		}
+		// only the response of the creation contains the uuid of the resource
+		createResponse = &apiv1.AuditTrace{
+			Uuid:       createRequest.Uuid,
+			Timestamp:  timestamppb.New(start.Add(time.Second)),
+			User:       "a-user",
+			Tenant:     "a-tenant",
+			Method:     "/metalstack.api.v1.IPService/Allocate",
+			Body:       pointer.Pointer(`{"ip":{"uuid":"` + id + `","name":"a"}}`),
+			ResultCode: pointer.Pointer(int32(codes.OK)),
+			Phase:      apiv1.AuditPhase_AUDIT_PHASE_RESPONSE,
+		}
+		updateRequest = &apiv1.AuditTrace{
+			Uuid:      "b5817ef7-980a-41ef-9ed3-741a143870b0",
+			Timestamp: timestamppb.New(start.Add(time.Hour)),
+			User:      "b-user",
+			Tenant:    "a-tenant",
+			Method:    "/metalstack.api.v1.IPService/Update",
+			Body:      pointer.Pointer(`{"uuid":"` + id + `","name":"b"}`),
+			Phase:     apiv1.AuditPhase_AUDIT_PHASE_REQUEST,
+		}
+		updateResponse = &apiv1.AuditTrace{
+			Uuid:       updateRequest.Uuid,
+			Timestamp:  timestamppb.New(start.Add(time.Hour + time.Second)),
+			User:       "b-user",
+			Tenant:     "a-tenant",
+			Method:     "/metalstack.api.v1.IPService/Update",
+			Body:       pointer.Pointer(`{"ip":{"uuid":"` + id + `","name":"b"}}`),
+			ResultCode: pointer.Pointer(int32(codes.OK)),
+			Phase:      apiv1.AuditPhase_AUDIT_PHASE_RESPONSE,
+		}
+		lookup = &apiv1.AuditTrace{
+			Uuid:       "0a5b4fd3-5bb2-4a2c-a8c1-2c3ee49b1ad7",
+			Timestamp:  timestamppb.New(start.Add(2 * time.Hour)),
+			User:       "b-user",
+			Tenant:     "a-tenant",
+			Method:     "/metalstack.api.v1.IPService/Get",
+			Body:       pointer.Pointer(`{"uuid":"` + id + `"}`),
+			ResultCode: pointer.Pointer(int32(codes.OK)),
+			Phase:      apiv1.AuditPhase_AUDIT_PHASE_REQUEST,
+		}
+	)
+
+	tests := []*Test[[]*models.AuditChange]{
+		{
+			Name: "history including the creation",
+			Cmd: func(want []*models.AuditChange) []string {
+				return []string{"audit", "history", id, "--tenant", "a-tenant", "--from", from.Format(time.RFC3339), "--to", to.Format(time.RFC3339)}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Audit: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.AuditServiceListRequest{
+							Login: "a-tenant",
+							Body:  &id,
+							From:  timestamppb.New(from),
+							To:    timestamppb.New(to),
+							Limit: pointer.Pointer(int32(1000)),
+						})).
+							Return(&connect.Response[apiv1.AuditServiceListResponse]{
+								Msg: &apiv1.AuditServiceListResponse{
+									Traces: []*apiv1.AuditTrace{lookup, updateResponse, updateRequest, createResponse},
+								},
+							}, nil)
+						m.On("Get", mock.Anything, connect.NewRequest(&apiv1.AuditServiceGetRequest{
+							Login: "a-tenant",
+							Uuid:  createRequest.Uuid,
+							Phase: pointer.Pointer(apiv1.AuditPhase_AUDIT_PHASE_REQUEST),
+						})).
+							Return(&connect.Response[apiv1.AuditServiceGetResponse]{
+								Msg: &apiv1.AuditServiceGetResponse{Trace: createRequest},
+							}, nil)
+					},
+				},
+			},
+			Want: []*models.AuditChange{
+				{
+					Uuid:       createRequest.Uuid,
+					Timestamp:  start,
+					User:       "a-user",
+					Method:     "/metalstack.api.v1.IPService/Allocate",
+					ResultCode: pointer.Pointer(int32(codes.OK)),
+					Changes: []helpers.JSONChange{
+						{Kind: helpers.JSONChangeAdded, New: map[string]any{"name": "a"}},
+					},
+				},
+				{
+					Uuid:       updateRequest.Uuid,
+					Timestamp:  start.Add(time.Hour),
+					User:       "b-user",
+					Method:     "/metalstack.api.v1.IPService/Update",
+					ResultCode: pointer.Pointer(int32(codes.OK)),
+					Changes: []helpers.JSONChange{
+						{Path: ".name", Kind: helpers.JSONChangeChanged, Old: "a", New: "b"},
+						{Path: ".uuid", Kind: helpers.JSONChangeAdded, New: id},
+					},
+				},
+			},
+		},
+		{
+			Name: "phase cannot be filtered",
+			Cmd: func(want []*models.AuditChange) []string {
+				return []string{"audit", "history", id, "--tenant", "a-tenant", "--phase", "request"}
+			},
+			WantErr: errors.New("the history cannot be filtered by phase, requests and responses are always needed"),
+		},
+	}
+	for _, tt := range tests {
+		tt.TestCmd(t)
+	}
+}
+
+func Test_AuditCmd_Export(t *testing.T) {
+	var (
+		from  = time.Date(2022, 5, 19, 0, 0, 0, 0, time.UTC)
+		to    = from.Add(150 * time.Minute)
+		args  = []string{"audit", "export", "--tenant", "a-tenant", "--from", from.Format(time.RFC3339), "--to", to.Format(time.RFC3339), "--window", "1h", "--out", "/export.jsonl"}
+		trace = func(uuid string, ts time.Time) *apiv1.AuditTrace {
+			return &apiv1.AuditTrace{Uuid: uuid, Timestamp: timestamppb.New(ts), Tenant: "a-tenant", Phase: apiv1.AuditPhase_AUDIT_PHASE_REQUEST}
+		}
+
+		t1 = trace("1", from.Add(10*time.Minute))
+		t2 = trace("2", from.Add(70*time.Minute))
+		t3 = trace("3", from.Add(130*time.Minute))
+	)
+
+	window := func(m *mock.Mock, start, end time.Time, traces ...*apiv1.AuditTrace) {
+		m.On("List", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(&apiv1.AuditServiceListRequest{
+			Login: "a-tenant",
+			From:  timestamppb.New(start),
+			To:    timestamppb.New(end),
+			Limit: pointer.Pointer(int32(1000)),
+		}), testcommon.IgnoreUnexported())).Return(&connect.Response[apiv1.AuditServiceListResponse]{
+			Msg: &apiv1.AuditServiceListResponse{Traces: traces},
+		}, nil).Once()
+	}
+
+	line := func(trace *apiv1.AuditTrace) string {
+		raw, err := json.Marshal(models.NewAuditRecord(trace))
+		require.NoError(t, err)
+		return string(raw) + "\n"
+	}
+
+	run := func(t *testing.T, fs afero.Fs, mocks func(m *mock.Mock), args ...string) (string, error) {
+		c := &Test[[]*apiv1.AuditTrace]{
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Audit: mocks,
+				},
+			},
+		}
+
+		_, out, conf := c.newMockConfig(t)
+		if fs != nil {
+			conf.Fs = fs
+		}
+
+		cmd := newRootCmd(conf)
+		os.Args = append([]string{config.BinaryName}, args...)
+
+		err := cmd.Execute()
+
+		return out.String(), err
+	}
+
+	// interrupted returns a file system with an export that was interrupted after the first window, leaving a partially written line behind
+	interrupted := func(t *testing.T) afero.Fs {
+		fs := afero.NewMemMapFs()
+
+		_, err := run(t, fs, func(m *mock.Mock) {
+			window(m, from, from.Add(time.Hour), t1)
+			m.On("List", mock.Anything, mock.Anything).Return(nil, errors.New("unavailable")).Once()
+		}, args...)
+		require.EqualError(t, err, "failed to list audit traces: unavailable")
+
+		f, err := fs.OpenFile("/export.jsonl", os.O_APPEND|os.O_WRONLY, 0600)
+		require.NoError(t, err)
+		_, err = f.WriteString(`{"partial":`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		return fs
+	}
+
+	t.Run("walks the range in windows", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		out, err := run(t, fs, func(m *mock.Mock) {
+			window(m, from, from.Add(time.Hour), t1)
+			window(m, from.Add(time.Hour), from.Add(2*time.Hour), t2)
+			window(m, from.Add(2*time.Hour), to, t3)
+		}, args...)
+		require.NoError(t, err)
+		require.Contains(t, out, "exported 3 audit traces to /export.jsonl")
+
+		content, err := afero.ReadFile(fs, "/export.jsonl")
+		require.NoError(t, err)
+		require.Equal(t, line(t1)+line(t2)+line(t3), string(content))
+
+		_, err = fs.Stat("/export.jsonl.checkpoint")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("resume truncates to the last completed window", func(t *testing.T) {
+		fs := interrupted(t)
+
+		out, err := run(t, fs, func(m *mock.Mock) {
+			window(m, from.Add(time.Hour), from.Add(2*time.Hour), t2)
+			window(m, from.Add(2*time.Hour), to, t3)
+		}, args...)
+		require.NoError(t, err)
+		require.Contains(t, out, "exported 3 audit traces to /export.jsonl")
+
+		content, err := afero.ReadFile(fs, "/export.jsonl")
+		require.NoError(t, err)
+		require.Equal(t, line(t1)+line(t2)+line(t3), string(content))
+	})
+
+	for _, tt := range []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "format",
+			args:    []string{"--format", "csv"},
+			wantErr: "found checkpoint of an unfinished export with different format jsonl at /export.jsonl.checkpoint, remove it to start over",
+		},
+		{
+			name:    "filters",
+			args:    []string{"--method", "/metalstack.api.v1.IPService/Get"},
+			wantErr: "found checkpoint of an unfinished export with different filters at /export.jsonl.checkpoint, remove it to start over",
+		},
+		{
+			name:    "time range",
+			args:    []string{"--to", to.Add(time.Hour).Format(time.RFC3339)},
+			wantErr: "found checkpoint of an unfinished export with different time range from=2022-05-19T00:00:00Z to=2022-05-19T02:30:00Z at /export.jsonl.checkpoint, remove it to start over",
+		},
+		{
+			name:    "body normalization",
+			args:    []string{"--normalize-body"},
+			wantErr: "found checkpoint of an unfinished export with different body normalization at /export.jsonl.checkpoint, remove it to start over",
+		},
+	} {
+		t.Run("resume with different "+tt.name+" is refused", func(t *testing.T) {
+			fs := interrupted(t)
+
+			_, err := run(t, fs, func(m *mock.Mock) {}, append(slices.Clone(args), tt.args...)...)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func Test_AuditCmd_Stats(t *testing.T) {
+	var (
+		from  = time.Date(2022, 5, 19, 0, 0, 0, 0, time.UTC)
+		to    = from.Add(2 * time.Hour)
+		trace = func(method string, code codes.Code, ts time.Time) *apiv1.AuditTrace {
+			return &apiv1.AuditTrace{
+				Uuid:       method + ts.String(),
+				Timestamp:  timestamppb.New(ts),
+				Method:     method,
+				ResultCode: pointer.Pointer(int32(code)),
+				Phase:      apiv1.AuditPhase_AUDIT_PHASE_RESPONSE,
+			}
+		}
+	)
+
+	mocks := &apitests.ClientMockFns{
+		Apiv1Mocks: &apitests.Apiv1MockFns{
+			Audit: func(m *mock.Mock) {
+				window := func(start, end time.Time, traces ...*apiv1.AuditTrace) {
+					m.On("List", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(&apiv1.AuditServiceListRequest{
+						Login: "a-tenant",
+						From:  timestamppb.New(start),
+						To:    timestamppb.New(end),
+						Limit: pointer.Pointer(int32(1000)),
+						Phase: pointer.Pointer(apiv1.AuditPhase_AUDIT_PHASE_RESPONSE),
+					}), testcommon.IgnoreUnexported())).Return(&connect.Response[apiv1.AuditServiceListResponse]{
+						Msg: &apiv1.AuditServiceListResponse{Traces: traces},
+					}, nil)
+				}
+
+				window(from, from.Add(time.Hour),
+					trace("/a", codes.OK, from.Add(10*time.Minute)),
+					trace("/a", codes.NotFound, from.Add(20*time.Minute)),
+					trace("/b", codes.OK, from.Add(30*time.Minute)),
+				)
+				window(from.Add(time.Hour), to,
+					trace("/a", codes.OK, from.Add(70*time.Minute)),
+				)
+			},
+		},
+	}
+
+	args := []string{"audit", "stats", "--tenant", "a-tenant", "--from", from.Format(time.RFC3339), "--to", to.Format(time.RFC3339)}
+
+	tests := []*Test[*models.AuditStats]{
+		{
+			Name: "grouped by method",
+			Cmd: func(want *models.AuditStats) []string {
+				return args
+			},
+			ClientMocks: mocks,
+			Want: &models.AuditStats{
+				GroupBy: []string{"method"},
+				Groups: []*models.AuditStatsGroup{
+					{Keys: []string{"/a"}, Count: 3, Errors: 1, ErrorRate: 1.0 / 3},
+					{Keys: []string{"/b"}, Count: 1},
+				},
+			},
+			WantTable: pointer.Pointer(`
+METHOD  COUNT  ERRORS  ERROR - RATE  
+/a      3      1       33.3%         
+/b      1      0       0.0%
+			`),
+		},
+		{
+			Name: "grouped by method in buckets",
+			Cmd: func(want *models.AuditStats) []string {
+				return append(slices.Clone(args), "--bucket", "1h")
+			},
+			ClientMocks: mocks,
+			Want: &models.AuditStats{
+				GroupBy: []string{"method"},
+				Bucket:  "1h",
+				Buckets: []time.Time{from, from.Add(time.Hour)},
+				Groups: []*models.AuditStatsGroup{
+					{Keys: []string{"/a"}, Count: 3, Errors: 1, ErrorRate: 1.0 / 3, Series: []*models.AuditStatsBucket{
+						{Start: from, Count: 2, Errors: 1, ErrorRate: 0.5},
+						{Start: from.Add(time.Hour), Count: 1},
+					}},
+					{Keys: []string{"/b"}, Count: 1, Series: []*models.AuditStatsBucket{
+						{Start: from, Count: 1},
+						{Start: from.Add(time.Hour)},
+					}},
+				},
+			},
+			WantTable: pointer.Pointer(`
+METHOD  BUCKET            COUNT  ERRORS  ERROR - RATE  
+/a      2022-05-19 00:00  2      1       50.0%         
+/a      2022-05-19 01:00  1      0       0.0%          
+/b      2022-05-19 00:00  1      0       0.0%
+			`),
+		},
+		{
+			Name: "sparkline",
+			Cmd: func(want *models.AuditStats) []string {
+				return append(slices.Clone(args), "--bucket", "1h", "--sparkline")
+			},
+			ClientMocks: mocks,
+			WantTable: pointer.Pointer(`
+METHOD  COUNT  ERROR - RATE  REQUESTS  ( PER 1 H )  ERRORS  
+/a      3      33.3%         █▄                     █       
+/b      1      0.0%          █
+			`),
+		},
+		{
+			Name: "sparkline requires a bucket",
+			Cmd: func(want *models.AuditStats) []string {
+				return append(slices.Clone(args), "--sparkline")
+			},
+			WantErr: errors.New("sparkline requires a bucket"),
+		},
+		{
+			Name: "phase cannot be filtered",
+			Cmd: func(want *models.AuditStats) []string {
+				return append(slices.Clone(args), "--phase", "AUDIT_PHASE_REQUEST")
+			},
+			WantErr: errors.New("the stats cannot be filtered by phase, only the response phase contains the result of a request"),
+		},
+	}
+	for _, tt := range tests {
+		tt.TestCmd(t)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 	apitests "github.com/metal-stack-cloud/api/go/tests"
 	v1 "github.com/metal-stack-cloud/cli/cmd/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
 	"github.com/metal-stack/metal-lib/pkg/testcommon"
 	"github.com/spf13/afero"
@@ -332,7 +333,7 @@ ID                                    TENANT       PROJECT  NAME      PARTITION
 					"--worker-max-unavailable", strconv.Itoa(int(want.Workers[0].Maxunavailable)), // nolint:gosec
 					"--worker-type", want.Workers[0].MachineType,
 				}
-				AssertExhaustiveArgs(t, args, commonExcludedFileArgs()...)
+				AssertExhaustiveArgs(t, args, append(commonExcludedFileArgs(), "estimate-cost")...)
 				return args
 			},
 			ClientMocks: &apitests.ClientMockFns{
@@ -366,7 +367,7 @@ ID                                    TENANT       PROJECT  NAME      PARTITION
 					"--worker-max-unavailable", strconv.Itoa(int(want.Workers[0].Maxunavailable)), // nolint:gosec
 					"--worker-type", want.Workers[0].MachineType,
 				}
-				exclude := append(commonExcludedFileArgs(), "remove-worker-group")
+				exclude := append(commonExcludedFileArgs(), "remove-worker-group", "estimate-cost")
 				AssertExhaustiveArgs(t, args, exclude...)
 				return args
 			},
@@ -394,3 +395,112 @@ ID                                    TENANT       PROJECT  NAME      PARTITION
 		tt.TestCmd(t)
 	}
 }
+
+func Test_ClusterCmd_Cost(t *testing.T) {
+	prices := func(m *mock.Mock) {
+		m.On("GetDefaultPrices", mock.Anything, mock.Anything).Return(connect.NewResponse(&apiv1.PaymentServiceGetDefaultPricesResponse{
+			Prices: []*apiv1.Price{
+				{Name: "c1-xlarge-x86", ProductType: apiv1.ProductType_PRODUCT_TYPE_COMPUTE, UnitAmountDecimal: 0.5, Currency: "eur"},
+				{Name: "c1-large-x86", ProductType: apiv1.ProductType_PRODUCT_TYPE_COMPUTE, UnitAmountDecimal: 0.25, Currency: "eur"},
+				{Name: "Kubernetes", ProductType: apiv1.ProductType_PRODUCT_TYPE_KUBERNETES, UnitAmountDecimal: 1, Currency: "eur"},
+			},
+		}), nil)
+	}
+	getCluster := func(m *mock.Mock) {
+		m.On("Get", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(&apiv1.ClusterServiceGetRequest{
+			Uuid:    cluster1().Uuid,
+			Project: cluster1().Project,
+		}), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(connect.NewResponse(&apiv1.ClusterServiceGetResponse{
+			Cluster: cluster1(),
+		}), nil)
+	}
+
+	tests := []*Test[*models.ClusterCost]{
+		{
+			Name: "cost",
+			Cmd: func(want *models.ClusterCost) []string {
+				return []string{"cluster", "cost", want.Cluster, "--project", "a"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Cluster: getCluster,
+					Payment: prices,
+				},
+			},
+			Want: &models.ClusterCost{
+				Cluster: cluster1().Uuid,
+				Name:    cluster1().Name,
+				Items: []*models.ClusterCostItem{
+					{Name: "group-0", MachineType: "c1-xlarge-x86", Minsize: 1, Maxsize: 3, MinMonthlyCost: pointer.Pointer(365.0), MaxMonthlyCost: pointer.Pointer(1095.0)},
+					{Name: "control plane", Minsize: 1, Maxsize: 1, MinMonthlyCost: pointer.Pointer(730.0), MaxMonthlyCost: pointer.Pointer(730.0)},
+				},
+				Currency:       "EUR",
+				MinMonthlyCost: 1095,
+				MaxMonthlyCost: 1825,
+				Complete:       true,
+			},
+		},
+		{
+			Name: "estimate create",
+			Cmd: func(want *models.ClusterCost) []string {
+				return []string{"cluster", "create", "--estimate-cost", "--project", "a", "--partition", "partition-a", "--name", want.Name,
+					"--worker-group", "group-0", "--worker-type", "c1-medium-x86", "--worker-min", "1", "--worker-max", "2"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Payment: prices,
+				},
+			},
+			Want: &models.ClusterCost{
+				Name: "cluster2",
+				Items: []*models.ClusterCostItem{
+					// only exact machine type names are priced
+					{Name: "group-0", MachineType: "c1-medium-x86", Minsize: 1, Maxsize: 2},
+					{Name: "control plane", Minsize: 1, Maxsize: 1, MinMonthlyCost: pointer.Pointer(730.0), MaxMonthlyCost: pointer.Pointer(730.0)},
+				},
+				Currency:       "EUR",
+				MinMonthlyCost: 730,
+				MaxMonthlyCost: 730,
+			},
+		},
+		{
+			Name: "estimate update",
+			Cmd: func(want *models.ClusterCost) []string {
+				return []string{"cluster", "update", want.Cluster, "--estimate-cost", "--project", "a", "--worker-type", "c1-large-x86", "--worker-max", "4"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Cluster: getCluster,
+					Payment: prices,
+				},
+			},
+			Want: &models.ClusterCost{
+				Cluster: cluster1().Uuid,
+				Name:    cluster1().Name,
+				Items: []*models.ClusterCostItem{
+					{Name: "group-0", MachineType: "c1-large-x86", Minsize: 1, Maxsize: 4, MinMonthlyCost: pointer.Pointer(182.5), MaxMonthlyCost: pointer.Pointer(730.0)},
+					{Name: "control plane", Minsize: 1, Maxsize: 1, MinMonthlyCost: pointer.Pointer(730.0), MaxMonthlyCost: pointer.Pointer(730.0)},
+				},
+				Currency:       "EUR",
+				MinMonthlyCost: 912.5,
+				MaxMonthlyCost: 1460,
+				Complete:       true,
+				Current: &models.ClusterCost{
+					Items: []*models.ClusterCostItem{
+						{Name: "group-0", MachineType: "c1-xlarge-x86", Minsize: 1, Maxsize: 3, MinMonthlyCost: pointer.Pointer(365.0), MaxMonthlyCost: pointer.Pointer(1095.0)},
+						{Name: "control plane", Minsize: 1, Maxsize: 1, MinMonthlyCost: pointer.Pointer(730.0), MaxMonthlyCost: pointer.Pointer(730.0)},
+					},
+					Currency:       "EUR",
+					MinMonthlyCost: 1095,
+					MaxMonthlyCost: 1825,
+					Complete:       true,
+				},
+				MinMonthlyCostDelta: pointer.Pointer(-182.5),
+				MaxMonthlyCostDelta: pointer.Pointer(-365.0),
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.TestCmd(t)
+	}
+}
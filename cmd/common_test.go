@@ -106,6 +106,7 @@ func (c *Test[R]) newMockConfig(t *testing.T) (any, *bytes.Buffer, *config.Confi
 			Out:        &out,
 			In:         in,
 			PromptOut:  io.Discard,
+			Err:        io.Discard,
 			Completion: &completion.Completion{},
 			Client:     mock.Client(c.ClientMocks),
 		}
@@ -95,3 +95,30 @@ func (c *Completion) TokenPermissionsCompletionfunc(cmd *cobra.Command, args []s
 
 	return perms, cobra.ShellCompDirectiveDefault
 }
+
+func (c *Completion) TokenPresetsCompletion(presets []string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		project, _, ok := strings.Cut(toComplete, "=")
+		if !ok {
+			req := &apiv1.ProjectServiceListRequest{}
+			resp, err := c.Client.Apiv1().Project().List(c.Ctx, connect.NewRequest(req))
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+
+			var names []string
+			for _, p := range resp.Msg.GetProjects() {
+				names = append(names, p.Uuid+"=\t"+p.Name)
+			}
+
+			return names, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		}
+
+		var names []string
+		for _, preset := range presets {
+			names = append(names, project+"="+preset)
+		}
+
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
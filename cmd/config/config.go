@@ -15,6 +15,7 @@ import (
 	"github.com/metal-stack/metal-lib/pkg/pointer"
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 )
@@ -25,6 +26,8 @@ const (
 	// ConfigDir is the directory in either the homedir or in /etc where the cli searches for a file config.yaml
 	// also used as prefix for environment based configuration, e.g. METAL_STACK_CLOUD_ will be the variable prefix.
 	ConfigDir = "metal-stack-cloud"
+	// DefaultTokenExpiryWarning is the duration before the token expires from which on a warning is printed
+	DefaultTokenExpiryWarning = 24 * time.Hour
 )
 
 type Config struct {
@@ -32,11 +35,27 @@ type Config struct {
 	In              io.Reader
 	Out             io.Writer
 	PromptOut       io.Writer
+	Err             io.Writer
 	Client          client.Client
 	ListPrinter     printers.Printer
 	DescribePrinter printers.Printer
 	Completion      *completion.Completion
 	Context         Context
+
+	// credentialPassphrases caches the passphrases of the encrypted credentials files by path for the duration of a command
+	credentialPassphrases map[string]string
+}
+
+// NewApiClient returns an api client for the given api url and token
+func NewApiClient(apiURL, token string) client.Client {
+	dialConfig := client.DialConfig{
+		BaseURL:   apiURL,
+		Token:     token,
+		UserAgent: "metal-stack-cloud-cli",
+		Debug:     viper.GetBool("debug"),
+	}
+
+	return client.New(dialConfig)
 }
 
 func (c *Config) NewRequestContext() (context.Context, context.CancelFunc) {
@@ -129,6 +148,33 @@ func (c *Config) GetApiURL() string {
 	return viper.GetString("api-url")
 }
 
+func (c *Config) GetTokenExpiryWarning() time.Duration {
+	if c.Context.TokenExpiryWarning != nil {
+		return *c.Context.TokenExpiryWarning
+	}
+	return DefaultTokenExpiryWarning
+}
+
+// GetTimezone returns the location in which times without timezone information given on the command line are interpreted, defaults to UTC.
+func (c *Config) GetTimezone() (*time.Location, error) {
+	if c.Context.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(c.Context.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q in context %q: %w", c.Context.Timezone, c.Context.Name, err)
+	}
+
+	return loc, nil
+}
+
+// IsInteractive returns true when the cli reads its input from a terminal.
+func (c *Config) IsInteractive() bool {
+	f, ok := c.In.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
 func (c *Config) GetProvider() string {
 	if viper.IsSet("provider") {
 		return viper.GetString("provider")
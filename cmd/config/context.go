@@ -20,16 +20,23 @@ type Contexts struct {
 	CurrentContext  string     `json:"current-context"`
 	PreviousContext string     `json:"previous-context"`
 	Contexts        []*Context `json:"contexts"`
+	// CredentialStore configures where the api tokens of the contexts are stored, defaults to plaintext in this file
+	CredentialStore *CredentialStoreConfig `json:"credential-store,omitempty"`
 }
 
 // Context configure
 type Context struct {
 	Name           string         `json:"name"`
 	ApiURL         *string        `json:"api-url,omitempty"`
-	Token          string         `json:"api-token"`
+	Token          string         `json:"api-token,omitempty"`
+	TokenRef       string         `json:"api-token-ref,omitempty"`
 	DefaultProject string         `json:"default-project"`
 	Timeout        *time.Duration `json:"timeout,omitempty"`
 	Provider       string         `json:"provider"`
+	// TokenExpiryWarning is the duration before the token expires from which on a warning is printed, zero disables the warning
+	TokenExpiryWarning *time.Duration `json:"token-expiry-warning,omitempty"`
+	// Timezone is the location in which times without timezone information are interpreted, defaults to UTC
+	Timezone string `json:"timezone,omitempty"`
 }
 
 func (cs *Contexts) Get(name string) (*Context, bool) {
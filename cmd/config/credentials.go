@@ -0,0 +1,615 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/spf13/afero"
+	"golang.org/x/term"
+)
+
+const (
+	// CredentialStorePlaintext stores the token inline in the context of the config file.
+	CredentialStorePlaintext = "plaintext"
+	// CredentialStoreFile stores tokens in a separate file, encrypted with a passphrase or an age key.
+	CredentialStoreFile = "file"
+	// CredentialStoreHelper delegates token storage to an external credential helper.
+	CredentialStoreHelper = "helper"
+
+	// CredentialPassphraseEnv can be used to provide the passphrase for the encrypted credentials file non-interactively.
+	CredentialPassphraseEnv = "METAL_STACK_CLOUD_CREDENTIAL_PASSPHRASE"
+	// CredentialHelperPrefix is prepended to the helper name in order to find the helper executable in the path.
+	CredentialHelperPrefix = BinaryName + "-credential-"
+
+	defaultCredentialsFile = "credentials.enc"
+)
+
+var CredentialStores = []string{CredentialStorePlaintext, CredentialStoreFile, CredentialStoreHelper}
+
+// CredentialStoreConfig defines where the secrets of the contexts are stored.
+type CredentialStoreConfig struct {
+	// Backend is one of plaintext, file or helper, defaults to plaintext.
+	Backend string `json:"backend"`
+	// Path to the encrypted credentials file, defaults to credentials.enc next to the config file.
+	Path string `json:"path,omitempty"`
+	// AgeRecipient encrypts the credentials file for the given age recipient instead of using a passphrase.
+	AgeRecipient string `json:"age-recipient,omitempty"`
+	// AgeIdentity is the age identity file used for decrypting the credentials file.
+	AgeIdentity string `json:"age-identity,omitempty"`
+	// Helper is the name of the credential helper (resolved as metal-credential-<name> from the path) or a path to an executable.
+	Helper string `json:"helper,omitempty"`
+}
+
+// CredentialStore persists secrets referenced by contexts.
+type CredentialStore interface {
+	Get(ref string) (string, error)
+	Store(ref, secret string) error
+	Erase(ref string) error
+}
+
+// NewCredentialStore returns the credential store configured for the given contexts.
+func (c *Config) NewCredentialStore(ctxs *Contexts) (CredentialStore, error) {
+	cfg := ctxs.CredentialStore
+	if cfg == nil {
+		cfg = &CredentialStoreConfig{}
+	}
+
+	switch cfg.Backend {
+	case "", CredentialStorePlaintext:
+		return &plaintextStore{ctxs: ctxs}, nil
+	case CredentialStoreFile:
+		p, err := credentialsFilePath(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return &fileStore{
+			c:            c,
+			path:         p,
+			ageRecipient: cfg.AgeRecipient,
+			ageIdentity:  cfg.AgeIdentity,
+		}, nil
+	case CredentialStoreHelper:
+		if cfg.Helper == "" {
+			return nil, errors.New("credential store helper requires a helper to be configured")
+		}
+
+		return &helperStore{helper: cfg.Helper}, nil
+	default:
+		return nil, fmt.Errorf("unsupported credential store backend %q, must be one of %s", cfg.Backend, strings.Join(CredentialStores, "|"))
+	}
+}
+
+// credentialStoreLocation identifies where the configured credential store keeps the secrets. Two configurations
+// with the same location share their secrets, even if they differ in other settings like the encryption.
+func credentialStoreLocation(cfg *CredentialStoreConfig) (string, error) {
+	if cfg == nil {
+		cfg = &CredentialStoreConfig{}
+	}
+
+	switch cfg.Backend {
+	case "", CredentialStorePlaintext:
+		return CredentialStorePlaintext, nil
+	case CredentialStoreFile:
+		p, err := credentialsFilePath(cfg)
+		if err != nil {
+			return "", err
+		}
+
+		return CredentialStoreFile + ":" + p, nil
+	case CredentialStoreHelper:
+		return CredentialStoreHelper + ":" + helperExecutable(cfg.Helper), nil
+	default:
+		return "", fmt.Errorf("unsupported credential store backend %q, must be one of %s", cfg.Backend, strings.Join(CredentialStores, "|"))
+	}
+}
+
+func credentialsFilePath(cfg *CredentialStoreConfig) (string, error) {
+	if cfg.Path != "" {
+		return path.Clean(cfg.Path), nil
+	}
+
+	configPath, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(path.Dir(configPath), defaultCredentialsFile), nil
+}
+
+// MigrateCredentialStore switches the contexts to the given credential store, moves all tokens into it and writes the contexts.
+// The tokens are removed from the previous credential store afterwards unless both stores keep their secrets at the same location.
+func (c *Config) MigrateCredentialStore(ctxs *Contexts, target *CredentialStoreConfig) error {
+	tokens := map[string]string{}
+	for _, ctx := range ctxs.Contexts {
+		token, err := c.LookupToken(ctxs, ctx)
+		if err != nil {
+			return err
+		}
+
+		tokens[ctx.Name] = token
+	}
+
+	previous := *ctxs
+
+	previousLocation, err := credentialStoreLocation(previous.CredentialStore)
+	if err != nil {
+		return err
+	}
+
+	if target != nil && (target.Backend == "" || target.Backend == CredentialStorePlaintext) {
+		target = nil
+	}
+
+	ctxs.CredentialStore = target
+
+	// verify the configuration before touching any tokens
+	_, err = c.NewCredentialStore(ctxs)
+	if err != nil {
+		return err
+	}
+
+	location, err := credentialStoreLocation(target)
+	if err != nil {
+		return err
+	}
+
+	sameLocation := location == previousLocation
+
+	if sameLocation && target != nil && target.Backend == CredentialStoreFile {
+		// the file is rewritten with the new settings, so it must not be decrypted with them
+		var restore func()
+		restore, err = c.moveAsideCredentialsFile(target)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err != nil {
+				restore()
+			}
+		}()
+	}
+
+	var erase []Context
+	for _, ctx := range ctxs.Contexts {
+		if ctx.TokenRef != "" {
+			erase = append(erase, *ctx)
+		}
+
+		token := tokens[ctx.Name]
+		if token == "" {
+			ctx.Token = ""
+			ctx.TokenRef = ""
+			continue
+		}
+
+		err = c.StoreToken(ctxs, ctx, token)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.WriteContexts(ctxs)
+	if err != nil {
+		return err
+	}
+
+	if sameLocation {
+		return nil
+	}
+
+	// cleanup the tokens from the previous store, this is best effort as the new configuration was already written
+	for _, ctx := range erase {
+		eraseErr := c.EraseToken(&previous, &ctx)
+		if eraseErr != nil {
+			_, _ = fmt.Fprintf(c.Err, "%s unable to remove token of context %q from previous credential store: %s\n", color.YellowString("⚠"), ctx.Name, eraseErr)
+		}
+	}
+
+	return nil
+}
+
+// moveAsideCredentialsFile removes the credentials file such that it is written from scratch,
+// the returned function restores the previous file.
+func (c *Config) moveAsideCredentialsFile(cfg *CredentialStoreConfig) (func(), error) {
+	p, err := credentialsFilePath(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// the passphrase of the previous file is not reused for the new one
+	delete(c.credentialPassphrases, p)
+
+	raw, err := afero.ReadFile(c.Fs, p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return func() {}, nil
+		}
+		return nil, fmt.Errorf("unable to read credentials file: %w", err)
+	}
+
+	err = c.Fs.Remove(p)
+	if err != nil {
+		return nil, fmt.Errorf("unable to replace credentials file: %w", err)
+	}
+
+	return func() {
+		_ = afero.WriteFile(c.Fs, p, raw, 0600)
+	}, nil
+}
+
+// LookupToken returns the api token of the given context, resolving the token reference through the credential store if necessary.
+func (c *Config) LookupToken(ctxs *Contexts, ctx *Context) (string, error) {
+	if ctx.TokenRef == "" {
+		return ctx.Token, nil
+	}
+
+	store, err := c.NewCredentialStore(ctxs)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := store.Get(ctx.TokenRef)
+	if err != nil {
+		return "", fmt.Errorf("unable to retrieve token of context %q from credential store: %w", ctx.Name, err)
+	}
+
+	return token, nil
+}
+
+// StoreToken puts the token into the configured credential store and lets the context reference it.
+// The given context must already be part of the contexts.
+func (c *Config) StoreToken(ctxs *Contexts, ctx *Context, token string) error {
+	store, err := c.NewCredentialStore(ctxs)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := store.(*plaintextStore); ok {
+		ctx.Token = token
+		ctx.TokenRef = ""
+		return nil
+	}
+
+	ref := ctx.Name
+
+	err = store.Store(ref, token)
+	if err != nil {
+		return fmt.Errorf("unable to store token of context %q in credential store: %w", ctx.Name, err)
+	}
+
+	ctx.Token = ""
+	ctx.TokenRef = ref
+
+	return nil
+}
+
+// EraseToken removes the token of the given context from the credential store.
+func (c *Config) EraseToken(ctxs *Contexts, ctx *Context) error {
+	if ctx.TokenRef == "" {
+		ctx.Token = ""
+		return nil
+	}
+
+	store, err := c.NewCredentialStore(ctxs)
+	if err != nil {
+		return err
+	}
+
+	err = store.Erase(ctx.TokenRef)
+	if err != nil {
+		return fmt.Errorf("unable to erase token of context %q from credential store: %w", ctx.Name, err)
+	}
+
+	ctx.TokenRef = ""
+
+	return nil
+}
+
+// plaintextStore keeps the token inline in the context, the ref is the name of the context.
+type plaintextStore struct {
+	ctxs *Contexts
+}
+
+func (s *plaintextStore) Get(ref string) (string, error) {
+	ctx, ok := s.ctxs.Get(ref)
+	if !ok {
+		return "", fmt.Errorf("no context with name %q found", ref)
+	}
+	return ctx.Token, nil
+}
+
+func (s *plaintextStore) Store(ref, secret string) error {
+	ctx, ok := s.ctxs.Get(ref)
+	if !ok {
+		return fmt.Errorf("no context with name %q found", ref)
+	}
+	ctx.Token = secret
+	return nil
+}
+
+func (s *plaintextStore) Erase(ref string) error {
+	if ctx, ok := s.ctxs.Get(ref); ok {
+		ctx.Token = ""
+	}
+	return nil
+}
+
+// fileStore keeps all secrets in a single file, encrypted either with a passphrase or with age.
+type fileStore struct {
+	c            *Config
+	path         string
+	ageRecipient string
+	ageIdentity  string
+}
+
+func (s *fileStore) Get(ref string) (string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	secret, ok := secrets[ref]
+	if !ok {
+		return "", fmt.Errorf("no secret %q found in %s", ref, s.path)
+	}
+
+	return secret, nil
+}
+
+func (s *fileStore) Store(ref, secret string) error {
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	secrets[ref] = secret
+
+	return s.save(secrets)
+}
+
+func (s *fileStore) Erase(ref string) error {
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[ref]; !ok {
+		return nil
+	}
+
+	delete(secrets, ref)
+
+	return s.save(secrets)
+}
+
+func (s *fileStore) load() (map[string]string, error) {
+	raw, err := afero.ReadFile(s.c.Fs, s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("unable to read credentials file: %w", err)
+	}
+
+	var plaintext []byte
+	if s.ageRecipient != "" || s.ageIdentity != "" {
+		if s.ageIdentity == "" {
+			return nil, errors.New("an age identity is required for decrypting the credentials file")
+		}
+
+		plaintext, err = runAge(raw, "--decrypt", "--identity", s.ageIdentity)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		passphrase, err := s.getPassphrase(false)
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext, err = helpers.DecryptWithPassphrase(raw, passphrase)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	secrets := map[string]string{}
+	err = json.Unmarshal(plaintext, &secrets)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse credentials file: %w", err)
+	}
+
+	return secrets, nil
+}
+
+func (s *fileStore) save(secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	var raw []byte
+	if s.ageRecipient != "" {
+		raw, err = runAge(plaintext, "--encrypt", "--armor", "--recipient", s.ageRecipient)
+		if err != nil {
+			return err
+		}
+	} else {
+		exists, err := afero.Exists(s.c.Fs, s.path)
+		if err != nil {
+			return err
+		}
+
+		passphrase, err := s.getPassphrase(!exists)
+		if err != nil {
+			return err
+		}
+
+		raw, err = helpers.EncryptWithPassphrase(plaintext, passphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.c.Fs.MkdirAll(path.Dir(s.path), 0700)
+	if err != nil {
+		return fmt.Errorf("unable to ensure credentials directory: %w", err)
+	}
+
+	return afero.WriteFile(s.c.Fs, s.path, raw, 0600)
+}
+
+func (s *fileStore) getPassphrase(confirm bool) (string, error) {
+	if p, ok := s.c.credentialPassphrases[s.path]; ok {
+		return p, nil
+	}
+
+	if p, ok := os.LookupEnv(CredentialPassphraseEnv); ok && p != "" {
+		return p, nil
+	}
+
+	p, err := s.c.ReadPassword(fmt.Sprintf("Passphrase for %s: ", s.path))
+	if err != nil {
+		return "", fmt.Errorf("unable to read passphrase for credentials file, consider setting %s: %w", CredentialPassphraseEnv, err)
+	}
+
+	if confirm {
+		again, err := s.c.ReadPassword("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if p != again {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	if p == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+
+	if s.c.credentialPassphrases == nil {
+		s.c.credentialPassphrases = map[string]string{}
+	}
+	s.c.credentialPassphrases[s.path] = p
+
+	return p, nil
+}
+
+func runAge(in []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("age", args...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("age %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// helperStore talks to an external credential helper, similar to docker and git credential helpers.
+//
+// The helper is called with one of the actions get, store or erase as its only argument and receives
+// a JSON object {"ref": "...", "secret": "..."} on stdin. On get, it is expected to print a JSON object
+// of the same form to stdout. A non-zero exit code signals an error.
+type helperStore struct {
+	helper string
+}
+
+type credentialHelperMessage struct {
+	Ref    string `json:"ref"`
+	Secret string `json:"secret,omitempty"`
+}
+
+func (s *helperStore) Get(ref string) (string, error) {
+	out, err := s.call("get", credentialHelperMessage{Ref: ref})
+	if err != nil {
+		return "", err
+	}
+
+	var msg credentialHelperMessage
+	err = json.Unmarshal(out, &msg)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse credential helper response: %w", err)
+	}
+
+	if msg.Secret == "" {
+		return "", fmt.Errorf("credential helper returned no secret for %q", ref)
+	}
+
+	return msg.Secret, nil
+}
+
+func (s *helperStore) Store(ref, secret string) error {
+	_, err := s.call("store", credentialHelperMessage{Ref: ref, Secret: secret})
+	return err
+}
+
+func (s *helperStore) Erase(ref string) error {
+	_, err := s.call("erase", credentialHelperMessage{Ref: ref})
+	return err
+}
+
+func (s *helperStore) call(action string, msg credentialHelperMessage) ([]byte, error) {
+	in, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	executable := helperExecutable(s.helper)
+
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(executable, action) // nolint:gosec
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s %s failed: %w", executable, action, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// helperExecutable resolves the name of a credential helper to its executable, paths are taken as they are.
+func helperExecutable(helper string) string {
+	if strings.ContainsRune(helper, os.PathSeparator) {
+		return helper
+	}
+	return CredentialHelperPrefix + helper
+}
+
+// ReadPassword reads a secret from the terminal without echoing it.
+func (c *Config) ReadPassword(prompt string) (string, error) {
+	if !c.IsInteractive() {
+		return "", errors.New("stdin is not a terminal")
+	}
+
+	f := c.In.(*os.File)
+
+	_, _ = fmt.Fprint(c.PromptOut, prompt)
+
+	raw, err := term.ReadPassword(int(f.Fd()))
+	_, _ = fmt.Fprintln(c.PromptOut)
+	if err != nil {
+		return "", err
+	}
+
+	return string(raw), nil
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+const credentialHelperTestDirEnv = "METAL_CREDENTIAL_HELPER_TEST_DIR"
+
+// TestMain lets the test binary act as a credential helper, which stores every secret in a file
+// of the directory given by the environment and logs all received messages.
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(credentialHelperTestDirEnv); dir != "" {
+		os.Exit(runCredentialHelper(dir, os.Args[len(os.Args)-1]))
+	}
+
+	os.Exit(m.Run())
+}
+
+func runCredentialHelper(dir, action string) int {
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return 1
+	}
+
+	log, err := os.OpenFile(path.Join(dir, "log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return 1
+	}
+	defer log.Close()
+	_, _ = fmt.Fprintf(log, "%s %s\n", action, in)
+
+	var msg credentialHelperMessage
+	if err := json.Unmarshal(in, &msg); err != nil {
+		return 1
+	}
+
+	secretFile := path.Join(dir, "secret-"+msg.Ref)
+
+	switch action {
+	case "get":
+		secret, err := os.ReadFile(secretFile)
+		if err != nil {
+			return 1
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(credentialHelperMessage{Ref: msg.Ref, Secret: string(secret)})
+	case "store":
+		if err := os.WriteFile(secretFile, []byte(msg.Secret), 0600); err != nil {
+			return 1
+		}
+	case "erase":
+		_ = os.Remove(secretFile)
+	default:
+		return 1
+	}
+
+	return 0
+}
+
+func newTestConfig(t *testing.T) *Config {
+	viper.Set("config", "/conf/config.yaml")
+	t.Cleanup(viper.Reset)
+
+	return &Config{
+		Fs:        afero.NewMemMapFs(),
+		Out:       io.Discard,
+		PromptOut: io.Discard,
+		Err:       io.Discard,
+	}
+}
+
+func TestPlaintextStore(t *testing.T) {
+	c := newTestConfig(t)
+
+	ctxs := &Contexts{Contexts: []*Context{{Name: "a"}}}
+	ctx := ctxs.Contexts[0]
+
+	require.NoError(t, c.StoreToken(ctxs, ctx, "token-a"))
+	require.Equal(t, &Context{Name: "a", Token: "token-a"}, ctx)
+
+	token, err := c.LookupToken(ctxs, ctx)
+	require.NoError(t, err)
+	require.Equal(t, "token-a", token)
+
+	require.NoError(t, c.EraseToken(ctxs, ctx))
+	require.Equal(t, &Context{Name: "a"}, ctx)
+}
+
+func TestFileStore(t *testing.T) {
+	t.Setenv(CredentialPassphraseEnv, "correct horse")
+
+	c := newTestConfig(t)
+
+	ctxs := &Contexts{
+		Contexts:        []*Context{{Name: "a"}, {Name: "b"}},
+		CredentialStore: &CredentialStoreConfig{Backend: CredentialStoreFile},
+	}
+
+	require.NoError(t, c.StoreToken(ctxs, ctxs.Contexts[0], "token-a"))
+	require.NoError(t, c.StoreToken(ctxs, ctxs.Contexts[1], "token-b"))
+	require.Equal(t, &Context{Name: "a", TokenRef: "a"}, ctxs.Contexts[0])
+
+	raw, err := afero.ReadFile(c.Fs, "/conf/credentials.enc")
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "token-a")
+
+	token, err := c.LookupToken(ctxs, ctxs.Contexts[1])
+	require.NoError(t, err)
+	require.Equal(t, "token-b", token)
+
+	require.NoError(t, c.EraseToken(ctxs, ctxs.Contexts[0]))
+
+	_, err = c.LookupToken(ctxs, &Context{Name: "a", TokenRef: "a"})
+	require.ErrorContains(t, err, `no secret "a" found in /conf/credentials.enc`)
+
+	t.Setenv(CredentialPassphraseEnv, "wrong")
+
+	_, err = c.LookupToken(ctxs, ctxs.Contexts[1])
+	require.Error(t, err)
+}
+
+func TestFileStorePassphraseIsNotSharedBetweenFiles(t *testing.T) {
+	c := newTestConfig(t)
+	c.credentialPassphrases = map[string]string{"/conf/a.enc": "secret"}
+
+	require.NoError(t, afero.WriteFile(c.Fs, "/conf/b.enc", []byte("encrypted"), 0600))
+
+	ctxs := &Contexts{
+		Contexts:        []*Context{{Name: "a", TokenRef: "a"}},
+		CredentialStore: &CredentialStoreConfig{Backend: CredentialStoreFile, Path: "/conf/b.enc"},
+	}
+
+	_, err := c.LookupToken(ctxs, ctxs.Contexts[0])
+	require.ErrorContains(t, err, "unable to read passphrase for credentials file")
+}
+
+func TestHelperStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(credentialHelperTestDirEnv, dir)
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	c := newTestConfig(t)
+
+	ctxs := &Contexts{
+		Contexts:        []*Context{{Name: "a"}},
+		CredentialStore: &CredentialStoreConfig{Backend: CredentialStoreHelper, Helper: executable},
+	}
+	ctx := ctxs.Contexts[0]
+
+	require.NoError(t, c.StoreToken(ctxs, ctx, "token-a"))
+	require.Equal(t, &Context{Name: "a", TokenRef: "a"}, ctx)
+
+	token, err := c.LookupToken(ctxs, ctx)
+	require.NoError(t, err)
+	require.Equal(t, "token-a", token)
+
+	require.NoError(t, c.EraseToken(ctxs, ctx))
+
+	_, err = c.LookupToken(ctxs, &Context{Name: "a", TokenRef: "a"})
+	require.ErrorContains(t, err, "unable to retrieve token of context \"a\" from credential store")
+
+	log, err := os.ReadFile(path.Join(dir, "log"))
+	require.NoError(t, err)
+
+	if diff := cmp.Diff([]string{
+		`store {"ref":"a","secret":"token-a"}`,
+		`get {"ref":"a"}`,
+		`erase {"ref":"a"}`,
+		`get {"ref":"a"}`,
+	}, strings.Split(strings.TrimSpace(string(log)), "\n")); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+}
+
+func TestMigrateCredentialStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous *CredentialStoreConfig
+		target   *CredentialStoreConfig
+		// wantRef is true if the tokens are expected to be referenced from the target store
+		wantRef bool
+		// wantErased lists the credential files which must not contain the tokens anymore
+		wantErased []string
+	}{
+		{
+			name:    "plaintext to file",
+			target:  &CredentialStoreConfig{Backend: CredentialStoreFile},
+			wantRef: true,
+		},
+		{
+			name:       "file to plaintext",
+			previous:   &CredentialStoreConfig{Backend: CredentialStoreFile},
+			target:     &CredentialStoreConfig{Backend: CredentialStorePlaintext},
+			wantErased: []string{"/conf/credentials.enc"},
+		},
+		{
+			name:       "file to another file",
+			previous:   &CredentialStoreConfig{Backend: CredentialStoreFile},
+			target:     &CredentialStoreConfig{Backend: CredentialStoreFile, Path: "/other/credentials.enc"},
+			wantRef:    true,
+			wantErased: []string{"/conf/credentials.enc"},
+		},
+		{
+			name:     "file to the same file with different settings",
+			previous: &CredentialStoreConfig{Backend: CredentialStoreFile},
+			target:   &CredentialStoreConfig{Backend: CredentialStoreFile, Path: "/conf/./credentials.enc"},
+			wantRef:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(CredentialPassphraseEnv, "correct horse")
+
+			c := newTestConfig(t)
+
+			ctxs := &Contexts{
+				CurrentContext:  "a",
+				Contexts:        []*Context{{Name: "a"}, {Name: "b"}, {Name: "empty"}},
+				CredentialStore: tt.previous,
+			}
+			require.NoError(t, c.StoreToken(ctxs, ctxs.Contexts[0], "token-a"))
+			require.NoError(t, c.StoreToken(ctxs, ctxs.Contexts[1], "token-b"))
+
+			require.NoError(t, c.MigrateCredentialStore(ctxs, tt.target))
+
+			written, err := c.GetContexts()
+			require.NoError(t, err)
+
+			for name, want := range map[string]string{"a": "token-a", "b": "token-b", "empty": ""} {
+				ctx, ok := written.Get(name)
+				require.True(t, ok)
+
+				token, err := c.LookupToken(written, ctx)
+				require.NoError(t, err)
+				require.Equal(t, want, token)
+
+				if want == "" {
+					continue
+				}
+
+				if tt.wantRef {
+					require.Equal(t, name, ctx.TokenRef)
+					require.Empty(t, ctx.Token)
+				} else {
+					require.Empty(t, ctx.TokenRef)
+				}
+			}
+
+			for _, file := range tt.wantErased {
+				store := &fileStore{c: c, path: file}
+
+				secrets, err := store.load()
+				require.NoError(t, err)
+				require.Empty(t, secrets)
+			}
+		})
+	}
+}
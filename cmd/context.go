@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/metal-stack-cloud/cli/cmd/config"
@@ -92,6 +94,8 @@ func newContextCmd(c *config.Config) *cobra.Command {
 	contextAddCmd.Flags().Duration("timeout", 0, "sets a default request timeout")
 	contextAddCmd.Flags().Bool("activate", false, "immediately switches to the new context")
 	contextAddCmd.Flags().String("provider", "", "sets the login provider for this context")
+	contextAddCmd.Flags().Duration("token-expiry-warning", config.DefaultTokenExpiryWarning, "prints a warning when the token expires within this duration, 0 disables the warning")
+	contextAddCmd.Flags().String("timezone", "", "sets the timezone in which times without timezone information are interpreted, e.g. Europe/Berlin, defaults to UTC")
 
 	genericcli.Must(contextAddCmd.MarkFlagRequired("api-token"))
 
@@ -109,9 +113,31 @@ func newContextCmd(c *config.Config) *cobra.Command {
 	contextUpdateCmd.Flags().Duration("timeout", 0, "sets a default request timeout")
 	contextUpdateCmd.Flags().Bool("activate", false, "immediately switches to the new context")
 	contextUpdateCmd.Flags().String("provider", "", "sets the login provider for this context")
+	contextUpdateCmd.Flags().Duration("token-expiry-warning", config.DefaultTokenExpiryWarning, "prints a warning when the token expires within this duration, 0 disables the warning")
+	contextUpdateCmd.Flags().String("timezone", "", "sets the timezone in which times without timezone information are interpreted, e.g. Europe/Berlin, defaults to UTC")
 
 	genericcli.Must(contextUpdateCmd.RegisterFlagCompletionFunc("default-project", c.Completion.ProjectListCompletion))
 
+	contextSetCredentialStoreCmd := &cobra.Command{
+		Use:   "set-credential-store <" + strings.Join(config.CredentialStores, "|") + ">",
+		Short: "sets the backend used for storing api tokens of the contexts",
+		Long: `sets the backend where the api tokens of all contexts are stored and migrates existing tokens into it.
+
+plaintext stores the tokens inline in the config file (default).
+
+file stores the tokens in an encrypted file. Either a passphrase is used for encryption, which is prompted or read from the ` + config.CredentialPassphraseEnv + ` environment variable, or the file is encrypted for an age recipient (requires the age binary in the path).
+
+helper delegates the storage to an external credential helper. The helper is called with one of the actions get, store or erase as its only argument and receives a JSON object {"ref": "...", "secret": "..."} on stdin. For get, the helper must print a JSON object of the same form to stdout. A helper named "pass" is looked up as ` + config.CredentialHelperPrefix + `pass in the path.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return w.setCredentialStore(args)
+		},
+		ValidArgs: config.CredentialStores,
+	}
+	contextSetCredentialStoreCmd.Flags().String("path", "", "path of the encrypted credentials file, defaults to credentials.enc next to the config file (only for file backend)")
+	contextSetCredentialStoreCmd.Flags().String("age-recipient", "", "encrypts the credentials file for this age recipient instead of using a passphrase (only for file backend)")
+	contextSetCredentialStoreCmd.Flags().String("age-identity", "", "the age identity file used to decrypt the credentials file (only for file backend)")
+	contextSetCredentialStoreCmd.Flags().String("helper", "", "name or path of the credential helper (only for helper backend)")
+
 	contextCmd.AddCommand(
 		contextListCmd,
 		contextSwitchCmd,
@@ -120,6 +146,7 @@ func newContextCmd(c *config.Config) *cobra.Command {
 		contextRemoveCmd,
 		contextShortCmd,
 		contextSetProjectCmd,
+		contextSetCredentialStoreCmd,
 	)
 
 	return contextCmd
@@ -169,14 +196,28 @@ func (c *ctx) add(args []string) error {
 	ctx := &config.Context{
 		Name:           name,
 		ApiURL:         pointer.PointerOrNil(viper.GetString("api-url")),
-		Token:          viper.GetString("api-token"),
 		DefaultProject: viper.GetString("default-project"),
 		Timeout:        pointer.PointerOrNil(viper.GetDuration("timeout")),
 		Provider:       viper.GetString("provider"),
 	}
 
+	if viper.IsSet("token-expiry-warning") {
+		ctx.TokenExpiryWarning = pointer.Pointer(viper.GetDuration("token-expiry-warning"))
+	}
+	if viper.IsSet("timezone") {
+		ctx.Timezone, err = validateTimezone(viper.GetString("timezone"))
+		if err != nil {
+			return err
+		}
+	}
+
 	ctxs.Contexts = append(ctxs.Contexts, ctx)
 
+	err = c.c.StoreToken(ctxs, ctx, viper.GetString("api-token"))
+	if err != nil {
+		return err
+	}
+
 	if viper.GetBool("activate") || ctxs.CurrentContext == "" {
 		ctxs.PreviousContext = ctxs.CurrentContext
 		ctxs.CurrentContext = ctx.Name
@@ -212,7 +253,10 @@ func (c *ctx) update(args []string) error {
 		ctx.ApiURL = pointer.PointerOrNil(viper.GetString("api-url"))
 	}
 	if viper.IsSet("api-token") {
-		ctx.Token = viper.GetString("api-token")
+		err = c.c.StoreToken(ctxs, ctx, viper.GetString("api-token"))
+		if err != nil {
+			return err
+		}
 	}
 	if viper.IsSet("default-project") {
 		ctx.DefaultProject = viper.GetString("default-project")
@@ -223,6 +267,15 @@ func (c *ctx) update(args []string) error {
 	if viper.IsSet("provider") {
 		ctx.Provider = viper.GetString("provider")
 	}
+	if viper.IsSet("token-expiry-warning") {
+		ctx.TokenExpiryWarning = pointer.Pointer(viper.GetDuration("token-expiry-warning"))
+	}
+	if viper.IsSet("timezone") {
+		ctx.Timezone, err = validateTimezone(viper.GetString("timezone"))
+		if err != nil {
+			return err
+		}
+	}
 	if viper.GetBool("activate") {
 		ctxs.PreviousContext = ctxs.CurrentContext
 		ctxs.CurrentContext = ctx.Name
@@ -238,6 +291,19 @@ func (c *ctx) update(args []string) error {
 	return nil
 }
 
+func validateTimezone(tz string) (string, error) {
+	if tz == "" {
+		return "", nil
+	}
+
+	_, err := time.LoadLocation(tz)
+	if err != nil {
+		return "", fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+
+	return tz, nil
+}
+
 func (c *ctx) remove(args []string) error {
 	name, err := genericcli.GetExactlyOneArg(args)
 	if err != nil {
@@ -254,6 +320,11 @@ func (c *ctx) remove(args []string) error {
 		return fmt.Errorf("no context with name %q found", name)
 	}
 
+	err = c.c.EraseToken(ctxs, ctx)
+	if err != nil {
+		return err
+	}
+
 	ctxs.Delete(ctx.Name)
 
 	err = c.c.WriteContexts(ctxs)
@@ -337,3 +408,30 @@ func (c *ctx) setProject(args []string) error {
 
 	return nil
 }
+
+func (c *ctx) setCredentialStore(args []string) error {
+	backend, err := genericcli.GetExactlyOneArg(args)
+	if err != nil {
+		return err
+	}
+
+	ctxs, err := c.c.GetContexts()
+	if err != nil {
+		return err
+	}
+
+	err = c.c.MigrateCredentialStore(ctxs, &config.CredentialStoreConfig{
+		Backend:      backend,
+		Path:         viper.GetString("path"),
+		AgeRecipient: viper.GetString("age-recipient"),
+		AgeIdentity:  viper.GetString("age-identity"),
+		Helper:       viper.GetString("helper"),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(c.c.Out, "%s switched credential store to \"%s\" and migrated %d context(s)\n", color.GreenString("✔"), color.GreenString(backend), len(ctxs.Contexts))
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// checkTokenExpiration warns before api calls are made if the token is about to expire.
+// In case the token has already expired and the cli runs in a terminal, the user is offered to login
+// and the command continues with the new token.
+func checkTokenExpiration(c *config.Config, cmd *cobra.Command) error {
+	if skipTokenExpirationCheck(cmd) {
+		return nil
+	}
+
+	token := c.GetToken()
+	if token == "" {
+		return nil
+	}
+
+	cs, err := helpers.ParseTokenClaims(token)
+	if err != nil || cs.ExpiresAt == nil {
+		return nil
+	}
+
+	remaining := time.Until(cs.ExpiresAt.Time)
+
+	if remaining > 0 {
+		if threshold := c.GetTokenExpiryWarning(); threshold > 0 && remaining < threshold {
+			_, _ = fmt.Fprintf(c.Err, "%s the token of context %q expires in %s (at %s)\n", color.YellowString("⚠"), c.Context.Name, helpers.HumanizeDuration(remaining), cs.ExpiresAt.Format(time.DateTime))
+		}
+
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(c.Err, "%s the token of context %q has expired at %s\n", color.RedString("✗"), c.Context.Name, cs.ExpiresAt.Format(time.DateTime))
+
+	if cs.Type == apiv1.TokenType_TOKEN_TYPE_API.String() || viper.IsSet("api-token") || !c.IsInteractive() {
+		return nil
+	}
+
+	err = genericcli.PromptCustom(&genericcli.PromptConfig{
+		Message:         "Do you want to login now?",
+		ShowAnswers:     true,
+		AcceptedAnswers: genericcli.PromptDefaultAnswers(),
+		DefaultAnswer:   "y",
+		No:              "n",
+		In:              c.In,
+		Out:             c.Err,
+	})
+	if err != nil {
+		// continue with the command, the api will respond with unauthenticated
+		return nil
+	}
+
+	// do not mix up login messages with the actual command output
+	out := c.Out
+	c.Out = c.Err
+	err = (&login{c: c}).loginContext(c.Context.Name)
+	c.Out = out
+	if err != nil {
+		return err
+	}
+
+	c.Client = nil
+
+	return initConfigWithViperCtx(c)
+}
+
+func skipTokenExpirationCheck(cmd *cobra.Command) bool {
+	path := strings.Fields(cmd.CommandPath())
+	if len(path) < 2 {
+		return true
+	}
+
+	switch path[1] {
+	case "login", "context", "markdown", "completion", "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkTokenExpiration(t *testing.T) {
+	token := func(t *testing.T, typ apiv1.TokenType, expiresIn time.Duration) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &helpers.TokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			},
+			Type: typ.String(),
+		}).SignedString([]byte("secret"))
+		require.NoError(t, err)
+		return signed
+	}
+
+	command := func(path string) *cobra.Command {
+		var (
+			fields = strings.Fields(path)
+			root   = &cobra.Command{Use: fields[0]}
+			parent = root
+		)
+		for _, name := range fields[1:] {
+			child := &cobra.Command{Use: name}
+			parent.AddCommand(child)
+			parent = child
+		}
+		return parent
+	}
+
+	tests := []struct {
+		name         string
+		cmd          string
+		token        func(t *testing.T) string
+		warning      *time.Duration
+		wantErr      []string
+		wantNoErr    []string
+		wantNoOutput bool
+	}{
+		{
+			name:    "warns within the threshold",
+			cmd:     "metal ip list",
+			token:   func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_CONSOLE, time.Hour) },
+			wantErr: []string{`the token of context "test" expires in`},
+		},
+		{
+			name:         "no warning outside of the threshold",
+			cmd:          "metal ip list",
+			token:        func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_CONSOLE, 48*time.Hour) },
+			wantNoOutput: true,
+		},
+		{
+			name:    "configured threshold",
+			cmd:     "metal ip list",
+			token:   func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_CONSOLE, 48*time.Hour) },
+			warning: pointer.Pointer(72 * time.Hour),
+			wantErr: []string{`the token of context "test" expires in`},
+		},
+		{
+			name:         "warning disabled",
+			cmd:          "metal ip list",
+			token:        func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_CONSOLE, time.Hour) },
+			warning:      pointer.Pointer(time.Duration(0)),
+			wantNoOutput: true,
+		},
+		{
+			name:      "expired token without terminal does not prompt",
+			cmd:       "metal ip list",
+			token:     func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_CONSOLE, -time.Hour) },
+			wantErr:   []string{`the token of context "test" has expired at`},
+			wantNoErr: []string{"Do you want to login now?"},
+		},
+		{
+			name:      "expired api token does not prompt",
+			cmd:       "metal ip list",
+			token:     func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_API, -time.Hour) },
+			wantErr:   []string{`the token of context "test" has expired at`},
+			wantNoErr: []string{"Do you want to login now?"},
+		},
+		{
+			name:         "login is not checked",
+			cmd:          "metal login",
+			token:        func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_CONSOLE, -time.Hour) },
+			wantNoOutput: true,
+		},
+		{
+			name:         "context commands are not checked",
+			cmd:          "metal context list",
+			token:        func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_CONSOLE, -time.Hour) },
+			wantNoOutput: true,
+		},
+		{
+			name:         "root command is not checked",
+			cmd:          "metal",
+			token:        func(t *testing.T) string { return token(t, apiv1.TokenType_TOKEN_TYPE_CONSOLE, -time.Hour) },
+			wantNoOutput: true,
+		},
+		{
+			name:         "no token",
+			cmd:          "metal ip list",
+			token:        func(t *testing.T) string { return "" },
+			wantNoOutput: true,
+		},
+		{
+			name:         "token without claims is ignored",
+			cmd:          "metal ip list",
+			token:        func(t *testing.T) string { return "not-a-jwt" },
+			wantNoOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				out    bytes.Buffer
+				errOut bytes.Buffer
+				c      = &config.Config{
+					Out: &out,
+					Err: &errOut,
+					In:  bytes.NewBufferString("y\n"),
+					Context: config.Context{
+						Name:               "test",
+						Token:              tt.token(t),
+						TokenExpiryWarning: tt.warning,
+					},
+				}
+			)
+
+			require.NoError(t, checkTokenExpiration(c, command(tt.cmd)))
+
+			require.Empty(t, out.String(), "nothing must be written to the command output")
+
+			if tt.wantNoOutput {
+				require.Empty(t, errOut.String())
+			}
+			for _, want := range tt.wantErr {
+				require.Contains(t, errOut.String(), want)
+			}
+			for _, notWant := range tt.wantNoErr {
+				require.NotContains(t, errOut.String(), notWant)
+			}
+		})
+	}
+}
@@ -1,14 +1,23 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 	apitests "github.com/metal-stack-cloud/api/go/tests"
 	v1 "github.com/metal-stack-cloud/cli/cmd/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
 	"github.com/metal-stack/metal-lib/pkg/tag"
 	"github.com/metal-stack/metal-lib/pkg/testcommon"
@@ -16,6 +25,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/runtime/protoimpl"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 var (
@@ -30,6 +40,17 @@ var (
 			Tags:        []string{tag.New(tag.ClusterServiceFQN, "<cluster>/default/ingress-nginx")},
 		}
 	}
+	bulkIP = func(n int) *apiv1.IP {
+		return &apiv1.IP{
+			Uuid:        fmt.Sprintf("00000000-0000-0000-0000-%012d", n),
+			Ip:          fmt.Sprintf("10.0.0.%d", n),
+			Name:        fmt.Sprintf("ingress-%d", n),
+			Description: "a description",
+			Project:     "a",
+			Type:        apiv1.IPType_IP_TYPE_STATIC,
+			Tags:        []string{"a=b"},
+		}
+	}
 	ip2 = func() *apiv1.IP {
 		return &apiv1.IP{
 			Uuid:        "9cef40ec-29c6-4dfa-aee8-47ee1f49223d",
@@ -92,6 +113,107 @@ IP       PROJECT  ID                                    TYPE       NAME  DESCRIP
 | 4.3.2.1 | b       | 9cef40ec-29c6-4dfa-aee8-47ee1f49223d | ephemeral | b    |                  |
 `),
 		},
+		{
+			Name: "create multiple",
+			Cmd: func(want []*apiv1.IP) []string {
+				args := []string{"ip", "create", "--project", "a", "--description", "a description", "--tags", "a=b", "--static",
+					"--count", "2", "--name-template", "ingress-{{ .Number }}", "--parallelism", "1", "--atomic"}
+				AssertExhaustiveArgs(t, args, append(commonExcludedFileArgs(), "name")...)
+				return args
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					IP: func(m *mock.Mock) {
+						for _, ip := range []*apiv1.IP{bulkIP(1), bulkIP(2)} {
+							m.On("Allocate", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(v1.IpResponseToCreate(ip)), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(connect.NewResponse(&apiv1.IPServiceAllocateResponse{
+								Ip: ip,
+							}), nil)
+						}
+					},
+				},
+			},
+			Want: []*apiv1.IP{
+				bulkIP(1),
+				bulkIP(2),
+			},
+			WantTable: pointer.Pointer(`
+IP        PROJECT  ID                                    TYPE    NAME       ATTACHED SERVICE  
+10.0.0.1  a        00000000-0000-0000-0000-000000000001  static  ingress-1  
+10.0.0.2  a        00000000-0000-0000-0000-000000000002  static  ingress-2
+`),
+		},
+		{
+			Name: "update by selector",
+			Cmd: func(want []*apiv1.IP) []string {
+				args := []string{"ip", "update", "--project", "a", "--selector", "a=b", "--description", "new description", "--tags", "a=b,c=d", "--static"}
+				AssertExhaustiveArgs(t, args, append(commonExcludedFileArgs(), "name")...)
+				return args
+			},
+			MockStdin: bytes.NewBufferString("y"),
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					IP: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.IPServiceListRequest{
+							Project: "a",
+						})).Return(&connect.Response[apiv1.IPServiceListResponse]{
+							Msg: &apiv1.IPServiceListResponse{
+								Ips: []*apiv1.IP{ip1(), ip2(), bulkIP(1)},
+							},
+						}, nil)
+
+						for _, ip := range []*apiv1.IP{ip2(), bulkIP(1)} {
+							ip.Description = "new description"
+							ip.Tags = []string{"a=b", "c=d"}
+							ip.Type = apiv1.IPType_IP_TYPE_STATIC
+
+							m.On("Update", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(v1.IpResponseToUpdate(ip)), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(connect.NewResponse(&apiv1.IPServiceUpdateResponse{
+								Ip: ip,
+							}), nil)
+						}
+					},
+				},
+			},
+			Want: []*apiv1.IP{
+				func() *apiv1.IP {
+					ip := ip2()
+					ip.Description = "new description"
+					ip.Tags = []string{"a=b", "c=d"}
+					ip.Type = apiv1.IPType_IP_TYPE_STATIC
+					return ip
+				}(),
+				func() *apiv1.IP {
+					ip := bulkIP(1)
+					ip.Tags = []string{"a=b", "c=d"}
+					ip.Description = "new description"
+					return ip
+				}(),
+			},
+			WantTable: pointer.Pointer(`
+IP        PROJECT  ID                                    TYPE    NAME       ATTACHED SERVICE  
+4.3.2.1   b        9cef40ec-29c6-4dfa-aee8-47ee1f49223d  static  b          
+10.0.0.1  a        00000000-0000-0000-0000-000000000001  static  ingress-1
+`),
+		},
+		{
+			Name: "update by selector without matches",
+			Cmd: func(want []*apiv1.IP) []string {
+				return []string{"ip", "update", "--project", "a", "--selector", "env=prod", "--description", "new description"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					IP: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.IPServiceListRequest{
+							Project: "a",
+						})).Return(&connect.Response[apiv1.IPServiceListResponse]{
+							Msg: &apiv1.IPServiceListResponse{
+								Ips: []*apiv1.IP{ip1(), ip2()},
+							},
+						}, nil)
+					},
+				},
+			},
+			WantErr: errors.New(`no ips match the selector "env=prod"`),
+		},
 		{
 			Name: "apply",
 			Cmd: func(want []*apiv1.IP) []string {
@@ -257,7 +379,7 @@ IP       PROJECT  ID                                    TYPE    NAME  DESCRIPTIO
 				if want.Type == apiv1.IPType_IP_TYPE_STATIC {
 					args = append(args, "--static")
 				}
-				AssertExhaustiveArgs(t, args, commonExcludedFileArgs()...)
+				AssertExhaustiveArgs(t, args, append(commonExcludedFileArgs(), "count", "name-template", "parallelism", "atomic")...)
 				return args
 			},
 			ClientMocks: &apitests.ClientMockFns{
@@ -278,7 +400,7 @@ IP       PROJECT  ID                                    TYPE    NAME  DESCRIPTIO
 				if want.Type == apiv1.IPType_IP_TYPE_STATIC {
 					args = append(args, "--static")
 				}
-				AssertExhaustiveArgs(t, args, commonExcludedFileArgs()...)
+				AssertExhaustiveArgs(t, args, append(commonExcludedFileArgs(), "selector")...)
 				return args
 			},
 			ClientMocks: &apitests.ClientMockFns{
@@ -304,3 +426,223 @@ IP       PROJECT  ID                                    TYPE    NAME  DESCRIPTIO
 		tt.TestCmd(t)
 	}
 }
+
+func Test_IPCmd_CreateMultipleErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		mocks   func(m *mock.Mock)
+		wantErr string
+	}{
+		{
+			name: "create multiple atomic releases allocated ips on failure",
+			args: []string{"ip", "create", "--project", "a", "--description", "a description", "--tags", "a=b", "--static",
+				"--count", "3", "--name-template", "ingress-{{ .Number }}", "--parallelism", "1", "--atomic"},
+			mocks: func(m *mock.Mock) {
+				m.On("Allocate", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(v1.IpResponseToCreate(bulkIP(1))), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(connect.NewResponse(&apiv1.IPServiceAllocateResponse{
+					Ip: bulkIP(1),
+				}), nil)
+				m.On("Allocate", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(v1.IpResponseToCreate(bulkIP(2))), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(nil, errors.New("quota exceeded"))
+				// the third allocation must not be started after the failure
+				m.On("Delete", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(&apiv1.IPServiceDeleteRequest{
+					Uuid:    bulkIP(1).Uuid,
+					Project: bulkIP(1).Project,
+				}), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(connect.NewResponse(&apiv1.IPServiceDeleteResponse{
+					Ip: bulkIP(1),
+				}), nil)
+			},
+			wantErr: `unable to allocate ip "ingress-2": quota exceeded`,
+		},
+		{
+			name: "create multiple atomic reports ips that could not be released",
+			args: []string{"ip", "create", "--project", "a", "--description", "a description", "--tags", "a=b", "--static",
+				"--count", "2", "--name-template", "ingress-{{ .Number }}", "--parallelism", "1", "--atomic"},
+			mocks: func(m *mock.Mock) {
+				m.On("Allocate", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(v1.IpResponseToCreate(bulkIP(1))), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(connect.NewResponse(&apiv1.IPServiceAllocateResponse{
+					Ip: bulkIP(1),
+				}), nil)
+				m.On("Allocate", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(v1.IpResponseToCreate(bulkIP(2))), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(nil, errors.New("quota exceeded"))
+				m.On("Delete", mock.Anything, mock.Anything).Return(nil, errors.New("unavailable"))
+			},
+			wantErr: "unable to allocate ip \"ingress-2\": quota exceeded\nunable to release ip 10.0.0.1 (00000000-0000-0000-0000-000000000001), please release it manually: unavailable",
+		},
+		{
+			name: "create multiple without atomic keeps allocated ips",
+			args: []string{"ip", "create", "--project", "a", "--description", "a description", "--tags", "a=b", "--static",
+				"--count", "2", "--name-template", "ingress-{{ .Number }}", "--parallelism", "1"},
+			mocks: func(m *mock.Mock) {
+				m.On("Allocate", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(v1.IpResponseToCreate(bulkIP(1))), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(nil, errors.New("quota exceeded"))
+				m.On("Allocate", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(v1.IpResponseToCreate(bulkIP(2))), cmpopts.IgnoreTypes(protoimpl.MessageState{}))).Return(connect.NewResponse(&apiv1.IPServiceAllocateResponse{
+					Ip: bulkIP(2),
+				}), nil)
+			},
+			wantErr: `allocated 1 of 2 ips: unable to allocate ip "ingress-1": quota exceeded`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Test[[]*apiv1.IP]{
+				ClientMocks: &apitests.ClientMockFns{
+					Apiv1Mocks: &apitests.Apiv1MockFns{
+						IP: tt.mocks,
+					},
+				},
+			}
+
+			_, _, conf := c.newMockConfig(t)
+
+			cmd := newRootCmd(conf)
+			os.Args = append([]string{config.BinaryName}, tt.args...)
+
+			// the allocation errors are joined and wrapped, so they are compared by their message only
+			require.EqualError(t, cmd.Execute(), tt.wantErr)
+		})
+	}
+}
+
+func Test_IPCmd_Usage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/services" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"ServiceList","apiVersion":"v1","items":[
+			{"metadata":{"namespace":"default","name":"ingress-nginx"},"spec":{"type":"LoadBalancer"},"status":{"loadBalancer":{"ingress":[{"ip":"1.1.1.1"}]}}},
+			{"metadata":{"namespace":"default","name":"kubernetes"},"spec":{"type":"ClusterIP"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: test
+`, server.URL)
+
+	var (
+		clusterA = &apiv1.Cluster{Uuid: "c0a5d0a4-c2b5-4d6c-9a52-1f5c0bde5d9f", Name: "cluster-a", Project: "a"}
+		clusterB = &apiv1.Cluster{Uuid: "7f4f7c1e-3e0b-4f3c-8c9a-6c1f7d1b0e2a", Name: "cluster-b", Project: "a"}
+		unused   = bulkIP(3)
+	)
+
+	ipMocks := func(m *mock.Mock) {
+		m.On("List", mock.Anything, connect.NewRequest(&apiv1.IPServiceListRequest{
+			Project: "a",
+		})).Return(&connect.Response[apiv1.IPServiceListResponse]{
+			Msg: &apiv1.IPServiceListResponse{
+				Ips: []*apiv1.IP{unused, ip2(), ip1()},
+			},
+		}, nil)
+	}
+
+	clusterMocks := func(clusters ...*apiv1.Cluster) func(m *mock.Mock) {
+		return func(m *mock.Mock) {
+			m.On("List", mock.Anything, connect.NewRequest(&apiv1.ClusterServiceListRequest{
+				Project: "a",
+			})).Return(&connect.Response[apiv1.ClusterServiceListResponse]{
+				Msg: &apiv1.ClusterServiceListResponse{
+					Clusters: clusters,
+				},
+			}, nil)
+
+			credentials := func(cluster *apiv1.Cluster) any {
+				return testcommon.MatchByCmpDiff(t, connect.NewRequest(&apiv1.ClusterServiceGetCredentialsRequest{
+					Uuid:       cluster.Uuid,
+					Project:    cluster.Project,
+					Expiration: durationpb.New(10 * time.Minute),
+				}), cmpopts.IgnoreTypes(protoimpl.MessageState{}))
+			}
+
+			m.On("GetCredentials", mock.Anything, credentials(clusterA)).Return(connect.NewResponse(&apiv1.ClusterServiceGetCredentialsResponse{
+				Kubeconfig: kubeconfig,
+			}), nil).Maybe()
+			m.On("GetCredentials", mock.Anything, credentials(clusterB)).Return(nil, errors.New("cluster is hibernated")).Maybe()
+		}
+	}
+
+	usage := func(ip *apiv1.IP, typ, status string) *models.IPUsage {
+		return &models.IPUsage{IP: ip.Ip, Uuid: ip.Uuid, Name: ip.Name, Type: typ, Status: status}
+	}
+
+	used := usage(ip1(), "static", models.IPUsageStatusUsed)
+	used.Cluster = clusterA.Name
+	used.ClusterID = clusterA.Uuid
+	used.Namespace = "default"
+	used.Service = "ingress-nginx"
+
+	tests := []*Test[[]*models.IPUsage]{
+		{
+			Name: "used and unused ips",
+			Cmd: func(want []*models.IPUsage) []string {
+				return []string{"ip", "usage", "--project", "a"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					IP:      ipMocks,
+					Cluster: clusterMocks(clusterA),
+				},
+			},
+			Want: []*models.IPUsage{
+				used,
+				usage(ip2(), "ephemeral", models.IPUsageStatusCleanup),
+				usage(unused, "static", models.IPUsageStatusUnused),
+			},
+			WantTable: pointer.Pointer(`
+IP        NAME       TYPE       CLUSTER    SERVICE                STATUS
+1.1.1.1   a          static     cluster-a  default/ingress-nginx  used
+4.3.2.1   b          ephemeral                                    cleanup
+10.0.0.3  ingress-3  static                                       unused
+			`),
+		},
+		{
+			Name: "only unused ips",
+			Cmd: func(want []*models.IPUsage) []string {
+				return []string{"ip", "usage", "--project", "a", "--unused"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					IP:      ipMocks,
+					Cluster: clusterMocks(clusterA),
+				},
+			},
+			Want: []*models.IPUsage{
+				usage(ip2(), "ephemeral", models.IPUsageStatusCleanup),
+				usage(unused, "static", models.IPUsageStatusUnused),
+			},
+		},
+		{
+			Name: "unused ips are unknown if a cluster cannot be inspected",
+			Cmd: func(want []*models.IPUsage) []string {
+				return []string{"ip", "usage", "--project", "a"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					IP:      ipMocks,
+					Cluster: clusterMocks(clusterA, clusterB),
+				},
+			},
+			Want: []*models.IPUsage{
+				used,
+				usage(ip2(), "ephemeral", models.IPUsageStatusUnknown),
+				usage(unused, "static", models.IPUsageStatusUnknown),
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.TestCmd(t)
+	}
+}
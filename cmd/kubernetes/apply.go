@@ -0,0 +1,212 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// FieldManager is the field manager of the objects applied by the cli
+const FieldManager = "metal-cli"
+
+const (
+	// EncryptionSecretName is the name of the secret holding the passphrase of encrypted volumes
+	EncryptionSecretName = "storage-encryption-key"
+	// EncryptionSecretPassphraseKey is the key of the passphrase in the encryption secret
+	EncryptionSecretPassphraseKey = "host-encryption-passphrase"
+)
+
+// ErrSecretDataDiffers is returned when an existing secret would be changed without overwrite
+var ErrSecretDataDiffers = errors.New("secret already exists with different data")
+
+// ApplyOptions control how existing objects are treated
+type ApplyOptions struct {
+	// Overwrite allows replacing the data of an existing secret, otherwise ErrSecretDataDiffers is returned
+	Overwrite bool
+}
+
+// EncryptionSecret returns the secret holding the passphrase of encrypted volumes in the given namespace
+func EncryptionSecret(namespace, passphrase string) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      EncryptionSecretName,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		StringData: map[string]string{
+			EncryptionSecretPassphraseKey: passphrase,
+		},
+	}
+}
+
+// AppliedObject describes the result of applying an object to a cluster
+type AppliedObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Created   bool
+}
+
+func (a AppliedObject) String() string {
+	action := "configured"
+	if a.Created {
+		action = "created"
+	}
+
+	if a.Namespace == "" {
+		return fmt.Sprintf("%s/%s %s", a.Kind, a.Name, action)
+	}
+
+	return fmt.Sprintf("%s/%s in namespace %s %s", a.Kind, a.Name, a.Namespace, action)
+}
+
+// Apply creates or updates the given objects in the cluster the given kubeconfig points to with server-side apply.
+// Supported are persistent volumes, persistent volume claims and secrets, objects are applied in the given order.
+func Apply(ctx context.Context, kubeconfig []byte, opts ApplyOptions, objects ...runtime.Object) ([]AppliedObject, error) {
+	client, err := newClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []AppliedObject
+	for _, obj := range objects {
+		applied, err := apply(ctx, client, opts, obj)
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, *applied)
+	}
+
+	return result, nil
+}
+
+func apply(ctx context.Context, client kubernetes.Interface, opts ApplyOptions, obj runtime.Object) (*AppliedObject, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal object: %w", err)
+	}
+
+	var (
+		// volumes are never forced, conflicts with fields managed by others are returned as error
+		patchOpts = metav1.PatchOptions{FieldManager: FieldManager}
+		get       func() error
+		do        func() error
+		ref       AppliedObject
+	)
+
+	switch o := obj.(type) {
+	case *corev1.PersistentVolume:
+		ref = AppliedObject{Kind: "persistentvolume", Name: o.Name}
+		get = func() error {
+			_, err := client.CoreV1().PersistentVolumes().Get(ctx, o.Name, metav1.GetOptions{})
+			return err
+		}
+		do = func() error {
+			_, err := client.CoreV1().PersistentVolumes().Patch(ctx, o.Name, types.ApplyPatchType, data, patchOpts)
+			return err
+		}
+	case *corev1.PersistentVolumeClaim:
+		ref = AppliedObject{Kind: "persistentvolumeclaim", Namespace: o.Namespace, Name: o.Name}
+		get = func() error {
+			_, err := client.CoreV1().PersistentVolumeClaims(o.Namespace).Get(ctx, o.Name, metav1.GetOptions{})
+			return err
+		}
+		do = func() error {
+			_, err := client.CoreV1().PersistentVolumeClaims(o.Namespace).Patch(ctx, o.Name, types.ApplyPatchType, data, patchOpts)
+			return err
+		}
+	case *corev1.Secret:
+		ref = AppliedObject{Kind: "secret", Namespace: o.Namespace, Name: o.Name}
+		get = func() error {
+			existing, err := client.CoreV1().Secrets(o.Namespace).Get(ctx, o.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if !opts.Overwrite && !secretDataEqual(existing, o) {
+				return fmt.Errorf("secret/%s in namespace %s: %w", o.Name, o.Namespace, ErrSecretDataDiffers)
+			}
+
+			return nil
+		}
+		do = func() error {
+			patchOpts.Force = pointer.Pointer(opts.Overwrite)
+			_, err := client.CoreV1().Secrets(o.Namespace).Patch(ctx, o.Name, types.ApplyPatchType, data, patchOpts)
+			return err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported object type %T", obj)
+	}
+
+	err = get()
+	switch {
+	case apierrors.IsNotFound(err):
+		ref.Created = true
+	case errors.Is(err, ErrSecretDataDiffers):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("unable to get %s/%s: %w", ref.Kind, ref.Name, err)
+	}
+
+	err = do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply %s/%s: %w", ref.Kind, ref.Name, err)
+	}
+
+	return &ref, nil
+}
+
+// secretDataEqual returns true if the existing secret contains the data of the desired secret
+func secretDataEqual(existing, desired *corev1.Secret) bool {
+	value := func(s *corev1.Secret, key string) (string, bool) {
+		if v, ok := s.StringData[key]; ok {
+			return v, true
+		}
+		v, ok := s.Data[key]
+		return string(v), ok
+	}
+
+	keys := map[string]bool{}
+	for k := range desired.StringData {
+		keys[k] = true
+	}
+	for k := range desired.Data {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		want, _ := value(desired, k)
+		got, ok := value(existing, k)
+		if !ok || got != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newClient(kubeconfig []byte) (kubernetes.Interface, error) {
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse kubeconfig: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kubernetes client: %w", err)
+	}
+
+	return client, nil
+}
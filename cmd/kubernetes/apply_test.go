@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+
+	passphrase := func(t *testing.T) string {
+		s, err := client.CoreV1().Secrets("default").Get(ctx, EncryptionSecretName, metav1.GetOptions{})
+		require.NoError(t, err)
+		if v, ok := s.StringData[EncryptionSecretPassphraseKey]; ok {
+			return v
+		}
+		return string(s.Data[EncryptionSecretPassphraseKey])
+	}
+
+	tests := []struct {
+		name    string
+		obj     runtime.Object
+		opts    ApplyOptions
+		want    *AppliedObject
+		wantErr error
+		wantKey string
+	}{
+		{
+			name:    "create secret",
+			obj:     EncryptionSecret("default", "a"),
+			want:    &AppliedObject{Kind: "secret", Namespace: "default", Name: EncryptionSecretName, Created: true},
+			wantKey: "a",
+		},
+		{
+			name:    "apply secret with same passphrase",
+			obj:     EncryptionSecret("default", "a"),
+			want:    &AppliedObject{Kind: "secret", Namespace: "default", Name: EncryptionSecretName},
+			wantKey: "a",
+		},
+		{
+			name:    "refuse to replace passphrase",
+			obj:     EncryptionSecret("default", "b"),
+			wantErr: ErrSecretDataDiffers,
+			wantKey: "a",
+		},
+		{
+			name:    "replace passphrase with overwrite",
+			obj:     EncryptionSecret("default", "b"),
+			opts:    ApplyOptions{Overwrite: true},
+			want:    &AppliedObject{Kind: "secret", Namespace: "default", Name: EncryptionSecretName},
+			wantKey: "b",
+		},
+		{
+			name: "create persistent volume",
+			obj: &corev1.PersistentVolume{
+				TypeMeta:   metav1.TypeMeta{Kind: "PersistentVolume", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+			},
+			want:    &AppliedObject{Kind: "persistentvolume", Name: "pv", Created: true},
+			wantKey: "b",
+		},
+		{
+			name: "create persistent volume claim",
+			obj: &corev1.PersistentVolumeClaim{
+				TypeMeta:   metav1.TypeMeta{Kind: "PersistentVolumeClaim", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "pvc", Namespace: "default"},
+			},
+			want:    &AppliedObject{Kind: "persistentvolumeclaim", Namespace: "default", Name: "pvc", Created: true},
+			wantKey: "b",
+		},
+		{
+			name:    "unsupported object type",
+			obj:     &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a"}},
+			wantErr: errors.New("unsupported object type *v1.ConfigMap"),
+			wantKey: "b",
+		},
+	}
+	// cases run in order against the same cluster
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := apply(ctx, client, tt.opts, tt.obj)
+			switch {
+			case errors.Is(tt.wantErr, ErrSecretDataDiffers):
+				require.ErrorIs(t, err, ErrSecretDataDiffers)
+			case tt.wantErr != nil:
+				require.EqualError(t, err, tt.wantErr.Error())
+			default:
+				require.NoError(t, err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+
+			require.Equal(t, tt.wantKey, passphrase(t))
+		})
+	}
+}
+
+func TestAppliedObjectString(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  AppliedObject
+		want string
+	}{
+		{
+			name: "created cluster scoped",
+			obj:  AppliedObject{Kind: "persistentvolume", Name: "pv", Created: true},
+			want: "persistentvolume/pv created",
+		},
+		{
+			name: "configured namespaced",
+			obj:  AppliedObject{Kind: "persistentvolumeclaim", Namespace: "default", Name: "pvc"},
+			want: "persistentvolumeclaim/pvc in namespace default configured",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.obj.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ServiceAddresses are the ip addresses a service is exposed with
+type ServiceAddresses struct {
+	Namespace string
+	Name      string
+	IPs       []string
+}
+
+// LoadBalancerAddresses returns the addresses of all load balancer services of the cluster the given kubeconfig points to,
+// these are the ingress addresses of the load balancer status, the requested load balancer ip and the external ips.
+func LoadBalancerAddresses(ctx context.Context, kubeconfig []byte) ([]ServiceAddresses, error) {
+	client, err := newClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadBalancerAddresses(ctx, client)
+}
+
+func loadBalancerAddresses(ctx context.Context, client kubernetes.Interface) ([]ServiceAddresses, error) {
+	services, err := client.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list services: %w", err)
+	}
+
+	var result []ServiceAddresses
+	for _, svc := range services.Items {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer && len(svc.Spec.ExternalIPs) == 0 {
+			continue
+		}
+
+		seen := map[string]bool{}
+		addresses := ServiceAddresses{Namespace: svc.Namespace, Name: svc.Name}
+
+		add := func(ip string) {
+			if ip == "" || seen[ip] {
+				return
+			}
+			seen[ip] = true
+			addresses.IPs = append(addresses.IPs, ip)
+		}
+
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			add(ingress.IP)
+		}
+		add(svc.Spec.LoadBalancerIP) //nolint:staticcheck
+		for _, ip := range svc.Spec.ExternalIPs {
+			add(ip)
+		}
+
+		if len(addresses.IPs) > 0 {
+			result = append(result, addresses)
+		}
+	}
+
+	return result, nil
+}
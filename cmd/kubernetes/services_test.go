@@ -0,0 +1,57 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadBalancerAddresses(t *testing.T) {
+	service := func(namespace, name string, typ corev1.ServiceType, loadBalancerIP string, externalIPs []string, ingress ...string) *corev1.Service {
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: corev1.ServiceSpec{
+				Type:           typ,
+				LoadBalancerIP: loadBalancerIP,
+				ExternalIPs:    externalIPs,
+			},
+		}
+		for _, ip := range ingress {
+			svc.Status.LoadBalancer.Ingress = append(svc.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{IP: ip})
+		}
+		return svc
+	}
+
+	client := fake.NewClientset(
+		// the requested ip equals the ingress address and is only returned once
+		service("ingress", "nginx", corev1.ServiceTypeLoadBalancer, "10.0.0.1", nil, "10.0.0.1"),
+		service("ingress", "dual-stack", corev1.ServiceTypeLoadBalancer, "", nil, "10.0.0.2", "2001:db8::1"),
+		// the ip was requested but not yet assigned
+		service("db", "pending", corev1.ServiceTypeLoadBalancer, "10.0.0.3", nil),
+		service("db", "external", corev1.ServiceTypeClusterIP, "", []string{"10.0.0.4"}),
+		// services without any address are left out
+		service("db", "no-address", corev1.ServiceTypeLoadBalancer, "", nil),
+		service("db", "cluster-ip", corev1.ServiceTypeClusterIP, "", nil),
+		service("db", "hostname", corev1.ServiceTypeLoadBalancer, "", nil, ""),
+	)
+
+	got, err := loadBalancerAddresses(context.Background(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []ServiceAddresses{
+		{Namespace: "db", Name: "external", IPs: []string{"10.0.0.4"}},
+		{Namespace: "db", Name: "pending", IPs: []string{"10.0.0.3"}},
+		{Namespace: "ingress", Name: "dual-stack", IPs: []string{"10.0.0.2", "2001:db8::1"}},
+		{Namespace: "ingress", Name: "nginx", IPs: []string{"10.0.0.1"}},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+}
@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// VolumeReference is a csi persistent volume together with the claim bound to it and the pods mounting the claim
+type VolumeReference struct {
+	VolumeHandle     string
+	PersistentVolume string
+	Namespace        string
+	Claim            string
+	Pods             []string
+}
+
+// VolumeReferences returns the references of all csi persistent volumes of the cluster the given kubeconfig points to, keyed by volume handle.
+func VolumeReferences(ctx context.Context, kubeconfig []byte) (map[string]VolumeReference, error) {
+	client, err := newClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return volumeReferences(ctx, client)
+}
+
+func volumeReferences(ctx context.Context, client kubernetes.Interface) (map[string]VolumeReference, error) {
+	pvs, err := client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list persistent volumes: %w", err)
+	}
+
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pods: %w", err)
+	}
+
+	podsByClaim := map[string][]string{}
+	for _, pod := range pods.Items {
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim == nil {
+				continue
+			}
+
+			key := pod.Namespace + "/" + v.PersistentVolumeClaim.ClaimName
+			podsByClaim[key] = append(podsByClaim[key], pod.Name)
+		}
+	}
+
+	result := map[string]VolumeReference{}
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI == nil {
+			continue
+		}
+
+		ref := VolumeReference{
+			VolumeHandle:     pv.Spec.CSI.VolumeHandle,
+			PersistentVolume: pv.Name,
+		}
+
+		// a claim ref of a released volume still points to the deleted claim
+		if claim := pv.Spec.ClaimRef; claim != nil && pv.Status.Phase != corev1.VolumeReleased {
+			ref.Namespace = claim.Namespace
+			ref.Claim = claim.Name
+			ref.Pods = podsByClaim[claim.Namespace+"/"+claim.Name]
+			sort.Strings(ref.Pods)
+		}
+
+		result[ref.VolumeHandle] = ref
+	}
+
+	return result, nil
+}
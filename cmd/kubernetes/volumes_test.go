@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestVolumeReferences(t *testing.T) {
+	csi := func(name, handle string, claim *corev1.ObjectReference, phase corev1.PersistentVolumePhase) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				ClaimRef: claim,
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: "csi.lightbitslabs.com", VolumeHandle: handle},
+				},
+			},
+			Status: corev1.PersistentVolumeStatus{Phase: phase},
+		}
+	}
+
+	pod := func(namespace, name, claim string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{Name: "config", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+					{Name: "data", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim}}},
+				},
+			},
+		}
+	}
+
+	client := fake.NewClientset(
+		csi("pv-1", "handle-1", &corev1.ObjectReference{Namespace: "db", Name: "data-0"}, corev1.VolumeBound),
+		csi("pv-2", "handle-2", &corev1.ObjectReference{Namespace: "db", Name: "data-1"}, corev1.VolumeBound),
+		csi("pv-3", "handle-3", &corev1.ObjectReference{Namespace: "db", Name: "deleted"}, corev1.VolumeReleased),
+		&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "local"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/data"}},
+			},
+		},
+		pod("db", "postgres-b", "data-0"),
+		pod("db", "postgres-a", "data-0"),
+		pod("other", "postgres-c", "data-1"),
+	)
+
+	got, err := volumeReferences(context.Background(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]VolumeReference{
+		"handle-1": {VolumeHandle: "handle-1", PersistentVolume: "pv-1", Namespace: "db", Claim: "data-0", Pods: []string{"postgres-a", "postgres-b"}},
+		"handle-2": {VolumeHandle: "handle-2", PersistentVolume: "pv-2", Namespace: "db", Claim: "data-1"},
+		"handle-3": {VolumeHandle: "handle-3", PersistentVolume: "pv-3"},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+}
@@ -52,7 +52,13 @@ func newLoginCmd(c *config.Config) *cobra.Command {
 }
 
 func (l *login) login() error {
-	mc := newApiClient(l.c.GetApiURL(), "")
+	return l.loginContext(viper.GetString("context"))
+}
+
+// loginContext runs the login flow and stores the token into the context with the given name.
+// If the name is empty, the current context is used or a context named default is created.
+func (l *login) loginContext(name string) error {
+	mc := config.NewApiClient(l.c.GetApiURL(), "")
 	assetResp, err := mc.Apiv1().Asset().List(context.Background(), connect.NewRequest(&apiv1.AssetServiceListRequest{}))
 	if err != nil {
 		return fmt.Errorf("unable to retrieve assets from api: %w", err)
@@ -73,16 +79,16 @@ func (l *login) login() error {
 	}
 
 	ctxName := ctxs.CurrentContext
-	if viper.IsSet("context") {
-		ctxName = viper.GetString("context")
+	if name != "" {
+		ctxName = name
 	}
 
 	ctx, ok := ctxs.Get(ctxName)
 	if !ok {
 		newCtx := l.c.MustDefaultContext()
 		newCtx.Name = "default"
-		if viper.IsSet("context") {
-			newCtx.Name = viper.GetString("context")
+		if name != "" {
+			newCtx.Name = name
 		}
 		newCtx.ApiURL = pointer.Pointer(l.c.GetApiURL())
 
@@ -144,7 +150,7 @@ func (l *login) login() error {
 	}
 
 	if viper.IsSet("admin-role") {
-		mc := newApiClient(l.c.GetApiURL(), token)
+		mc := config.NewApiClient(l.c.GetApiURL(), token)
 
 		tokenResp, err := mc.Apiv1().Token().Create(context.Background(), connect.NewRequest(&apiv1.TokenServiceCreateRequest{
 			Description: "admin access issues by metal cli",
@@ -158,10 +164,13 @@ func (l *login) login() error {
 		token = tokenResp.Msg.Secret
 	}
 
-	ctx.Token = token
+	err = l.c.StoreToken(ctxs, ctx, token)
+	if err != nil {
+		return err
+	}
 
 	if ctx.DefaultProject == "" {
-		mc := newApiClient(l.c.GetApiURL(), token)
+		mc := config.NewApiClient(l.c.GetApiURL(), token)
 
 		projects, err := mc.Apiv1().Project().List(context.Background(), connect.NewRequest(&apiv1.ProjectServiceListRequest{}))
 		if err != nil {
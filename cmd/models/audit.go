@@ -0,0 +1,141 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+)
+
+// AuditRecord is a flat representation of an audit trace as it is used for streaming and exporting traces
+type AuditRecord struct {
+	Uuid       string    `json:"uuid"`
+	Timestamp  time.Time `json:"timestamp"`
+	User       string    `json:"user"`
+	Tenant     string    `json:"tenant"`
+	Project    string    `json:"project,omitempty"`
+	Method     string    `json:"method"`
+	Phase      string    `json:"phase"`
+	ResultCode *int32    `json:"resultCode,omitempty"`
+	SourceIp   string    `json:"sourceIp,omitempty"`
+	Body       string    `json:"body,omitempty"`
+}
+
+func NewAuditRecord(trace *apiv1.AuditTrace) *AuditRecord {
+	return &AuditRecord{
+		Uuid:       trace.Uuid,
+		Timestamp:  trace.Timestamp.AsTime(),
+		User:       trace.User,
+		Tenant:     trace.Tenant,
+		Project:    pointer.SafeDeref(trace.Project),
+		Method:     trace.Method,
+		Phase:      trace.Phase.String(),
+		ResultCode: trace.ResultCode,
+		SourceIp:   trace.SourceIp,
+		Body:       pointer.SafeDeref(trace.Body),
+	}
+}
+
+// AuditRecordCSVHeader are the columns of an audit record in csv format
+var AuditRecordCSVHeader = []string{"uuid", "timestamp", "user", "tenant", "project", "method", "phase", "result_code", "source_ip", "body"}
+
+// CSV returns the audit record as csv row matching the AuditRecordCSVHeader
+func (r *AuditRecord) CSV() []string {
+	code := ""
+	if r.ResultCode != nil {
+		code = strconv.Itoa(int(*r.ResultCode))
+	}
+
+	return []string{r.Uuid, r.Timestamp.Format(time.RFC3339Nano), r.User, r.Tenant, r.Project, r.Method, r.Phase, code, r.SourceIp, r.Body}
+}
+
+// AuditCorrelation joins the request and response phase of an audit trace
+type AuditCorrelation struct {
+	Uuid     string         `json:"uuid"`
+	Request  *AuditRecord   `json:"request,omitempty"`
+	Response *AuditRecord   `json:"response,omitempty"`
+	Latency  *time.Duration `json:"latency,omitempty"`
+}
+
+// CorrelateAuditTraces joins the phases of the given traces by their uuid, the order of first occurrence is preserved
+func CorrelateAuditTraces(traces []*apiv1.AuditTrace) []*AuditCorrelation {
+	var (
+		result []*AuditCorrelation
+		byUuid = map[string]*AuditCorrelation{}
+	)
+
+	for _, trace := range traces {
+		c, ok := byUuid[trace.Uuid]
+		if !ok {
+			c = &AuditCorrelation{Uuid: trace.Uuid}
+			byUuid[trace.Uuid] = c
+			result = append(result, c)
+		}
+
+		switch trace.Phase {
+		case apiv1.AuditPhase_AUDIT_PHASE_REQUEST:
+			c.Request = NewAuditRecord(trace)
+		case apiv1.AuditPhase_AUDIT_PHASE_RESPONSE:
+			c.Response = NewAuditRecord(trace)
+		}
+	}
+
+	for _, c := range result {
+		if c.Request != nil && c.Response != nil {
+			c.Latency = pointer.Pointer(c.Response.Timestamp.Sub(c.Request.Timestamp))
+		}
+	}
+
+	return result
+}
+
+// Record returns the request phase of the correlation, or the response phase in case the request is missing
+func (c *AuditCorrelation) Record() *AuditRecord {
+	if c.Request != nil {
+		return c.Request
+	}
+	if c.Response != nil {
+		return c.Response
+	}
+	return &AuditRecord{Uuid: c.Uuid}
+}
+
+// AuditStats are audit traces aggregated by groups and optionally by time buckets
+type AuditStats struct {
+	GroupBy []string           `json:"group-by"`
+	Bucket  string             `json:"bucket,omitempty"`
+	Buckets []time.Time        `json:"buckets,omitempty"`
+	Groups  []*AuditStatsGroup `json:"groups"`
+	// Sparkline renders the buckets of a group as sparkline in table output
+	Sparkline bool `json:"-"`
+}
+
+type AuditStatsGroup struct {
+	Keys      []string `json:"keys"`
+	Count     int      `json:"count"`
+	Errors    int      `json:"errors"`
+	ErrorRate float64  `json:"error-rate"`
+	// Series contains the counts for every bucket of the stats, it is empty if no bucket was requested
+	Series []*AuditStatsBucket `json:"series,omitempty"`
+}
+
+type AuditStatsBucket struct {
+	Start     time.Time `json:"start"`
+	Count     int       `json:"count"`
+	Errors    int       `json:"errors"`
+	ErrorRate float64   `json:"error-rate"`
+}
+
+// AuditChange is a mutating request on a resource with the changes of the request body compared to the previous request
+type AuditChange struct {
+	Uuid       string    `json:"uuid"`
+	Timestamp  time.Time `json:"timestamp"`
+	User       string    `json:"user"`
+	Method     string    `json:"method"`
+	ResultCode *int32    `json:"resultCode,omitempty"`
+	// Failed is true if the request was not successful, in this case the changes did not take effect
+	Failed  bool                 `json:"failed"`
+	Changes []helpers.JSONChange `json:"changes"`
+}
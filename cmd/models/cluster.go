@@ -0,0 +1,30 @@
+package models
+
+// ClusterCost is the estimated monthly cost range of a cluster configuration
+type ClusterCost struct {
+	Cluster  string             `json:"cluster,omitempty"`
+	Name     string             `json:"name,omitempty"`
+	Items    []*ClusterCostItem `json:"items"`
+	Currency string             `json:"currency,omitempty"`
+	// MinMonthlyCost and MaxMonthlyCost sum up the items with a matching price
+	MinMonthlyCost float64 `json:"min-monthly-cost"`
+	MaxMonthlyCost float64 `json:"max-monthly-cost"`
+	// Complete is false if a price was not found for an item, the sums are lower bounds then
+	Complete bool `json:"complete"`
+	// Current is the cost of the current cluster configuration, it is only set when estimating an update
+	Current *ClusterCost `json:"current,omitempty"`
+	// MinMonthlyCostDelta and MaxMonthlyCostDelta are the differences to the current cost, they are only set when estimating an update
+	MinMonthlyCostDelta *float64 `json:"min-monthly-cost-delta,omitempty"`
+	MaxMonthlyCostDelta *float64 `json:"max-monthly-cost-delta,omitempty"`
+}
+
+// ClusterCostItem is the cost of a worker group or the cluster control plane
+type ClusterCostItem struct {
+	Name        string `json:"name"`
+	MachineType string `json:"machine-type,omitempty"`
+	Minsize     uint32 `json:"minsize"`
+	Maxsize     uint32 `json:"maxsize"`
+	// MinMonthlyCost and MaxMonthlyCost are nil if no matching price was found
+	MinMonthlyCost *float64 `json:"min-monthly-cost,omitempty"`
+	MaxMonthlyCost *float64 `json:"max-monthly-cost,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+const (
+	IPUsageStatusUsed    = "used"
+	IPUsageStatusUnused  = "unused"
+	IPUsageStatusCleanup = "cleanup"
+	IPUsageStatusUnknown = "unknown"
+)
+
+// IPUsage is a row of the ip usage report, an ip used by multiple services results in multiple rows
+type IPUsage struct {
+	IP        string `json:"ip"`
+	Uuid      string `json:"uuid"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type"`
+	Cluster   string `json:"cluster,omitempty"`
+	ClusterID string `json:"cluster-id,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Service   string `json:"service,omitempty"`
+	// Status is one of used, unused, cleanup for unused ephemeral ips and unknown if not all clusters could be inspected
+	Status string `json:"status"`
+}
+
+// IPRecord is a dns record of an ip address
+type IPRecord struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	TTL     int    `json:"ttl,omitempty"`
+	Uuid    string `json:"uuid,omitempty"`
+}
+
+const (
+	IPRecordDiffMissing = "missing"
+	IPRecordDiffStale   = "stale"
+	IPRecordDiffChanged = "changed"
+)
+
+// IPRecordDiff is a difference between the allocated ips and an existing zone or hosts file
+type IPRecordDiff struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Address is the address of the allocated ip, empty for stale records
+	Address string `json:"address,omitempty"`
+	// FileAddress is the address in the file, empty for missing records
+	FileAddress string `json:"file-address,omitempty"`
+	// Status is missing if the record is not in the file, stale if the address is not allocated anymore and changed if the addresses differ
+	Status string `json:"status"`
+}
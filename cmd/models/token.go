@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	TokenExpiryStatusOK       = "ok"
+	TokenExpiryStatusWarning  = "warning"
+	TokenExpiryStatusCritical = "critical"
+	TokenExpiryStatusExpired  = "expired"
+	TokenExpiryStatusUnknown  = "unknown"
+)
+
+// TokenExpiry is a row of the token expiry report
+type TokenExpiry struct {
+	Context     string     `json:"context"`
+	Source      string     `json:"source"`
+	ID          string     `json:"id,omitempty"`
+	Type        string     `json:"type,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Expires     *time.Time `json:"expires,omitempty"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	VolumeUsageStatusUsed         = "used"
+	VolumeUsageStatusClaimed      = "claimed"
+	VolumeUsageStatusUnclaimed    = "unclaimed"
+	VolumeUsageStatusUnreferenced = "unreferenced"
+)
+
+// VolumeUsage is a row of the volume usage report of a cluster
+type VolumeUsage struct {
+	Uuid             string   `json:"uuid"`
+	Name             string   `json:"name,omitempty"`
+	Size             uint64   `json:"size"`
+	VolumeHandle     string   `json:"volume-handle"`
+	PersistentVolume string   `json:"persistent-volume,omitempty"`
+	Namespace        string   `json:"namespace,omitempty"`
+	Claim            string   `json:"claim,omitempty"`
+	Pods             []string `json:"pods,omitempty"`
+	// Status is one of used if pods mount the volume, claimed if only a claim is bound, unclaimed if only a persistent volume exists
+	// and unreferenced if the cluster does not reference the volume at all
+	Status string `json:"status"`
+}
+
+const (
+	StorageOrphanKindVolume   = "volume"
+	StorageOrphanKindSnapshot = "snapshot"
+)
+
+// StorageOrphan is a volume or snapshot left behind by a deleted cluster or volume
+type StorageOrphan struct {
+	Kind      string `json:"kind"`
+	Uuid      string `json:"uuid"`
+	Name      string `json:"name,omitempty"`
+	Size      uint64 `json:"size"`
+	Partition string `json:"partition,omitempty"`
+	// Reference is the cluster of an orphaned volume or the source volume of an orphaned snapshot
+	Reference string `json:"reference"`
+}
+
+const (
+	SnapshotPruneActionKeep   = "keep"
+	SnapshotPruneActionDelete = "delete"
+)
+
+// SnapshotPrune is the planned action for a snapshot when pruning snapshots
+type SnapshotPrune struct {
+	Uuid             string     `json:"uuid"`
+	Name             string     `json:"name,omitempty"`
+	SourceVolumeUuid string     `json:"source-volume-uuid"`
+	SourceVolumeName string     `json:"source-volume-name,omitempty"`
+	CreatedAt        *time.Time `json:"created-at,omitempty"`
+	Size             uint64     `json:"size"`
+	Action           string     `json:"action"`
+	Reason           string     `json:"reason,omitempty"`
+}
+
+// StorageReportRow sums the volumes and snapshots of a project in a partition and storage class
+type StorageReportRow struct {
+	Project      string `json:"project"`
+	ProjectName  string `json:"project-name,omitempty"`
+	Partition    string `json:"partition"`
+	StorageClass string `json:"storage-class"`
+	Volumes      int    `json:"volumes"`
+	VolumeSize   uint64 `json:"volume-size"`
+	Snapshots    int    `json:"snapshots"`
+	SnapshotSize uint64 `json:"snapshot-size"`
+	// MonthlyCost is the estimated cost of a month, it is nil if no matching price was found
+	MonthlyCost *float64 `json:"monthly-cost,omitempty"`
+	Currency    string   `json:"currency,omitempty"`
+}
+
+// StorageReportCSVHeader are the columns of a storage report row in csv format
+var StorageReportCSVHeader = []string{"project", "project_name", "partition", "storage_class", "volumes", "volume_size_bytes", "snapshots", "snapshot_size_bytes", "monthly_cost", "currency"}
+
+// CSV returns the storage report row as csv row matching the StorageReportCSVHeader
+func (r *StorageReportRow) CSV() []string {
+	cost := ""
+	if r.MonthlyCost != nil {
+		cost = strconv.FormatFloat(*r.MonthlyCost, 'f', 2, 64)
+	}
+
+	return []string{
+		r.Project,
+		r.ProjectName,
+		r.Partition,
+		r.StorageClass,
+		strconv.Itoa(r.Volumes),
+		strconv.FormatUint(r.VolumeSize, 10),
+		strconv.Itoa(r.Snapshots),
+		strconv.FormatUint(r.SnapshotSize, 10),
+		cost,
+		r.Currency,
+	}
+}
@@ -8,9 +8,7 @@ import (
 	"time"
 
 	"connectrpc.com/connect"
-	"github.com/golang-jwt/jwt/v5"
 
-	client "github.com/metal-stack-cloud/api/go/client"
 	"github.com/metal-stack/metal-lib/pkg/genericcli"
 
 	adminv1cmds "github.com/metal-stack-cloud/cli/cmd/admin/v1"
@@ -21,6 +19,7 @@ import (
 	"github.com/charmbracelet/fang"
 	"github.com/metal-stack-cloud/cli/cmd/completion"
 	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
@@ -33,6 +32,7 @@ func Execute() {
 		Fs:         afero.NewOsFs(),
 		Out:        os.Stdout,
 		PromptOut:  os.Stdout,
+		Err:        os.Stderr,
 		In:         os.Stdin,
 		Completion: &completion.Completion{},
 	}
@@ -42,6 +42,11 @@ func Execute() {
 
 	err := fang.Execute(cmd.Context(), cmd, fang.WithErrorHandler(customErrHandler(cfg)))
 	if err != nil {
+		var exitErr *helpers.ExitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+
 		if viper.GetBool("debug") {
 			panic(err)
 		}
@@ -63,7 +68,12 @@ func newRootCmd(c *config.Config) *cobra.Command {
 			genericcli.Must(viper.BindPFlags(cmd.Flags()))
 			genericcli.Must(viper.BindPFlags(cmd.PersistentFlags()))
 
-			return initConfigWithViperCtx(c)
+			err := initConfigWithViperCtx(c)
+			if err != nil {
+				return err
+			}
+
+			return checkTokenExpiration(c, cmd)
 		},
 	}
 	rootCmd.PersistentFlags().StringP("config", "c", "", "alternative config file path, (default is ~/.metal-stack-cloud/config.yaml)")
@@ -119,7 +129,19 @@ func initConfigWithViperCtx(c *config.Config) error {
 		return nil
 	}
 
-	mc := newApiClient(c.GetApiURL(), c.GetToken())
+	if c.Context.TokenRef != "" && !viper.IsSet("api-token") {
+		ctxs, err := c.GetContexts()
+		if err != nil {
+			return err
+		}
+
+		c.Context.Token, err = c.LookupToken(ctxs, &c.Context)
+		if err != nil {
+			return err
+		}
+	}
+
+	mc := config.NewApiClient(c.GetApiURL(), c.GetToken())
 
 	c.Client = mc
 	c.Completion.Client = mc
@@ -129,17 +151,6 @@ func initConfigWithViperCtx(c *config.Config) error {
 	return nil
 }
 
-func newApiClient(apiURL, token string) client.Client {
-	dialConfig := client.DialConfig{
-		BaseURL:   apiURL,
-		Token:     token,
-		UserAgent: "metal-stack-cloud-cli",
-		Debug:     viper.GetBool("debug"),
-	}
-
-	return client.New(dialConfig)
-}
-
 func recursiveAutoGenDisable(cmd *cobra.Command) {
 	cmd.DisableAutoGenTag = true
 	for _, child := range cmd.Commands() {
@@ -161,13 +172,7 @@ func catchTokenExpiration(c *config.Config, originalErr error) error {
 
 	token := c.GetToken()
 	if token != "" {
-		type claims struct {
-			jwt.RegisteredClaims
-			Type string `json:"type"`
-		}
-
-		cs := &claims{}
-		_, _, tokenErr := new(jwt.Parser).ParseUnverified(string(token), cs)
+		cs, tokenErr := helpers.ParseTokenClaims(token)
 		if tokenErr == nil && cs.ExpiresAt != nil {
 			if cs.ExpiresAt.Before(time.Now()) {
 				switch cs.Type {
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	apitests "github.com/metal-stack-cloud/api/go/tests"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	snapshot1 = func() *apiv1.Snapshot {
+		return &apiv1.Snapshot{
+			Uuid:             "5c1e3bc4-71a0-4f36-a8ba-8ef0c7d1a411",
+			Name:             "snapshot1",
+			Project:          "a",
+			Partition:        "partition-a",
+			StorageClass:     "storageclass-a",
+			Size:             1024,
+			Usage:            42,
+			State:            "Ready",
+			SourceVolumeUuid: "bd0f32e2-eabf-4eb7-a0db-25fc993c3678",
+			SourceVolumeName: "volume1",
+		}
+	}
+	snapshot2 = func() *apiv1.Snapshot {
+		return &apiv1.Snapshot{
+			Uuid:             "e2a8f6d3-3c0b-4f4e-9d51-6f0b8a7c2b22",
+			Name:             "snapshot2",
+			Project:          "a",
+			Partition:        "partition-a",
+			StorageClass:     "storageclass-a",
+			Size:             1024,
+			Usage:            42,
+			State:            "Ready",
+			SourceVolumeUuid: "0372d029-1077-4e9b-b303-7d64ad5496fd",
+			SourceVolumeName: "volume2",
+		}
+	}
+)
+
+func Test_SnapshotCmd_MultiResult(t *testing.T) {
+	listMocks := func(m *mock.Mock) {
+		m.On("List", mock.Anything, connect.NewRequest(&apiv1.SnapshotServiceListRequest{
+			Project: "a",
+		})).Return(&connect.Response[apiv1.SnapshotServiceListResponse]{Msg: &apiv1.SnapshotServiceListResponse{
+			Snapshots: []*apiv1.Snapshot{snapshot2(), snapshot1()},
+		}}, nil)
+	}
+	volumeListMocks := func(m *mock.Mock) {
+		m.On("List", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+			Project: "a",
+		})).Return(&connect.Response[apiv1.VolumeServiceListResponse]{Msg: &apiv1.VolumeServiceListResponse{
+			Volumes: []*apiv1.Volume{volume2(), volume1()},
+		}}, nil)
+	}
+
+	tests := []*Test[[]*apiv1.Snapshot]{
+		{
+			Name: "list by selector",
+			Cmd: func(want []*apiv1.Snapshot) []string {
+				return []string{"storage", "snapshot", "list", "--project", "a", "--selector", "foo=bar"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Snapshot: listMocks,
+					Volume:   volumeListMocks,
+				},
+			},
+			Want: []*apiv1.Snapshot{
+				snapshot1(),
+			},
+			WantTable: pointer.Pointer(`
+ID                                    NAME       SIZE     USAGE  SOURCE VOLUME ID                      SOURCE VOLUME NAME  PROJECT  PARTITION
+5c1e3bc4-71a0-4f36-a8ba-8ef0c7d1a411  snapshot1  1.0 KiB  42 B   bd0f32e2-eabf-4eb7-a0db-25fc993c3678  volume1             a        partition-a
+		`),
+		},
+		{
+			Name: "delete by selector",
+			Cmd: func(want []*apiv1.Snapshot) []string {
+				args := []string{"storage", "snapshot", "delete", "--project", "a", "--selector", "bar=baz"}
+				AssertExhaustiveArgs(t, args, commonExcludedFileArgs()...)
+				return args
+			},
+			MockStdin: bytes.NewBufferString("y"),
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Snapshot: func(m *mock.Mock) {
+						listMocks(m)
+						m.On("Delete", mock.Anything, connect.NewRequest(&apiv1.SnapshotServiceDeleteRequest{
+							Uuid:    "e2a8f6d3-3c0b-4f4e-9d51-6f0b8a7c2b22",
+							Project: "a",
+						})).Return(&connect.Response[apiv1.SnapshotServiceDeleteResponse]{Msg: &apiv1.SnapshotServiceDeleteResponse{
+							Snapshot: snapshot2(),
+						}}, nil)
+					},
+					Volume: volumeListMocks,
+				},
+			},
+			Want: []*apiv1.Snapshot{
+				snapshot2(),
+			},
+			WantTable: pointer.Pointer(`
+ID                                    NAME       SIZE     USAGE  SOURCE VOLUME ID                      SOURCE VOLUME NAME  PROJECT  PARTITION
+e2a8f6d3-3c0b-4f4e-9d51-6f0b8a7c2b22  snapshot2  1.0 KiB  42 B   0372d029-1077-4e9b-b303-7d64ad5496fd  volume2             a        partition-a
+		`),
+		},
+		{
+			Name: "delete by selector without matches",
+			Cmd: func(want []*apiv1.Snapshot) []string {
+				return []string{"storage", "snapshot", "delete", "--project", "a", "--selector", "env=prod"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Snapshot: listMocks,
+					Volume:   volumeListMocks,
+				},
+			},
+			WantErr: errors.New(`no snapshots match the selector "env=prod"`),
+		},
+	}
+
+	for _, tt := range tests {
+		tt.TestCmd(t)
+	}
+}
@@ -1,7 +1,16 @@
 package tableprinters
 
 import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+	"github.com/metal-stack-cloud/cli/pkg/helpers"
 	"github.com/metal-stack/metal-lib/pkg/genericcli"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
 	"google.golang.org/grpc/codes"
@@ -19,7 +28,7 @@ func (t *TablePrinter) AuditTable(data []*apiv1.AuditTrace, wide bool) ([]string
 
 	for _, audit := range data {
 		id := audit.Uuid
-		time := audit.Timestamp.AsTime().Format("2006-01-02 15:04:05")
+		ts := audit.Timestamp.AsTime().Format("2006-01-02 15:04:05")
 		user := audit.User
 		phase := audit.Phase
 		method := audit.Method
@@ -33,9 +42,9 @@ func (t *TablePrinter) AuditTable(data []*apiv1.AuditTrace, wide bool) ([]string
 		}
 
 		if wide {
-			rows = append(rows, []string{time, id, user, project, method, phase.String(), sourceIp, code, body})
+			rows = append(rows, []string{ts, id, user, project, method, phase.String(), sourceIp, code, body})
 		} else {
-			rows = append(rows, []string{time, id, user, project, method, phase.String(), code})
+			rows = append(rows, []string{ts, id, user, project, method, phase.String(), code})
 		}
 	}
 
@@ -43,3 +52,169 @@ func (t *TablePrinter) AuditTable(data []*apiv1.AuditTrace, wide bool) ([]string
 
 	return header, rows, nil
 }
+
+func (t *TablePrinter) AuditCorrelationTable(data []*models.AuditCorrelation, wide bool) ([]string, [][]string, error) {
+	var (
+		rows [][]string
+	)
+
+	header := []string{"Time", "Request-Id", "User", "Project", "Method", "Code", "Latency"}
+	if wide {
+		header = []string{"Time", "Request-Id", "User", "Project", "Method", "Source-Ip", "Code", "Latency"}
+	}
+
+	for _, c := range data {
+		record := c.Record()
+
+		code := ""
+		if c.Response != nil && c.Response.ResultCode != nil {
+			code = codes.Code(uint32(*c.Response.ResultCode)).String()
+		}
+
+		var latency string
+		switch {
+		case c.Latency != nil:
+			latency = c.Latency.Round(time.Millisecond).String()
+		case c.Response == nil:
+			latency = color.RedString("no response")
+		default:
+			latency = color.YellowString("no request")
+		}
+
+		ts := record.Timestamp.Format("2006-01-02 15:04:05")
+
+		if wide {
+			rows = append(rows, []string{ts, c.Uuid, record.User, record.Project, record.Method, record.SourceIp, code, latency})
+		} else {
+			rows = append(rows, []string{ts, c.Uuid, record.User, record.Project, record.Method, code, latency})
+		}
+	}
+
+	return header, rows, nil
+}
+
+func (t *TablePrinter) AuditStatsTable(data *models.AuditStats, wide bool) ([]string, [][]string, error) {
+	var (
+		rows   [][]string
+		header []string
+	)
+
+	for _, g := range data.GroupBy {
+		header = append(header, toHeaderTitle(g))
+	}
+
+	rate := func(r float64) string {
+		s := fmt.Sprintf("%.1f%%", r*100)
+		if r > 0 {
+			return color.RedString(s)
+		}
+		return s
+	}
+
+	switch {
+	case data.Bucket == "":
+		header = append(header, "Count", "Errors", "Error-Rate")
+
+		for _, g := range data.Groups {
+			rows = append(rows, append(slices.Clone(g.Keys), fmt.Sprintf("%d", g.Count), fmt.Sprintf("%d", g.Errors), rate(g.ErrorRate)))
+		}
+	case data.Sparkline:
+		header = append(header, "Count", "Error-Rate", fmt.Sprintf("Requests (per %s)", data.Bucket), "Errors")
+
+		for _, g := range data.Groups {
+			var counts, errs []int
+			for _, b := range g.Series {
+				counts = append(counts, b.Count)
+				errs = append(errs, b.Errors)
+			}
+
+			rows = append(rows, append(slices.Clone(g.Keys), fmt.Sprintf("%d", g.Count), rate(g.ErrorRate), helpers.Sparkline(counts), color.RedString(helpers.Sparkline(errs))))
+		}
+	default:
+		header = append(header, "Bucket", "Count", "Errors", "Error-Rate")
+
+		for _, g := range data.Groups {
+			for _, b := range g.Series {
+				if b.Count == 0 && !wide {
+					continue
+				}
+
+				rows = append(rows, append(slices.Clone(g.Keys), b.Start.Format("2006-01-02 15:04"), fmt.Sprintf("%d", b.Count), fmt.Sprintf("%d", b.Errors), rate(b.ErrorRate)))
+			}
+		}
+	}
+
+	return header, rows, nil
+}
+
+func toHeaderTitle(s string) string {
+	parts := strings.Split(s, "-")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func (t *TablePrinter) AuditChangeTable(data []*models.AuditChange, wide bool) ([]string, [][]string, error) {
+	var (
+		rows [][]string
+	)
+
+	header := []string{"Time", "User", "Method", "Code", "Changes"}
+	if wide {
+		header = []string{"Time", "Request-Id", "User", "Method", "Code", "Changes"}
+	}
+
+	value := func(v any) string {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		if wide {
+			return string(raw)
+		}
+		return genericcli.TruncateEnd(string(raw), 60)
+	}
+
+	for _, c := range data {
+		code := ""
+		if c.ResultCode != nil {
+			code = codes.Code(uint32(*c.ResultCode)).String()
+			if c.Failed {
+				code = color.RedString(code)
+			}
+		}
+
+		var changes []string
+		for _, change := range c.Changes {
+			p := change.Path
+			if p == "" {
+				p = "."
+			}
+
+			switch change.Kind {
+			case helpers.JSONChangeAdded:
+				changes = append(changes, color.GreenString("+ %s: %s", p, value(change.New)))
+			case helpers.JSONChangeRemoved:
+				changes = append(changes, color.RedString("- %s: %s", p, value(change.Old)))
+			default:
+				changes = append(changes, color.YellowString("~ %s: %s → %s", p, value(change.Old), value(change.New)))
+			}
+		}
+		if len(changes) == 0 {
+			changes = append(changes, "no changes")
+		}
+
+		ts := c.Timestamp.Format("2006-01-02 15:04:05")
+
+		if wide {
+			rows = append(rows, []string{ts, c.Uuid, c.User, c.Method, code, strings.Join(changes, "\n")})
+		} else {
+			rows = append(rows, []string{ts, c.User, c.Method, code, strings.Join(changes, "\n")})
+		}
+	}
+
+	return header, rows, nil
+}
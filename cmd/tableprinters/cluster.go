@@ -2,12 +2,14 @@ package tableprinters
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	adminv1 "github.com/metal-stack-cloud/api/go/admin/v1"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
 )
 
@@ -214,3 +216,44 @@ func (t *TablePrinter) ClusterMachineTable(data []*adminv1.ClusterServiceGetResp
 
 	return t.ClusterTable(clusters, machines, wide)
 }
+
+func (t *TablePrinter) ClusterCostTable(data *models.ClusterCost, _ bool) ([]string, [][]string, error) {
+	var (
+		rows   [][]string
+		header = []string{"Worker Group", "Machine Type", "Min", "Max", "Monthly Cost"}
+
+		costRange = func(min, max float64, format string) string {
+			if min == max {
+				return fmt.Sprintf(format+" %s", min, data.Currency)
+			}
+			return fmt.Sprintf(format+" - "+format+" %s", min, max, data.Currency)
+		}
+		lowerBound = func(cost string, complete bool) string {
+			if complete {
+				return cost
+			}
+			return ">= " + cost
+		}
+	)
+
+	for _, item := range data.Items {
+		cost := "-"
+		if item.MinMonthlyCost != nil && item.MaxMonthlyCost != nil {
+			cost = costRange(*item.MinMonthlyCost, *item.MaxMonthlyCost, "%.2f")
+		}
+
+		rows = append(rows, []string{item.Name, item.MachineType, strconv.FormatUint(uint64(item.Minsize), 10), strconv.FormatUint(uint64(item.Maxsize), 10), cost})
+	}
+
+	rows = append(rows, []string{"Total", "", "", "", lowerBound(costRange(data.MinMonthlyCost, data.MaxMonthlyCost, "%.2f"), data.Complete)})
+
+	if data.Current != nil {
+		rows = append(rows, []string{"Current", "", "", "", lowerBound(costRange(data.Current.MinMonthlyCost, data.Current.MaxMonthlyCost, "%.2f"), data.Current.Complete)})
+	}
+
+	if data.MinMonthlyCostDelta != nil && data.MaxMonthlyCostDelta != nil {
+		rows = append(rows, []string{"Delta", "", "", "", lowerBound(costRange(*data.MinMonthlyCostDelta, *data.MaxMonthlyCostDelta, "%+.2f"), data.Complete)})
+	}
+
+	return header, rows, nil
+}
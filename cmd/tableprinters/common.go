@@ -6,6 +6,7 @@ import (
 	adminv1 "github.com/metal-stack-cloud/api/go/admin/v1"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
 )
@@ -32,10 +33,20 @@ func (t *TablePrinter) ToHeaderAndRows(data any, wide bool) ([]string, [][]strin
 		return t.AuditTable(pointer.WrapInSlice(d), wide)
 	case []*apiv1.AuditTrace:
 		return t.AuditTable(d, wide)
+	case []*models.AuditCorrelation:
+		return t.AuditCorrelationTable(d, wide)
+	case *models.AuditStats:
+		return t.AuditStatsTable(d, wide)
+	case []*models.AuditChange:
+		return t.AuditChangeTable(d, wide)
 
 	case *config.Contexts:
 		return t.ContextTable(d, wide)
 
+	case []*models.IPUsage:
+		return t.IPUsageTable(d, wide)
+	case []*models.IPRecordDiff:
+		return t.IPRecordDiffTable(d, wide)
 	case *apiv1.IP:
 		return t.IPTable(pointer.WrapInSlice(d), wide)
 	case []*apiv1.IP:
@@ -60,6 +71,8 @@ func (t *TablePrinter) ToHeaderAndRows(data any, wide bool) ([]string, [][]strin
 		return t.ClusterStatusConditionsTable(pointer.WrapInSlice(d), wide)
 	case []*apiv1.ClusterStatusCondition:
 		return t.ClusterStatusConditionsTable(d, wide)
+	case *models.ClusterCost:
+		return t.ClusterCostTable(d, wide)
 
 	case *apiv1.Price:
 		return t.PaymentPricesTable(pointer.WrapInSlice(d), wide)
@@ -83,6 +96,14 @@ func (t *TablePrinter) ToHeaderAndRows(data any, wide bool) ([]string, [][]strin
 		return t.VolumeTable(pointer.WrapInSlice(d), wide)
 	case []*apiv1.Volume:
 		return t.VolumeTable(d, wide)
+	case []*models.VolumeUsage:
+		return t.VolumeUsageTable(d, wide)
+	case []*models.StorageOrphan:
+		return t.StorageOrphanTable(d, wide)
+	case []*models.StorageReportRow:
+		return t.StorageReportTable(d, wide)
+	case []*models.SnapshotPrune:
+		return t.SnapshotPruneTable(d, wide)
 
 	case *apiv1.Snapshot:
 		return t.SnapshotTable(pointer.WrapInSlice(d), wide)
@@ -98,6 +119,8 @@ func (t *TablePrinter) ToHeaderAndRows(data any, wide bool) ([]string, [][]strin
 		return t.TokenTable(pointer.WrapInSlice(d), wide)
 	case []*apiv1.Token:
 		return t.TokenTable(d, wide)
+	case []*models.TokenExpiry:
+		return t.TokenExpiryTable(d, wide)
 
 	case *apiv1.Tenant:
 		return t.TenantTable(pointer.WrapInSlice(d), wide)
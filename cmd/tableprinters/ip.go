@@ -3,7 +3,9 @@ package tableprinters
 import (
 	"strings"
 
+	"github.com/fatih/color"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 	"github.com/metal-stack/metal-lib/pkg/tag"
 )
 
@@ -52,3 +54,60 @@ func (t *TablePrinter) IPTable(data []*apiv1.IP, wide bool) ([]string, [][]strin
 
 	return header, rows, nil
 }
+
+func (t *TablePrinter) IPUsageTable(data []*models.IPUsage, wide bool) ([]string, [][]string, error) {
+	var (
+		rows   [][]string
+		header = []string{"IP", "Name", "Type", "Cluster", "Service", "Status"}
+	)
+
+	if wide {
+		header = []string{"IP", "ID", "Name", "Type", "Cluster", "Cluster ID", "Service", "Status"}
+	}
+
+	for _, u := range data {
+		service := ""
+		if u.Service != "" {
+			service = u.Namespace + "/" + u.Service
+		}
+
+		status := u.Status
+		switch u.Status {
+		case models.IPUsageStatusUsed:
+			status = color.GreenString(status)
+		case models.IPUsageStatusCleanup:
+			status = color.RedString(status)
+		case models.IPUsageStatusUnknown:
+			status = color.YellowString(status)
+		}
+
+		if wide {
+			rows = append(rows, []string{u.IP, u.Uuid, u.Name, u.Type, u.Cluster, u.ClusterID, service, status})
+		} else {
+			rows = append(rows, []string{u.IP, u.Name, u.Type, u.Cluster, service, status})
+		}
+	}
+
+	return header, rows, nil
+}
+
+func (t *TablePrinter) IPRecordDiffTable(data []*models.IPRecordDiff, _ bool) ([]string, [][]string, error) {
+	var (
+		rows   [][]string
+		header = []string{"Name", "Type", "Allocated", "File", "Status"}
+	)
+
+	for _, d := range data {
+		status := d.Status
+		switch d.Status {
+		case models.IPRecordDiffMissing:
+			status = color.YellowString(status)
+		case models.IPRecordDiffStale, models.IPRecordDiffChanged:
+			status = color.RedString(status)
+		}
+
+		rows = append(rows, []string{d.Name, d.Type, d.Address, d.FileAddress, status})
+	}
+
+	return header, rows, nil
+}
@@ -2,9 +2,12 @@ package tableprinters
 
 import (
 	"sort"
+	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 )
 
 func (t *TablePrinter) SnapshotTable(data []*apiv1.Snapshot, _ bool) ([]string, [][]string, error) {
@@ -30,3 +33,40 @@ func (t *TablePrinter) SnapshotTable(data []*apiv1.Snapshot, _ bool) ([]string,
 
 	return header, rows, nil
 }
+
+func (t *TablePrinter) SnapshotPruneTable(data []*models.SnapshotPrune, wide bool) ([]string, [][]string, error) {
+	var (
+		rows   [][]string
+		header = []string{"Volume", "ID", "Name", "Age", "Size", "Action", "Reason"}
+	)
+
+	if wide {
+		header = []string{"Volume", "Volume ID", "ID", "Name", "Created", "Size", "Action", "Reason"}
+	}
+
+	for _, p := range data {
+		volume := p.SourceVolumeName
+		if volume == "" {
+			volume = p.SourceVolumeUuid
+		}
+
+		var age, created string
+		if p.CreatedAt != nil {
+			age = humanize.Time(*p.CreatedAt)
+			created = p.CreatedAt.Format(time.DateTime)
+		}
+
+		action := p.Action
+		if p.Action == models.SnapshotPruneActionDelete {
+			action = color.RedString(action)
+		}
+
+		if wide {
+			rows = append(rows, []string{volume, p.SourceVolumeUuid, p.Uuid, p.Name, created, humanize.IBytes(p.Size), action, p.Reason})
+		} else {
+			rows = append(rows, []string{volume, p.Uuid, p.Name, age, humanize.IBytes(p.Size), action, p.Reason})
+		}
+	}
+
+	return header, rows, nil
+}
@@ -0,0 +1,88 @@
+package tableprinters
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metal-stack-cloud/cli/cmd/models"
+)
+
+func (t *TablePrinter) StorageOrphanTable(data []*models.StorageOrphan, _ bool) ([]string, [][]string, error) {
+	var (
+		rows   [][]string
+		header = []string{"Kind", "ID", "Name", "Size", "Partition", "Missing Reference"}
+	)
+
+	for _, o := range data {
+		rows = append(rows, []string{o.Kind, o.Uuid, o.Name, humanize.IBytes(o.Size), o.Partition, o.Reference})
+	}
+
+	return header, rows, nil
+}
+
+func (t *TablePrinter) StorageReportTable(data []*models.StorageReportRow, wide bool) ([]string, [][]string, error) {
+	var (
+		rows   [][]string
+		header = []string{"Project", "Partition", "Storage Class", "Volumes", "Volume Size", "Snapshots", "Snapshot Size", "Monthly Cost"}
+
+		volumes, snapshots       int
+		volumeSize, snapshotSize uint64
+		totalCost                float64
+		currencies               = map[string]bool{}
+		costComplete             = true
+	)
+
+	if wide {
+		header = []string{"Project", "Project ID", "Partition", "Storage Class", "Volumes", "Volume Size", "Snapshots", "Snapshot Size", "Monthly Cost"}
+	}
+
+	for _, r := range data {
+		project := r.ProjectName
+		if project == "" {
+			project = r.Project
+		}
+
+		cost := "-"
+		if r.MonthlyCost != nil {
+			cost = fmt.Sprintf("%.2f %s", *r.MonthlyCost, r.Currency)
+			totalCost += *r.MonthlyCost
+			currencies[r.Currency] = true
+		} else {
+			costComplete = false
+		}
+
+		volumes += r.Volumes
+		snapshots += r.Snapshots
+		volumeSize += r.VolumeSize
+		snapshotSize += r.SnapshotSize
+
+		row := []string{project, r.Partition, r.StorageClass, strconv.Itoa(r.Volumes), humanize.IBytes(r.VolumeSize), strconv.Itoa(r.Snapshots), humanize.IBytes(r.SnapshotSize), cost}
+		if wide {
+			row = []string{project, r.Project, r.Partition, r.StorageClass, strconv.Itoa(r.Volumes), humanize.IBytes(r.VolumeSize), strconv.Itoa(r.Snapshots), humanize.IBytes(r.SnapshotSize), cost}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(data) > 1 {
+		cost := "-"
+		if len(currencies) == 1 {
+			for currency := range currencies {
+				cost = fmt.Sprintf("%.2f %s", totalCost, currency)
+			}
+			if !costComplete {
+				cost = ">= " + cost
+			}
+		}
+
+		total := []string{"Total", "", "", strconv.Itoa(volumes), humanize.IBytes(volumeSize), strconv.Itoa(snapshots), humanize.IBytes(snapshotSize), cost}
+		if wide {
+			total = append([]string{"Total", ""}, total[1:]...)
+		}
+
+		rows = append(rows, total)
+	}
+
+	return header, rows, nil
+}
@@ -5,7 +5,9 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/fatih/color"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 	"github.com/metal-stack-cloud/cli/pkg/helpers"
 )
 
@@ -41,3 +43,59 @@ func (t *TablePrinter) TokenTable(data []*apiv1.Token, _ bool) ([]string, [][]st
 
 	return header, rows, nil
 }
+
+func (t *TablePrinter) TokenExpiryTable(data []*models.TokenExpiry, wide bool) ([]string, [][]string, error) {
+	var (
+		rows [][]string
+	)
+	header := []string{"Context", "Source", "Type", "ID", "Description", "Expires", "Status"}
+
+	if wide {
+		header = append(header, "Error")
+	}
+
+	for _, r := range data {
+		expires := ""
+		if r.Expires != nil {
+			expires = fmt.Sprintf("%s (in %s)", r.Expires.Format(time.DateTime+" MST"), helpers.HumanizeDuration(time.Until(*r.Expires)))
+			if r.Expires.Before(time.Now()) {
+				expires = fmt.Sprintf("%s (%s ago)", r.Expires.Format(time.DateTime+" MST"), helpers.HumanizeDuration(time.Since(*r.Expires)))
+			}
+		}
+
+		status := r.Status
+		switch r.Status {
+		case models.TokenExpiryStatusOK:
+			status = color.GreenString(status)
+		case models.TokenExpiryStatusWarning:
+			status = color.YellowString(status)
+		case models.TokenExpiryStatusCritical, models.TokenExpiryStatusExpired, models.TokenExpiryStatusUnknown:
+			status = color.RedString(status)
+		}
+
+		description := r.Description
+		if r.Error != "" && !wide {
+			description = r.Error
+		}
+
+		row := []string{
+			r.Context,
+			r.Source,
+			r.Type,
+			r.ID,
+			description,
+			expires,
+			status,
+		}
+
+		if wide {
+			row = append(row, r.Error)
+		}
+
+		rows = append(rows, row)
+	}
+
+	t.t.DisableAutoWrap(false)
+
+	return header, rows, nil
+}
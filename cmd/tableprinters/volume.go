@@ -7,7 +7,9 @@ import (
 	"strings"
 
 	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 )
 
 func (t *TablePrinter) VolumeTable(data []*apiv1.Volume, wide bool) ([]string, [][]string, error) {
@@ -73,3 +75,41 @@ func volumeLabels(vol *apiv1.Volume) []string {
 	slices.Sort(labels)
 	return labels
 }
+
+func (t *TablePrinter) VolumeUsageTable(data []*models.VolumeUsage, wide bool) ([]string, [][]string, error) {
+	var (
+		rows   [][]string
+		header = []string{"ID", "Name", "Size", "PersistentVolume", "Claim", "Pods", "Status"}
+	)
+
+	if wide {
+		header = []string{"ID", "Name", "Size", "VolumeHandle", "PersistentVolume", "Claim", "Pods", "Status"}
+	}
+
+	for _, u := range data {
+		claim := ""
+		if u.Claim != "" {
+			claim = u.Namespace + "/" + u.Claim
+		}
+
+		pods := strings.Join(u.Pods, "\n")
+
+		status := u.Status
+		switch u.Status {
+		case models.VolumeUsageStatusUsed:
+			status = color.GreenString(status)
+		case models.VolumeUsageStatusUnclaimed, models.VolumeUsageStatusUnreferenced:
+			status = color.YellowString(status)
+		}
+
+		size := humanize.IBytes(u.Size)
+
+		if wide {
+			rows = append(rows, []string{u.Uuid, u.Name, size, u.VolumeHandle, u.PersistentVolume, claim, pods, status})
+		} else {
+			rows = append(rows, []string{u.Uuid, u.Name, size, u.PersistentVolume, claim, pods, status})
+		}
+	}
+
+	return header, rows, nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
+	apitests "github.com/metal-stack-cloud/api/go/tests"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_TokenCmd_Rotate(t *testing.T) {
+	const (
+		oldID      = "a3d5fb9e-5dbb-4b2b-9c66-2d1b3fd2d0c1"
+		newID      = "7e0bfa44-2f56-4c4b-8f24-0e5e4a2f4b9a"
+		secret     = "new-secret"
+		statePath  = "/token-rotations/" + oldID + ".yaml"
+		secretPath = "/secret"
+	)
+
+	var (
+		args = []string{"token", "rotate", oldID, "--config", "/config.yaml", "--secret-file", secretPath}
+
+		oldToken = &apiv1.Token{
+			Uuid:        oldID,
+			Description: "ci pipeline",
+			IssuedAt:    timestamppb.New(testTime.Add(-time.Hour)),
+			Expires:     timestamppb.New(testTime.Add(7 * time.Hour)),
+		}
+
+		createMocks = func(m *mock.Mock) {
+			m.On("Get", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(&apiv1.TokenServiceGetRequest{
+				Uuid: oldID,
+			}), testcommon.IgnoreUnexported())).Return(connect.NewResponse(&apiv1.TokenServiceGetResponse{
+				Token: oldToken,
+			}), nil)
+			m.On("Create", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(&apiv1.TokenServiceCreateRequest{
+				Description: "ci pipeline",
+				Expires:     durationpb.New(8 * time.Hour),
+			}), testcommon.IgnoreUnexported())).Return(connect.NewResponse(&apiv1.TokenServiceCreateResponse{
+				Token:  &apiv1.Token{Uuid: newID},
+				Secret: secret,
+			}), nil)
+		}
+		revokeMock = func(err error) func(m *mock.Mock) {
+			return func(m *mock.Mock) {
+				resp := connect.NewResponse(&apiv1.TokenServiceRevokeResponse{})
+				if err != nil {
+					resp = nil
+				}
+				m.On("Revoke", mock.Anything, testcommon.MatchByCmpDiff(t, connect.NewRequest(&apiv1.TokenServiceRevokeRequest{
+					Uuid: oldID,
+				}), testcommon.IgnoreUnexported())).Return(resp, err)
+			}
+		}
+		writeState = func(content string) func(fs afero.Fs) {
+			return func(fs afero.Fs) {
+				require.NoError(t, afero.WriteFile(fs, statePath, []byte(content), 0600))
+			}
+		}
+	)
+
+	tests := []struct {
+		name       string
+		fsMocks    func(fs afero.Fs)
+		mocks      []func(m *mock.Mock)
+		wantErr    string
+		wantSecret bool
+		wantState  string
+	}{
+		{
+			name:       "create new token, write secret and revoke old token",
+			mocks:      []func(m *mock.Mock){createMocks, revokeMock(nil)},
+			wantSecret: true,
+		},
+		{
+			name:    "resume from state file",
+			fsMocks: writeState("old-uuid: " + oldID + "\nnew-uuid: " + newID + "\nsecret-written: true\n"),
+			mocks:   []func(m *mock.Mock){revokeMock(nil)},
+		},
+		{
+			name:    "old token was already revoked",
+			fsMocks: writeState("old-uuid: " + oldID + "\nnew-uuid: " + newID + "\nsecret-written: true\n"),
+			mocks:   []func(m *mock.Mock){revokeMock(connect.NewError(connect.CodeNotFound, errors.New("token not found")))},
+		},
+		{
+			name:       "failed revoke keeps the state without the secret",
+			mocks:      []func(m *mock.Mock){createMocks, revokeMock(connect.NewError(connect.CodeUnavailable, errors.New("unavailable")))},
+			wantErr:    "failed to revoke token: unavailable: unavailable",
+			wantSecret: true,
+			wantState:  "new-uuid: " + newID + "\nold-uuid: " + oldID + "\nsecret-written: true\n",
+		},
+		{
+			name:      "state file without written secret cannot be resumed",
+			fsMocks:   writeState("old-uuid: " + oldID + "\nnew-uuid: " + newID + "\nsecret-written: false\n"),
+			wantErr:   "the secret of new token " + newID + " was never written and cannot be recovered, please revoke the token and remove " + statePath + " to restart the rotation",
+			wantState: "old-uuid: " + oldID + "\nnew-uuid: " + newID + "\nsecret-written: false\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Test[*apiv1.Token]{
+				ClientMocks: &apitests.ClientMockFns{
+					Apiv1Mocks: &apitests.Apiv1MockFns{
+						Token: func(m *mock.Mock) {
+							for _, fn := range tt.mocks {
+								fn(m)
+							}
+						},
+					},
+				},
+			}
+
+			if tt.fsMocks != nil {
+				c.FsMocks = func(fs afero.Fs, _ *apiv1.Token) { tt.fsMocks(fs) }
+			}
+
+			_, out, conf := c.newMockConfig(t)
+
+			cmd := newRootCmd(conf)
+			os.Args = append([]string{config.BinaryName}, args...)
+
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Contains(t, out.String(), "rotated token "+oldID+", new token is "+newID)
+			}
+
+			written, err := afero.ReadFile(conf.Fs, secretPath)
+			if tt.wantSecret {
+				require.NoError(t, err)
+				require.Equal(t, secret, string(written))
+			} else {
+				require.ErrorIs(t, err, os.ErrNotExist)
+			}
+
+			state, err := afero.ReadFile(conf.Fs, statePath)
+			if tt.wantState != "" {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantState, string(state))
+				require.NotContains(t, string(state), secret)
+			} else {
+				require.ErrorIs(t, err, os.ErrNotExist)
+			}
+		})
+	}
+}
@@ -1,13 +1,22 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"regexp"
 	"testing"
 
 	"connectrpc.com/connect"
 	apiv1 "github.com/metal-stack-cloud/api/go/api/v1"
 	apitests "github.com/metal-stack-cloud/api/go/tests"
+	"github.com/metal-stack-cloud/cli/cmd/config"
+	"github.com/metal-stack-cloud/cli/cmd/models"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -48,6 +57,14 @@ var (
 		}
 	}
 	volume2 = func() *apiv1.Volume {
+		return volume2WithLabels([]*apiv1.VolumeLabel{
+			{
+				Key:   "bar",
+				Value: "baz",
+			},
+		})
+	}
+	volume2WithLabels = func(labels []*apiv1.VolumeLabel) *apiv1.Volume {
 		return &apiv1.Volume{
 			Uuid:               "0372d029-1077-4e9b-b303-7d64ad5496fd",
 			Name:               "volume2",
@@ -72,12 +89,7 @@ var (
 			Statistics:         nil,
 			ClusterName:        "cluster-a2",
 			ClusterId:          "",
-			Labels: []*apiv1.VolumeLabel{
-				{
-					Key:   "bar",
-					Value: "baz",
-				},
-			},
+			Labels:             labels,
 		}
 	}
 )
@@ -238,6 +250,139 @@ bd0f32e2-eabf-4eb7-a0db-25fc993c3678 a
 | bd0f32e2-eabf-4eb7-a0db-25fc993c3678 | volume1 | 1.0 KiB | 42 B  | 0        | cluster-a1   | storageclass-a | a       | partition-a |
 		`),
 		},
+		{
+			Name: "list by selector",
+			Cmd: func(want []*apiv1.Volume) []string {
+				return []string{"storage", "volume", "list", "--project", "a", "--selector", "foo=bar"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Volume: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+							Project: "a",
+						})).Return(&connect.Response[apiv1.VolumeServiceListResponse]{Msg: &apiv1.VolumeServiceListResponse{
+							Volumes: []*apiv1.Volume{volume2(), volume1()},
+						}}, nil)
+					},
+				},
+			},
+			Want: []*apiv1.Volume{
+				volume1(),
+			},
+			WantTable: pointer.Pointer(`
+ID                                    NAME     SIZE     USAGE  REPLICAS  CLUSTER NAME  STORAGE CLASS   PROJECT  PARTITION
+bd0f32e2-eabf-4eb7-a0db-25fc993c3678  volume1  1.0 KiB  42 B   0         cluster-a1    storageclass-a  a        partition-a
+		`),
+		},
+		{
+			Name: "delete by selector",
+			Cmd: func(want []*apiv1.Volume) []string {
+				args := []string{"storage", "volume", "delete", "--project", "a", "--selector", "foo"}
+				AssertExhaustiveArgs(t, args, commonExcludedFileArgs()...)
+				return args
+			},
+			MockStdin: bytes.NewBufferString("y"),
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Volume: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+							Project: "a",
+						})).Return(&connect.Response[apiv1.VolumeServiceListResponse]{Msg: &apiv1.VolumeServiceListResponse{
+							Volumes: []*apiv1.Volume{volume2(), volume1()},
+						}}, nil)
+						m.On("Delete", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceDeleteRequest{
+							Uuid:    "bd0f32e2-eabf-4eb7-a0db-25fc993c3678",
+							Project: "a",
+						})).Return(&connect.Response[apiv1.VolumeServiceDeleteResponse]{Msg: &apiv1.VolumeServiceDeleteResponse{
+							Volume: volume1(),
+						}}, nil)
+					},
+				},
+			},
+			Want: []*apiv1.Volume{
+				volume1(),
+			},
+			WantTable: pointer.Pointer(`
+ID                                    NAME     SIZE     USAGE  REPLICAS  CLUSTER NAME  STORAGE CLASS   PROJECT  PARTITION
+bd0f32e2-eabf-4eb7-a0db-25fc993c3678  volume1  1.0 KiB  42 B   0         cluster-a1    storageclass-a  a        partition-a
+		`),
+		},
+		{
+			Name: "delete by selector without matches",
+			Cmd: func(want []*apiv1.Volume) []string {
+				return []string{"storage", "volume", "delete", "--project", "a", "--selector", "env=prod"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Volume: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+							Project: "a",
+						})).Return(&connect.Response[apiv1.VolumeServiceListResponse]{Msg: &apiv1.VolumeServiceListResponse{
+							Volumes: []*apiv1.Volume{volume2(), volume1()},
+						}}, nil)
+					},
+				},
+			},
+			WantErr: errors.New(`no volumes match the selector "env=prod"`),
+		},
+		{
+			Name: "update by selector",
+			Cmd: func(want []*apiv1.Volume) []string {
+				args := []string{"storage", "volume", "update", "--project", "a", "--selector", "!temporary", "--add-label", "foo=bar", "--remove-label", "bar"}
+				AssertExhaustiveArgs(t, args, commonExcludedFileArgs()...)
+				return args
+			},
+			MockStdin: bytes.NewBufferString("y"),
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Volume: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+							Project: "a",
+						})).Return(&connect.Response[apiv1.VolumeServiceListResponse]{Msg: &apiv1.VolumeServiceListResponse{
+							Volumes: []*apiv1.Volume{volume2(), volume1()},
+						}}, nil)
+						// volume1 already has the requested labels and is not updated
+						m.On("Update", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceUpdateRequest{
+							Uuid:    "0372d029-1077-4e9b-b303-7d64ad5496fd",
+							Project: "a",
+							Labels: &apiv1.UpdateVolumeLabels{
+								Update: []*apiv1.VolumeLabel{
+									{Key: "foo", Value: "bar"},
+								},
+								Remove: []string{"bar"},
+							},
+						})).Return(&connect.Response[apiv1.VolumeServiceUpdateResponse]{Msg: &apiv1.VolumeServiceUpdateResponse{
+							Volume: volume2WithLabels([]*apiv1.VolumeLabel{{Key: "foo", Value: "bar"}}),
+						}}, nil)
+					},
+				},
+			},
+			Want: []*apiv1.Volume{
+				volume2WithLabels([]*apiv1.VolumeLabel{{Key: "foo", Value: "bar"}}),
+			},
+			WantWideTable: pointer.Pointer(`
+ID                                    NAME     SIZE     USAGE  REPLICAS  CLUSTER NAME  STORAGE CLASS   PROJECT  PARTITION    NODES  LABELS
+0372d029-1077-4e9b-b303-7d64ad5496fd  volume2  1.0 KiB  42 B   0         cluster-a2    storageclass-a  a        partition-a         foo=bar
+		`),
+		},
+		{
+			Name: "update by selector without changes",
+			Cmd: func(want []*apiv1.Volume) []string {
+				return []string{"storage", "volume", "update", "--project", "a", "--selector", "foo=bar", "--add-label", "foo=bar"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Volume: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+							Project: "a",
+						})).Return(&connect.Response[apiv1.VolumeServiceListResponse]{Msg: &apiv1.VolumeServiceListResponse{
+							Volumes: []*apiv1.Volume{volume2(), volume1()},
+						}}, nil)
+					},
+				},
+			},
+			WantErr: errors.New("the labels of the 1 matching volumes already are as requested"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -429,3 +574,199 @@ bd0f32e2-eabf-4eb7-a0db-25fc993c3678  volume1  1.0 KiB  42 B   0         cluster
 		tt.TestCmd(t)
 	}
 }
+
+func Test_VolumeCmd_EncryptionSecret(t *testing.T) {
+	const (
+		escrowEnv  = "METAL_STACK_CLOUD_ESCROW_PASSPHRASE"
+		escrowFile = "/escrow/passphrase.enc"
+	)
+
+	passphraseOf := regexp.MustCompile(`(?m)host-encryption-passphrase: (.*)$`)
+
+	run := func(t *testing.T, fs afero.Fs, stdin string, args ...string) (string, error) {
+		c := &Test[*apiv1.Volume]{}
+		if stdin != "" {
+			c.MockStdin = bytes.NewBufferString(stdin)
+		}
+
+		_, out, conf := c.newMockConfig(t)
+		conf.Fs = fs
+
+		cmd := newRootCmd(conf)
+		os.Args = append([]string{config.BinaryName, "storage", "volume", "encryptionsecret"}, args...)
+
+		err := cmd.Execute()
+		if err != nil {
+			return "", err
+		}
+
+		match := passphraseOf.FindStringSubmatch(out.String())
+		require.Len(t, match, 2, "no passphrase found in output:\n%s", out.String())
+
+		return match[1], nil
+	}
+
+	passphraseFile := func(t *testing.T) afero.Fs {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/passphrase", []byte("file secret\nsecond line\n"), 0600))
+		return fs
+	}
+
+	t.Run("passphrase from file", func(t *testing.T) {
+		got, err := run(t, passphraseFile(t), "", "--passphrase-file", "/passphrase")
+		require.NoError(t, err)
+		require.Equal(t, "file secret", got)
+	})
+
+	t.Run("passphrase from stdin", func(t *testing.T) {
+		got, err := run(t, afero.NewMemMapFs(), "stdin secret\r\n", "--passphrase-file", "-")
+		require.NoError(t, err)
+		require.Equal(t, "stdin secret", got)
+	})
+
+	t.Run("empty passphrase", func(t *testing.T) {
+		_, err := run(t, afero.NewMemMapFs(), "\n", "--passphrase-file", "-")
+		require.EqualError(t, err, "passphrase must not be empty")
+	})
+
+	t.Run("no passphrase without terminal", func(t *testing.T) {
+		_, err := run(t, afero.NewMemMapFs(), "")
+		require.EqualError(t, err, "no passphrase given, use --passphrase-file, --generate or --from-escrow when not running in a terminal")
+	})
+
+	t.Run("generated passphrase", func(t *testing.T) {
+		first, err := run(t, afero.NewMemMapFs(), "", "--generate")
+		require.NoError(t, err)
+
+		raw, err := base64.RawURLEncoding.DecodeString(first)
+		require.NoError(t, err)
+		require.Len(t, raw, 32)
+
+		second, err := run(t, afero.NewMemMapFs(), "", "--generate")
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+	})
+
+	t.Run("escrow round trip", func(t *testing.T) {
+		t.Setenv(escrowEnv, "escrow secret")
+
+		fs := passphraseFile(t)
+
+		_, err := run(t, fs, "", "--passphrase-file", "/passphrase", "--escrow-file", escrowFile)
+		require.NoError(t, err)
+
+		raw, err := afero.ReadFile(fs, escrowFile)
+		require.NoError(t, err)
+		require.NotContains(t, string(raw), "file secret", "escrow file must be encrypted")
+
+		got, err := run(t, fs, "", "--from-escrow", escrowFile)
+		require.NoError(t, err)
+		require.Equal(t, "file secret", got)
+	})
+
+	t.Run("existing escrow file is not overwritten", func(t *testing.T) {
+		t.Setenv(escrowEnv, "escrow secret")
+
+		fs := passphraseFile(t)
+		require.NoError(t, afero.WriteFile(fs, escrowFile, []byte("existing"), 0600))
+
+		_, err := run(t, fs, "", "--passphrase-file", "/passphrase", "--escrow-file", escrowFile)
+		require.EqualError(t, err, "escrow file "+escrowFile+" already exists, refusing to overwrite it")
+
+		raw, err := afero.ReadFile(fs, escrowFile)
+		require.NoError(t, err)
+		require.Equal(t, "existing", string(raw))
+	})
+
+	t.Run("wrong escrow passphrase", func(t *testing.T) {
+		t.Setenv(escrowEnv, "escrow secret")
+
+		fs := passphraseFile(t)
+
+		_, err := run(t, fs, "", "--passphrase-file", "/passphrase", "--escrow-file", escrowFile)
+		require.NoError(t, err)
+
+		t.Setenv(escrowEnv, "wrong")
+
+		_, err = run(t, fs, "", "--from-escrow", escrowFile)
+		require.EqualError(t, err, "unable to decrypt, wrong passphrase?")
+	})
+}
+
+func Test_StorageCmd_Report(t *testing.T) {
+	var (
+		volume = func() *apiv1.Volume {
+			v := volume1()
+			v.Size = 4 << 30
+			return v
+		}
+		snapshot = func() *apiv1.Snapshot {
+			s := snapshot1()
+			s.Size = 8 << 30
+			return s
+		}
+	)
+
+	tests := []*Test[[]*models.StorageReportRow]{
+		{
+			Name: "storage price without size in its unit is per GiB",
+			Cmd: func(want []*models.StorageReportRow) []string {
+				return []string{"storage", "report", "--project", "a"}
+			},
+			ClientMocks: &apitests.ClientMockFns{
+				Apiv1Mocks: &apitests.Apiv1MockFns{
+					Project: func(m *mock.Mock) {
+						m.On("Get", mock.Anything, connect.NewRequest(&apiv1.ProjectServiceGetRequest{
+							Project: "a",
+						})).Return(connect.NewResponse(&apiv1.ProjectServiceGetResponse{
+							Project: &apiv1.Project{Uuid: "a", Name: "project-a"},
+						}), nil)
+					},
+					Payment: func(m *mock.Mock) {
+						m.On("GetDefaultPrices", mock.Anything, mock.Anything).Return(connect.NewResponse(&apiv1.PaymentServiceGetDefaultPricesResponse{
+							Prices: []*apiv1.Price{
+								{Name: "Storage", ProductType: apiv1.ProductType_PRODUCT_TYPE_STORAGE, UnitAmountDecimal: 0.25, UnitLabel: "per hour", Currency: "eur"},
+								{Name: "Kubernetes", ProductType: apiv1.ProductType_PRODUCT_TYPE_KUBERNETES, UnitAmountDecimal: 1, Currency: "eur"},
+							},
+						}), nil)
+					},
+					Volume: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.VolumeServiceListRequest{
+							Project: "a",
+						})).Return(connect.NewResponse(&apiv1.VolumeServiceListResponse{
+							Volumes: []*apiv1.Volume{volume(), volume()},
+						}), nil)
+					},
+					Snapshot: func(m *mock.Mock) {
+						m.On("List", mock.Anything, connect.NewRequest(&apiv1.SnapshotServiceListRequest{
+							Project: "a",
+						})).Return(connect.NewResponse(&apiv1.SnapshotServiceListResponse{
+							Snapshots: []*apiv1.Snapshot{snapshot()},
+						}), nil)
+					},
+				},
+			},
+			Want: []*models.StorageReportRow{
+				{
+					Project:      "a",
+					ProjectName:  "project-a",
+					Partition:    "partition-a",
+					StorageClass: "storageclass-a",
+					Volumes:      2,
+					VolumeSize:   8 << 30,
+					Snapshots:    1,
+					SnapshotSize: 8 << 30,
+					MonthlyCost:  pointer.Pointer(2920.0),
+					Currency:     "EUR",
+				},
+			},
+			WantTable: pointer.Pointer(`
+PROJECT    PARTITION    STORAGE CLASS   VOLUMES  VOLUME SIZE  SNAPSHOTS  SNAPSHOT SIZE  MONTHLY COST
+project-a  partition-a  storageclass-a  2        8.0 GiB      1          8.0 GiB        2920.00 EUR
+`),
+		},
+	}
+	for _, tt := range tests {
+		tt.TestCmd(t)
+	}
+}
@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SideBySide renders two texts next to each other within the given width, long lines are wrapped
+func SideBySide(left, right string, width int) string {
+	colWidth := (width - 3) / 2
+	if colWidth < 1 {
+		colWidth = 1
+	}
+
+	var (
+		leftLines  = wrapLines(left, colWidth)
+		rightLines = wrapLines(right, colWidth)
+		sb         strings.Builder
+	)
+
+	for i := 0; i < max(len(leftLines), len(rightLines)); i++ {
+		var l, r string
+		if i < len(leftLines) {
+			l = leftLines[i]
+		}
+		if i < len(rightLines) {
+			r = rightLines[i]
+		}
+
+		line := l + strings.Repeat(" ", colWidth-utf8.RuneCountInString(l)) + " │ " + r
+		sb.WriteString(strings.TrimRight(line, " "))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func wrapLines(s string, width int) []string {
+	var result []string
+
+	for _, line := range strings.Split(strings.ReplaceAll(strings.TrimRight(s, "\n"), "\t", "    "), "\n") {
+		runes := []rune(line)
+		for len(runes) > width {
+			result = append(result, string(runes[:width]))
+			runes = runes[width:]
+		}
+		result = append(result, string(runes))
+	}
+
+	return result
+}
@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSideBySide(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  string
+		right string
+		width int
+		want  string
+	}{
+		{
+			name:  "same amount of lines",
+			left:  "a\nbb",
+			right: "c\nd",
+			width: 9,
+			want:  "a   │ c\nbb  │ d\n",
+		},
+		{
+			name:  "right is longer",
+			left:  "a",
+			right: "c\nd",
+			width: 9,
+			want:  "a   │ c\n    │ d\n",
+		},
+		{
+			name:  "left is empty",
+			left:  "",
+			right: "c",
+			width: 9,
+			want:  "    │ c\n",
+		},
+		{
+			name:  "long lines are wrapped",
+			left:  "abcdef",
+			right: "ü",
+			width: 11,
+			want:  "abcd │ ü\nef   │\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SideBySide(tt.left, tt.right, tt.width)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("SideBySide() diff (+got -want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package helpers
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	sealedVersion = 1
+	sealedKDF     = "scrypt"
+
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptSaltSz = 16
+)
+
+// sealed is the on-disk envelope of data encrypted with a passphrase.
+type sealed struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptWithPassphrase encrypts the given plaintext with a key derived from the passphrase (scrypt, XChaCha20-Poly1305)
+// and returns a self-describing JSON envelope.
+func EncryptWithPassphrase(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	salt := make([]byte, scryptSaltSz)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("unable to generate salt: %w", err)
+	}
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+
+	return json.Marshal(sealed{
+		Version:    sealedVersion,
+		KDF:        sealedKDF,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, nil),
+	})
+}
+
+// DecryptWithPassphrase reverses EncryptWithPassphrase.
+func DecryptWithPassphrase(data []byte, passphrase string) ([]byte, error) {
+	var s sealed
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("unable to parse encrypted data: %w", err)
+	}
+
+	if s.Version != sealedVersion || s.KDF != sealedKDF {
+		return nil, fmt.Errorf("unsupported encryption format (version %d, kdf %q)", s.Version, s.KDF)
+	}
+
+	aead, err := newAEAD(passphrase, s.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+
+	plaintext, err := aead.Open(nil, s.Nonce, s.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("unable to decrypt, wrong passphrase?")
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive key: %w", err)
+	}
+
+	return chacha20poly1305.NewX(key)
+}
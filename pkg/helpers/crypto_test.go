@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"testing"
+)
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	tests := []struct {
+		name        string
+		plaintext   string
+		passphrase  string
+		decryptWith string
+		wantErr     bool
+	}{
+		{
+			name:        "roundtrip",
+			plaintext:   "a-secret-token",
+			passphrase:  "correct horse battery staple",
+			decryptWith: "correct horse battery staple",
+		},
+		{
+			name:        "empty plaintext",
+			plaintext:   "",
+			passphrase:  "pass",
+			decryptWith: "pass",
+		},
+		{
+			name:        "wrong passphrase",
+			plaintext:   "a-secret-token",
+			passphrase:  "pass",
+			decryptWith: "other",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := EncryptWithPassphrase([]byte(tt.plaintext), tt.passphrase)
+			if err != nil {
+				t.Fatalf("EncryptWithPassphrase() error = %v", err)
+			}
+
+			got, err := DecryptWithPassphrase(sealed, tt.decryptWith)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecryptWithPassphrase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if string(got) != tt.plaintext {
+				t.Errorf("DecryptWithPassphrase() = %q, want %q", string(got), tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptWithPassphraseEmpty(t *testing.T) {
+	if _, err := EncryptWithPassphrase([]byte("data"), ""); err == nil {
+		t.Error("expected error for empty passphrase")
+	}
+}
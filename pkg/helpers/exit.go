@@ -0,0 +1,16 @@
+package helpers
+
+// ExitCodeError is returned by commands that need to terminate the cli with a specific exit code,
+// e.g. for being used in alerting scripts.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,104 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+type JSONChangeKind string
+
+const (
+	JSONChangeAdded   JSONChangeKind = "added"
+	JSONChangeRemoved JSONChangeKind = "removed"
+	JSONChangeChanged JSONChangeKind = "changed"
+)
+
+// JSONChange is a single difference between two json documents
+type JSONChange struct {
+	// Path points to the changed value, e.g. .spec.workers[0].name, it is empty for the document root
+	Path string         `json:"path"`
+	Kind JSONChangeKind `json:"kind"`
+	Old  any            `json:"old,omitempty"`
+	New  any            `json:"new,omitempty"`
+}
+
+// DiffJSON returns the differences between two unmarshalled json documents ordered by path.
+// Objects are compared key by key and arrays index by index, all other values are compared as a whole.
+func DiffJSON(old, new any) []JSONChange {
+	var changes []JSONChange
+	diffJSON("", old, new, &changes)
+	return changes
+}
+
+func diffJSON(path string, old, new any, changes *[]JSONChange) {
+	switch {
+	case old == nil && new == nil:
+		return
+	case old == nil:
+		*changes = append(*changes, JSONChange{Path: path, Kind: JSONChangeAdded, New: new})
+		return
+	case new == nil:
+		*changes = append(*changes, JSONChange{Path: path, Kind: JSONChangeRemoved, Old: old})
+		return
+	}
+
+	switch o := old.(type) {
+	case map[string]any:
+		n, ok := new.(map[string]any)
+		if !ok {
+			break
+		}
+
+		var keys []string
+		for k := range o {
+			keys = append(keys, k)
+		}
+		for k := range n {
+			if _, ok := o[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			ov, oldOk := o[k]
+			nv, newOk := n[k]
+
+			switch {
+			case !oldOk:
+				*changes = append(*changes, JSONChange{Path: path + "." + k, Kind: JSONChangeAdded, New: nv})
+			case !newOk:
+				*changes = append(*changes, JSONChange{Path: path + "." + k, Kind: JSONChangeRemoved, Old: ov})
+			default:
+				diffJSON(path+"."+k, ov, nv, changes)
+			}
+		}
+
+		return
+	case []any:
+		n, ok := new.([]any)
+		if !ok {
+			break
+		}
+
+		for i := 0; i < max(len(o), len(n)); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+
+			switch {
+			case i >= len(o):
+				*changes = append(*changes, JSONChange{Path: p, Kind: JSONChangeAdded, New: n[i]})
+			case i >= len(n):
+				*changes = append(*changes, JSONChange{Path: p, Kind: JSONChangeRemoved, Old: o[i]})
+			default:
+				diffJSON(p, o[i], n[i], changes)
+			}
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, JSONChange{Path: path, Kind: JSONChangeChanged, Old: old, New: new})
+	}
+}
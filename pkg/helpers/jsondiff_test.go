@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []JSONChange
+	}{
+		{
+			name: "equal",
+			old:  `{"a": 1, "b": [1, 2]}`,
+			new:  `{"b": [1, 2], "a": 1}`,
+			want: nil,
+		},
+		{
+			name: "added document",
+			old:  `null`,
+			new:  `{"a": 1}`,
+			want: []JSONChange{
+				{Path: "", Kind: JSONChangeAdded, New: map[string]any{"a": float64(1)}},
+			},
+		},
+		{
+			name: "nested changes are sorted by key",
+			old:  `{"spec": {"version": "1.30", "name": "a", "old": true}}`,
+			new:  `{"spec": {"version": "1.31", "name": "a", "new": true}}`,
+			want: []JSONChange{
+				{Path: ".spec.new", Kind: JSONChangeAdded, New: true},
+				{Path: ".spec.old", Kind: JSONChangeRemoved, Old: true},
+				{Path: ".spec.version", Kind: JSONChangeChanged, Old: "1.30", New: "1.31"},
+			},
+		},
+		{
+			name: "arrays are compared by index",
+			old:  `{"workers": [{"name": "a", "min": 1}, {"name": "b"}]}`,
+			new:  `{"workers": [{"name": "a", "min": 2}]}`,
+			want: []JSONChange{
+				{Path: ".workers[0].min", Kind: JSONChangeChanged, Old: float64(1), New: float64(2)},
+				{Path: ".workers[1]", Kind: JSONChangeRemoved, Old: map[string]any{"name": "b"}},
+			},
+		},
+		{
+			name: "type change",
+			old:  `{"a": [1]}`,
+			new:  `{"a": "1"}`,
+			want: []JSONChange{
+				{Path: ".a", Kind: JSONChangeChanged, Old: []any{float64(1)}, New: "1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var old, new any
+			if err := json.Unmarshal([]byte(tt.old), &old); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.new), &new); err != nil {
+				t.Fatal(err)
+			}
+
+			got := DiffJSON(old, new)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("DiffJSON() diff (+got -want):\n%s", diff)
+			}
+		})
+	}
+}
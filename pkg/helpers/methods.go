@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ExpandMethodPatterns resolves the given method patterns against the available api methods.
+//
+// A pattern is either a fully qualified method like metalstack.api.v1.IPService/Get or contains
+// wildcards like metalstack.api.v1.ClusterService/* or */List. Wildcards do not match across the
+// service and method separator. The leading slash of a method can be omitted. An error is returned
+// for patterns that do not match any available method.
+func ExpandMethodPatterns(patterns []string, available []string) ([]string, error) {
+	result, unmatched, err := MatchMethodPatterns(patterns, available)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("no api methods found for %s, see api-methods for available methods", strings.Join(unmatched, ", "))
+	}
+
+	return result, nil
+}
+
+// MatchMethodPatterns resolves the given method patterns like ExpandMethodPatterns, but returns the patterns
+// that do not match any available method instead of failing. Only invalid patterns result in an error.
+func MatchMethodPatterns(patterns []string, available []string) (methods []string, unmatched []string, err error) {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid method pattern %q: %w", pattern, err)
+		}
+
+		matched := false
+		for _, method := range available {
+			if matchMethod(pattern, method) {
+				matched = true
+				methods = append(methods, method)
+			}
+		}
+
+		if !matched {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	slices.Sort(methods)
+
+	return slices.Compact(methods), unmatched, nil
+}
+
+func matchMethod(pattern, method string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	method = strings.TrimPrefix(method, "/")
+
+	ok, _ := path.Match(pattern, method)
+
+	return ok
+}
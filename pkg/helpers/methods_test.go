@@ -0,0 +1,124 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExpandMethodPatterns(t *testing.T) {
+	available := []string{
+		"/metalstack.api.v1.ClusterService/Create",
+		"/metalstack.api.v1.ClusterService/Get",
+		"/metalstack.api.v1.ClusterService/List",
+		"/metalstack.api.v1.IPService/Get",
+		"/metalstack.api.v1.IPService/List",
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "exact method",
+			patterns: []string{"/metalstack.api.v1.IPService/Get"},
+			want:     []string{"/metalstack.api.v1.IPService/Get"},
+		},
+		{
+			name:     "leading slash can be omitted",
+			patterns: []string{"metalstack.api.v1.IPService/Get"},
+			want:     []string{"/metalstack.api.v1.IPService/Get"},
+		},
+		{
+			name:     "service wildcard",
+			patterns: []string{"metalstack.api.v1.ClusterService/*"},
+			want: []string{
+				"/metalstack.api.v1.ClusterService/Create",
+				"/metalstack.api.v1.ClusterService/Get",
+				"/metalstack.api.v1.ClusterService/List",
+			},
+		},
+		{
+			name:     "method wildcard across services, deduplicated",
+			patterns: []string{"*/List", "metalstack.api.v1.IPService/List"},
+			want: []string{
+				"/metalstack.api.v1.ClusterService/List",
+				"/metalstack.api.v1.IPService/List",
+			},
+		},
+		{
+			name:     "wildcard does not cross separator",
+			patterns: []string{"metalstack.api.v1.*"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown method",
+			patterns: []string{"metalstack.api.v1.IPService/Delete"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid pattern",
+			patterns: []string{"metalstack.api.v1.IPService/["},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandMethodPatterns(tt.patterns, available)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandMethodPatterns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ExpandMethodPatterns() diff (+got -want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMatchMethodPatterns(t *testing.T) {
+	available := []string{
+		"/metalstack.api.v1.ClusterService/Get",
+		"/metalstack.api.v1.IPService/Get",
+	}
+
+	tests := []struct {
+		name          string
+		patterns      []string
+		want          []string
+		wantUnmatched []string
+		wantErr       bool
+	}{
+		{
+			name:     "all matching",
+			patterns: []string{"*/Get"},
+			want:     []string{"/metalstack.api.v1.ClusterService/Get", "/metalstack.api.v1.IPService/Get"},
+		},
+		{
+			name:          "unmatched patterns are returned",
+			patterns:      []string{"metalstack.api.v1.IPService/Get", "metalstack.api.v1.HealthService/Get"},
+			want:          []string{"/metalstack.api.v1.IPService/Get"},
+			wantUnmatched: []string{"metalstack.api.v1.HealthService/Get"},
+		},
+		{
+			name:     "invalid pattern",
+			patterns: []string{"metalstack.api.v1.IPService/["},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, unmatched, err := MatchMethodPatterns(tt.patterns, available)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchMethodPatterns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("MatchMethodPatterns() diff (+got -want):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantUnmatched, unmatched); diff != "" {
+				t.Errorf("MatchMethodPatterns() unmatched diff (+got -want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package helpers
+
+import "sync"
+
+// RunParallel calls fn for every item with at most parallelism concurrent calls.
+// Results and errors are returned at the index of their item, all items are processed regardless of errors.
+func RunParallel[T, R any](items []T, parallelism int, fn func(int, T) (R, error)) ([]R, []error) {
+	var (
+		results = make([]R, len(items))
+		errs    = make([]error, len(items))
+		sem     = make(chan struct{}, max(parallelism, 1))
+		wg      sync.WaitGroup
+	)
+
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i], errs[i] = fn(i, item)
+		}()
+	}
+
+	wg.Wait()
+
+	return results, errs
+}
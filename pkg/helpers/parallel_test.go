@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunParallel(t *testing.T) {
+	var (
+		running atomic.Int32
+		peak    atomic.Int32
+	)
+
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+
+	results, errs := RunParallel(items, 3, func(i int, item int) (int, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			p := peak.Load()
+			if current <= p || peak.CompareAndSwap(p, current) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		if item == 4 {
+			return 0, errors.New("four")
+		}
+
+		return item * 10, nil
+	})
+
+	if diff := cmp.Diff([]int{10, 20, 30, 0, 50, 60, 70, 80}, results); diff != "" {
+		t.Errorf("RunParallel() results diff (+got -want):\n%s", diff)
+	}
+
+	for i, err := range errs {
+		if (err != nil) != (i == 3) {
+			t.Errorf("RunParallel() unexpected error at index %d: %v", i, err)
+		}
+	}
+
+	if p := peak.Load(); p > 3 {
+		t.Errorf("RunParallel() ran %d calls concurrently, expected at most 3", p)
+	}
+}
@@ -0,0 +1,70 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// HoursPerMonth is the average amount of hours in a month as it is used for monthly cost estimations
+const HoursPerMonth = 730
+
+var (
+	priceUnitSizes = map[string]float64{
+		"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12,
+		"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+	}
+	priceUnitHours = map[string]float64{
+		"h": 1, "hr": 1, "hour": 1, "hours": 1, "hourly": 1,
+		"d": 24, "day": 24, "days": 24, "daily": 24,
+		"mo": HoursPerMonth, "month": HoursPerMonth, "months": HoursPerMonth, "monthly": HoursPerMonth,
+	}
+	priceUnitToken = regexp.MustCompile(`[a-zA-Z]+`)
+)
+
+// PriceUnit is the interpretation of the unit label of a price, e.g. "GiB/h" or "per machine and month"
+type PriceUnit struct {
+	// Bytes is the size the price refers to, zero for prices which are not size based
+	Bytes float64
+	// Hours is the duration the price refers to
+	Hours float64
+}
+
+// ParsePriceUnit interprets the unit label of a price. Sizes like GB or GiB and durations like hour, day or month are recognized,
+// prices without a duration are considered to be hourly prices.
+func ParsePriceUnit(label string) PriceUnit {
+	unit := PriceUnit{Hours: 1}
+
+	for _, token := range priceUnitToken.FindAllString(strings.ToLower(label), -1) {
+		if size, ok := priceUnitSizes[token]; ok {
+			unit.Bytes = size
+		}
+		if hours, ok := priceUnitHours[token]; ok {
+			unit.Hours = hours
+		}
+	}
+
+	return unit
+}
+
+// MonthlyCost returns the cost of the given quantity for a month, the quantity is in bytes for size based prices and a count otherwise.
+func MonthlyCost(unitAmount float64, unitLabel string, quantity float64) float64 {
+	unit := ParsePriceUnit(unitLabel)
+
+	if unit.Bytes > 0 {
+		quantity /= unit.Bytes
+	}
+
+	return unitAmount * quantity * HoursPerMonth / unit.Hours
+}
+
+// MonthlyStorageCost returns the cost of the given amount of bytes for a month, storage prices without a size in their unit
+// are considered to be prices per GiB.
+func MonthlyStorageCost(unitAmount float64, unitLabel string, bytes float64) float64 {
+	unit := ParsePriceUnit(unitLabel)
+
+	if unit.Bytes == 0 {
+		unit.Bytes = 1 << 30
+	}
+
+	return unitAmount * bytes / unit.Bytes * HoursPerMonth / unit.Hours
+}
@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePriceUnit(t *testing.T) {
+	tests := []struct {
+		label string
+		want  PriceUnit
+	}{
+		{label: "", want: PriceUnit{Hours: 1}},
+		{label: "GiB/h", want: PriceUnit{Bytes: 1 << 30, Hours: 1}},
+		{label: "per GB and month", want: PriceUnit{Bytes: 1e9, Hours: HoursPerMonth}},
+		{label: "TiB per day", want: PriceUnit{Bytes: 1 << 40, Hours: 24}},
+		{label: "per machine", want: PriceUnit{Hours: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, ParsePriceUnit(tt.label)); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func TestMonthlyCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		unitAmount float64
+		unitLabel  string
+		quantity   float64
+		want       float64
+	}{
+		{
+			name:       "hourly size based",
+			unitAmount: 0.0001,
+			unitLabel:  "GiB/h",
+			quantity:   100 << 30,
+			want:       7.3,
+		},
+		{
+			name:       "monthly size based",
+			unitAmount: 0.05,
+			unitLabel:  "GB per month",
+			quantity:   200e9,
+			want:       10,
+		},
+		{
+			name:       "hourly count based",
+			unitAmount: 0.5,
+			unitLabel:  "per machine",
+			quantity:   3,
+			want:       1095,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MonthlyCost(tt.unitAmount, tt.unitLabel, tt.quantity); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("MonthlyCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonthlyStorageCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		unitAmount float64
+		unitLabel  string
+		bytes      float64
+		want       float64
+	}{
+		{
+			name:       "size in unit",
+			unitAmount: 0.05,
+			unitLabel:  "GB per month",
+			bytes:      200e9,
+			want:       10,
+		},
+		{
+			name:       "no unit defaults to GiB",
+			unitAmount: 0.0001,
+			unitLabel:  "",
+			bytes:      100 << 30,
+			want:       7.3,
+		},
+		{
+			name:       "no size in unit defaults to GiB",
+			unitAmount: 0.0001,
+			unitLabel:  "per hour",
+			bytes:      100 << 30,
+			want:       7.3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MonthlyStorageCost(tt.unitAmount, tt.unitLabel, tt.bytes); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("MonthlyStorageCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/metal-stack/metal-lib/pkg/tag"
+)
+
+type selectorOperator int
+
+const (
+	selectorEquals selectorOperator = iota
+	selectorNotEquals
+	selectorExists
+	selectorNotExists
+)
+
+type selectorRequirement struct {
+	key      string
+	value    string
+	operator selectorOperator
+}
+
+// Selector matches tags in the form key=value against a set of requirements, all requirements must be fulfilled
+type Selector struct {
+	requirements []selectorRequirement
+}
+
+// ParseSelector parses selector expressions, every expression may contain multiple comma-separated requirements:
+//
+//   - key=value: the tag key must be present with the given value
+//   - key!=value: the tag key must not be present with the given value
+//   - key: the tag key must be present with any value
+//   - !key: the tag key must not be present
+func ParseSelector(expressions ...string) (*Selector, error) {
+	s := &Selector{}
+
+	for _, expression := range expressions {
+		for _, r := range strings.Split(expression, ",") {
+			r = strings.TrimSpace(r)
+			if r == "" {
+				continue
+			}
+
+			var req selectorRequirement
+
+			switch {
+			case strings.Contains(r, "!="):
+				key, value, _ := strings.Cut(r, "!=")
+				req = selectorRequirement{key: key, value: value, operator: selectorNotEquals}
+			case strings.Contains(r, "="):
+				key, value, _ := strings.Cut(r, "=")
+				req = selectorRequirement{key: key, value: value, operator: selectorEquals}
+			case strings.HasPrefix(r, "!"):
+				req = selectorRequirement{key: strings.TrimPrefix(r, "!"), operator: selectorNotExists}
+			default:
+				req = selectorRequirement{key: r, operator: selectorExists}
+			}
+
+			req.key = strings.TrimSpace(req.key)
+			if req.key == "" {
+				return nil, fmt.Errorf("invalid selector %q, key must not be empty", r)
+			}
+
+			s.requirements = append(s.requirements, req)
+		}
+	}
+
+	return s, nil
+}
+
+// Empty returns true if the selector has no requirements and therefore matches everything
+func (s *Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches returns true if the given tags fulfill all requirements of the selector
+func (s *Selector) Matches(tags []string) bool {
+	tm := tag.NewTagMap(tags)
+
+	for _, req := range s.requirements {
+		value, ok := tm.Value(req.key)
+
+		switch req.operator {
+		case selectorEquals:
+			if !ok || value != req.value {
+				return false
+			}
+		case selectorNotEquals:
+			if ok && value == req.value {
+				return false
+			}
+		case selectorExists:
+			if !ok {
+				return false
+			}
+		case selectorNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,43 @@
+package helpers
+
+import "testing"
+
+func TestSelector(t *testing.T) {
+	tags := []string{"app=ingress", "env=prod", "managed"}
+
+	tests := []struct {
+		name        string
+		expressions []string
+		want        bool
+		wantErr     bool
+	}{
+		{name: "empty selector matches everything", expressions: nil, want: true},
+		{name: "equals", expressions: []string{"app=ingress"}, want: true},
+		{name: "equals with other value", expressions: []string{"app=egress"}, want: false},
+		{name: "not equals", expressions: []string{"env!=dev"}, want: true},
+		{name: "not equals with same value", expressions: []string{"env!=prod"}, want: false},
+		{name: "not equals on missing key", expressions: []string{"zone!=a"}, want: true},
+		{name: "exists", expressions: []string{"managed"}, want: true},
+		{name: "exists on missing key", expressions: []string{"zone"}, want: false},
+		{name: "not exists", expressions: []string{"!zone"}, want: true},
+		{name: "not exists on present key", expressions: []string{"!app"}, want: false},
+		{name: "comma-separated requirements are combined", expressions: []string{"app=ingress,env=prod"}, want: true},
+		{name: "multiple expressions are combined", expressions: []string{"app=ingress", "env=dev"}, want: false},
+		{name: "empty key", expressions: []string{"=value"}, wantErr: true},
+		{name: "empty negated key", expressions: []string{"!"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSelector(tt.expressions...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := s.Matches(tags); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package helpers
+
+import "strings"
+
+var sparklineTicks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders the given values as a line of block characters scaled to the maximum value, zero values are rendered as space
+func Sparkline(values []int) string {
+	maxValue := 0
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		if v <= 0 || maxValue == 0 {
+			sb.WriteRune(' ')
+			continue
+		}
+
+		idx := (v*len(sparklineTicks) - 1) / maxValue
+		sb.WriteRune(sparklineTicks[idx])
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,39 @@
+package helpers
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int
+		want   string
+	}{
+		{
+			name:   "empty",
+			values: nil,
+			want:   "",
+		},
+		{
+			name:   "only zeros",
+			values: []int{0, 0},
+			want:   "  ",
+		},
+		{
+			name:   "scaled to maximum",
+			values: []int{1, 2, 4, 8, 0},
+			want:   "▁▂▄█ ",
+		},
+		{
+			name:   "small values are visible",
+			values: []int{1, 1000},
+			want:   "▁█",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values); got != tt.want {
+				t.Errorf("Sparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
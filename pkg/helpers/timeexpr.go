@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationComponent = regexp.MustCompile(`(\d+(?:\.\d+)?)(ns|us|µs|ms|s|m|h|d|w)`)
+
+// localTimeLayouts are layouts without timezone information, they are interpreted in the given location
+var localTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	time.DateOnly,
+}
+
+// TimeAnchors are the named points in time that are understood by ParseTimeExpression
+var TimeAnchors = []string{"now", "today", "yesterday", "this-week", "last-week", "this-month", "last-month"}
+
+// ParseDuration is like time.ParseDuration but additionally supports days (d) and weeks (w), e.g. 1w2d12h
+func ParseDuration(s string) (time.Duration, error) {
+	matches := durationComponent.FindAllStringSubmatchIndex(s, -1)
+	if s == "" || len(matches) == 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var (
+		result time.Duration
+		pos    int
+	)
+
+	for _, m := range matches {
+		if m[0] != pos {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		pos = m[1]
+
+		value, unit := s[m[2]:m[3]], s[m[4]:m[5]]
+
+		var factor time.Duration
+		switch unit {
+		case "d":
+			factor = 24 * time.Hour
+		case "w":
+			factor = 7 * 24 * time.Hour
+		default:
+			d, err := time.ParseDuration(value + unit)
+			if err != nil {
+				return 0, err
+			}
+			result += d
+			continue
+		}
+
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+
+		result += time.Duration(n * float64(factor))
+	}
+
+	if pos != len(s) {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return result, nil
+}
+
+// ParseTimeExpression parses a point in time, supported are:
+//
+//   - durations relative to now, e.g. 30m, 12h, 7d, 2w
+//   - RFC3339 timestamps, e.g. 2006-01-02T15:04:05+02:00
+//   - dates and date times without timezone, interpreted in the given location, e.g. 2006-01-02 or 2006-01-02 15:04:05
+//   - named anchors in the given location, see TimeAnchors
+func ParseTimeExpression(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	// weeks start on monday
+	thisWeek := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+
+	switch strings.ToLower(s) {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "this-week":
+		return thisWeek, nil
+	case "last-week":
+		return thisWeek.AddDate(0, 0, -7), nil
+	case "this-month":
+		return thisMonth, nil
+	case "last-month":
+		return thisMonth.AddDate(0, -1, 0), nil
+	}
+
+	if d, err := ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse time %q, must be a duration relative to now (e.g. 1h, 7d, 2w), a date (2006-01-02), a date time (2006-01-02 15:04:05), RFC3339 or one of %s", s, strings.Join(TimeAnchors, "|"))
+}
@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "go duration", s: "1h30m", want: 90 * time.Minute},
+		{name: "days", s: "7d", want: 7 * 24 * time.Hour},
+		{name: "weeks and days", s: "1w2d12h", want: 9*24*time.Hour + 12*time.Hour},
+		{name: "fractional days", s: "1.5d", want: 36 * time.Hour},
+		{name: "empty", s: "", wantErr: true},
+		{name: "unknown unit", s: "3y", wantErr: true},
+		{name: "trailing garbage", s: "1dx", wantErr: true},
+		{name: "leading garbage", s: "x1d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDuration(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeExpression(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	// a wednesday
+	now := time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		s       string
+		loc     *time.Location
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "now", s: "now", loc: time.UTC, want: now},
+		{name: "relative hours", s: "2h", loc: time.UTC, want: now.Add(-2 * time.Hour)},
+		{name: "relative days", s: "7d", loc: time.UTC, want: now.AddDate(0, 0, -7)},
+		{name: "today", s: "today", loc: time.UTC, want: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)},
+		{name: "yesterday in location", s: "Yesterday", loc: berlin, want: time.Date(2026, 10, 13, 0, 0, 0, 0, berlin)},
+		{name: "this week starts on monday", s: "this-week", loc: time.UTC, want: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{name: "last week", s: "last-week", loc: time.UTC, want: time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "last month", s: "last-month", loc: time.UTC, want: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "rfc3339 keeps its offset", s: "2026-10-01T12:00:00+02:00", loc: time.UTC, want: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)},
+		{name: "date only in location", s: "2026-10-01", loc: berlin, want: time.Date(2026, 10, 1, 0, 0, 0, 0, berlin)},
+		{name: "date time in location", s: "2026-10-01 15:04:05", loc: berlin, want: time.Date(2026, 10, 1, 15, 4, 5, 0, berlin)},
+		{name: "date time without seconds", s: "2026-10-01T15:04", loc: time.UTC, want: time.Date(2026, 10, 1, 15, 4, 0, 0, time.UTC)},
+		{name: "invalid", s: "last-year", loc: time.UTC, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimeExpression(tt.s, now, tt.loc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTimeExpression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
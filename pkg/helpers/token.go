@@ -0,0 +1,22 @@
+package helpers
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenClaims are the claims contained in a metalstack.cloud api token
+type TokenClaims struct {
+	jwt.RegisteredClaims
+	Type string `json:"type"`
+}
+
+// ParseTokenClaims decodes the claims of the given token without verifying its signature.
+func ParseTokenClaims(token string) (*TokenClaims, error) {
+	cs := &TokenClaims{}
+	_, _, err := new(jwt.Parser).ParseUnverified(token, cs)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}